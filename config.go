@@ -28,6 +28,8 @@ type Config struct {
 	//Deprecated: please use SetUserId/GetUserId
 	UUID                          string
 	CipherKey                     string             // If CipherKey is passed, all communications to/from PubNub will be encrypted.
+	CryptoModule                  CryptoModule       // Encrypts and decrypts the messages and files, takes precedence over CipherKey. See NewAesGcmCryptoModule, and NewKeyring for the key rotation.
+	CipherKeyResolver             CipherKeyResolver  // When set, returns the CryptoModule of each channel, nil for the plaintext channels. It replaces CryptoModule and CipherKey, and also encrypts the signals.
 	Secure                        bool               // True to use TLS
 	ConnectTimeout                int                // net.Dialer.Timeout
 	NonSubscribeRequestTimeout    int                // http.Client.Timeout for non-subscribe requests
@@ -50,6 +52,12 @@ type Config struct {
 	StoreTokensOnGrant            bool               // Will store grant v3 tokens in token manager for further use.
 	FileMessagePublishRetryLimit  int                // The number of tries made in case of Publish File Message failure.
 	UseRandomInitializationVector bool               // When true the IV will be random for all requests and not just file upload. When false the IV will be hardcoded for all requests except File Upload
+	RetryPolicy                   RetryPolicy        // Retry policy for failed non-subscribe requests, nil disables retries.
+	EnableGapFill                 bool               // When true the messages published while the subscribe loop was reconnecting are fetched from history and delivered after the reconnection, a PNGapFillCategory status reports the outcome.
+	GapFillMaxMessages            int                // The max number of messages recovered per channel by the gap fill.
+	CursorStore                   CursorStore        // When set the subscribe cursor is saved after the messages are acknowledged and Subscribe resumes from it.
+	DedupCacheSize                int                // When positive, the messages, signals and files already delivered by the subscribe loop are filtered, remembering up to this many of them.
+	DedupCacheTTL                 int                // Seconds after which a message is forgotten by the dedup cache, 0 keeps it until it is evicted by DedupCacheSize.
 }
 
 // NewDemoConfig initiates the config with demo keys, for tests only.
@@ -85,6 +93,7 @@ func NewConfigWithUserId(userId UserId) *Config {
 		StoreTokensOnGrant:            true,
 		FileMessagePublishRetryLimit:  5,
 		UseRandomInitializationVector: true,
+		GapFillMaxMessages:            100,
 	}
 
 	return &c
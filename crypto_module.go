@@ -0,0 +1,319 @@
+package pubnub
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/pubnub/go/v7/utils"
+)
+
+// CryptoModule encrypts the messages and files sent by the client and
+// decrypts the ones it receives.
+type CryptoModule interface {
+	Encrypt(data []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
+	EncryptStream(r io.Reader) (io.Reader, error)
+	DecryptStream(r io.Reader) (io.Reader, error)
+}
+
+// CipherKeyResolver returns the CryptoModule of the channel, nil when the
+// channel is not encrypted.
+type CipherKeyResolver func(channel string) CryptoModule
+
+// cryptoModule encrypts with its default cryptor and writes the header of the
+// cryptor before the payload, except for the legacy cryptor whose payloads
+// are read by the SDKs which don't know the header. It decrypts with the
+// cryptor named in the header, with the legacy cryptor when there is none.
+type cryptoModule struct {
+	defaultCryptor utils.Cryptor
+	cryptors       map[string]utils.Cryptor
+}
+
+// NewCryptoModule creates a CryptoModule encrypting with defaultCryptor and
+// decrypting with it or any of the cryptors.
+func NewCryptoModule(defaultCryptor utils.Cryptor, cryptors ...utils.Cryptor) CryptoModule {
+	m := &cryptoModule{
+		defaultCryptor: defaultCryptor,
+		cryptors:       make(map[string]utils.Cryptor),
+	}
+	for _, c := range cryptors {
+		m.cryptors[c.ID()] = c
+	}
+	m.cryptors[defaultCryptor.ID()] = defaultCryptor
+	return m
+}
+
+// NewAesGcmCryptoModule creates a CryptoModule encrypting with AES-256-GCM.
+// It also decrypts the payloads of the legacy AES-CBC cryptor, so that the
+// messages published before the migration can still be read.
+//
+// AES-256-GCM authenticates the file as a whole: the files are encrypted and
+// decrypted in memory, not as they are uploaded or downloaded.
+func NewAesGcmCryptoModule(cipherKey string, useRandomInitializationVector bool) CryptoModule {
+	return NewCryptoModule(
+		utils.NewAesGcmCryptor(cipherKey),
+		utils.NewLegacyCryptor(cipherKey, useRandomInitializationVector),
+	)
+}
+
+// NewLegacyCryptoModule creates a CryptoModule encrypting with the legacy
+// AES-CBC cryptor, as Config.CipherKey does. It also decrypts the payloads
+// of the AES-256-GCM cryptor, so that the clients can be upgraded before the
+// publishers switch.
+func NewLegacyCryptoModule(cipherKey string, useRandomInitializationVector bool) CryptoModule {
+	return NewCryptoModule(
+		utils.NewLegacyCryptor(cipherKey, useRandomInitializationVector),
+		utils.NewAesGcmCryptor(cipherKey),
+	)
+}
+
+func (m *cryptoModule) Encrypt(data []byte) ([]byte, error) {
+	return m.encrypt(m.defaultCryptor, data)
+}
+
+func (m *cryptoModule) Decrypt(data []byte) ([]byte, error) {
+	return m.decrypt(data, false)
+}
+
+// EncryptStream encrypts r as it is read when the cryptor supports it, else
+// it encrypts the whole content of r in memory.
+func (m *cryptoModule) EncryptStream(r io.Reader) (io.Reader, error) {
+	cryptor := fileCryptor(m.defaultCryptor)
+	if streamCryptor, ok := cryptor.(utils.StreamCryptor); ok {
+		metadata, encrypted, err := streamCryptor.EncryptStream(r)
+		if err != nil {
+			return nil, err
+		}
+		if cryptor.ID() == utils.LegacyCryptorID {
+			return encrypted, nil
+		}
+		header, err := utils.EncodeCryptoHeader(cryptor.ID(), metadata)
+		if err != nil {
+			return nil, err
+		}
+		return io.MultiReader(bytes.NewReader(header), encrypted), nil
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := m.encrypt(cryptor, data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(encrypted), nil
+}
+
+// encryptedStreamSize returns the size of the stream returned by
+// EncryptStream for size bytes, false when it is only known once encrypted.
+func (m *cryptoModule) encryptedStreamSize(size int64) (int64, bool) {
+	cryptor := fileCryptor(m.defaultCryptor)
+	streamCryptor, ok := cryptor.(utils.StreamCryptor)
+	if !ok || cryptor.ID() != utils.LegacyCryptorID {
+		return 0, false
+	}
+	return streamCryptor.EncryptedSize(size), true
+}
+
+// DecryptStream decrypts r as it is read when the cryptor named in its header
+// supports it, else it decrypts the whole content of r in memory.
+func (m *cryptoModule) DecryptStream(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	cryptorID, metadata, ok, err := utils.ReadCryptoHeader(buffered)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		cryptorID = utils.LegacyCryptorID
+	}
+
+	cryptor, found := m.cryptors[cryptorID]
+	if !found {
+		return nil, fmt.Errorf("decrypt error: unknown cryptor %q", cryptorID)
+	}
+	cryptor = fileCryptor(cryptor)
+	if streamCryptor, ok := cryptor.(utils.StreamCryptor); ok {
+		return streamCryptor.DecryptStream(metadata, buffered)
+	}
+
+	data, err := ioutil.ReadAll(buffered)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := cryptor.Decrypt(&utils.EncryptedData{Metadata: metadata, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decrypted), nil
+}
+
+func (m *cryptoModule) encrypt(cryptor utils.Cryptor, data []byte) ([]byte, error) {
+	encrypted, err := cryptor.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	if cryptor.ID() == utils.LegacyCryptorID {
+		return encrypted.Data, nil
+	}
+
+	header, err := utils.EncodeCryptoHeader(cryptor.ID(), encrypted.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, encrypted.Data...), nil
+}
+
+func (m *cryptoModule) decrypt(data []byte, file bool) ([]byte, error) {
+	cryptorID, encrypted, ok, err := utils.DecodeCryptoHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		cryptorID = utils.LegacyCryptorID
+		encrypted = &utils.EncryptedData{Data: data}
+	}
+
+	cryptor, found := m.cryptors[cryptorID]
+	if !found {
+		return nil, fmt.Errorf("decrypt error: unknown cryptor %q", cryptorID)
+	}
+	if file {
+		cryptor = fileCryptor(cryptor)
+	}
+	return cryptor.Decrypt(encrypted)
+}
+
+// fileCryptor returns the cryptor used for the files, the legacy cryptor
+// always prepends a random IV to them.
+func fileCryptor(cryptor utils.Cryptor) utils.Cryptor {
+	if legacy, ok := cryptor.(*utils.LegacyCryptor); ok {
+		return legacy.WithRandomInitializationVector()
+	}
+	return cryptor
+}
+
+// streamSizer is implemented by the modules of the SDK which know the size of
+// their encrypted streams before reading them.
+type streamSizer interface {
+	encryptedStreamSize(size int64) (int64, bool)
+}
+
+// encryptedStreamSize returns the size of the encrypted stream of size bytes
+// returned by the module, false when it is unknown.
+func encryptedStreamSize(module CryptoModule, encrypted io.Reader, size int64) (int64, bool) {
+	if sized, ok := encrypted.(interface{ Len() int }); ok {
+		return int64(sized.Len()), true
+	}
+	if sizer, ok := module.(streamSizer); ok && size >= 0 {
+		return sizer.encryptedStreamSize(size)
+	}
+	return 0, false
+}
+
+// cryptoModule returns Config.CryptoModule, or a legacy module for
+// Config.CipherKey, nil when no encryption is configured.
+func (c *Config) cryptoModule() CryptoModule {
+	if c.CryptoModule != nil {
+		return c.CryptoModule
+	}
+	if c.CipherKey != "" {
+		return NewLegacyCryptoModule(c.CipherKey, c.UseRandomInitializationVector)
+	}
+	return nil
+}
+
+// cryptoModuleFor returns the module of the channel, the one returned by
+// Config.CipherKeyResolver when it is set.
+func (c *Config) cryptoModuleFor(channel string) CryptoModule {
+	if c.CipherKeyResolver != nil {
+		return c.CipherKeyResolver(channel)
+	}
+	return c.cryptoModule()
+}
+
+// signalCryptoModule returns the module of the signals of the channel. The
+// signals are only encrypted for the channels of Config.CipherKeyResolver,
+// CipherKey doesn't apply to them.
+func (c *Config) signalCryptoModule(channel string) CryptoModule {
+	if c.CipherKeyResolver != nil {
+		return c.CipherKeyResolver(channel)
+	}
+	return nil
+}
+
+// fileCryptoModule returns the module for the files of the channel. The
+// cipherKey of the request encrypts and is the first key tried to decrypt,
+// before the module of the channel.
+func (c *Config) fileCryptoModule(channel, cipherKey string) CryptoModule {
+	module := c.cryptoModuleFor(channel)
+	if cipherKey == "" {
+		return module
+	}
+	requestModule := NewLegacyCryptoModule(cipherKey, true)
+	if module == nil {
+		return requestModule
+	}
+	return NewKeyring(KeyringKey{Module: requestModule}, KeyringKey{Module: module})
+}
+
+// encryptString encrypts the message and encodes it in base64.
+func encryptString(module CryptoModule, message string) (string, error) {
+	encrypted, err := module.Encrypt([]byte(message))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// decryptString decodes the base64 message and decrypts it.
+func decryptString(module CryptoModule, message string) (string, error) {
+	if message == "" {
+		return "", fmt.Errorf("decrypt error: message is empty")
+	}
+	value, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return "", fmt.Errorf("decrypt error on decode: %s", err)
+	}
+	decrypted, err := module.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// serializeAndEncrypt serializes the message to JSON, unless it already is,
+// and encrypts it.
+func serializeAndEncrypt(module CryptoModule, msg interface{}, serialize bool) (string, error) {
+	if serialize {
+		jsonSerialized, err := json.Marshal(msg)
+		if err != nil {
+			return "", err
+		}
+		return encryptString(module, string(jsonSerialized))
+	}
+	if serializedMsg, ok := msg.(string); ok {
+		return encryptString(module, serializedMsg)
+	}
+	return "", pnerr.NewBuildRequestError("Message is not JSON serialized.")
+}
+
+// serializeEncryptAndSerialize encrypts the message and serializes the
+// encrypted string to JSON.
+func serializeEncryptAndSerialize(module CryptoModule, msg interface{}, serialize bool) (string, error) {
+	encrypted, err := serializeAndEncrypt(module, msg, serialize)
+	if err != nil {
+		return "", err
+	}
+	jsonSerialized, err := json.Marshal(encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonSerialized), nil
+}
@@ -0,0 +1,232 @@
+package pubnub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pubnub/go/v7/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAesGcmCryptoModuleWritesHeader(t *testing.T) {
+	assert := assert.New(t)
+	module := NewAesGcmCryptoModule("enigma", true)
+
+	encrypted, err := module.Encrypt([]byte(`{"a":1}`))
+	assert.Nil(err)
+	assert.True(bytes.HasPrefix(encrypted, []byte("PNED\x01ACGM")))
+
+	decrypted, err := module.Decrypt(encrypted)
+	assert.Nil(err)
+	assert.Equal(`{"a":1}`, string(decrypted))
+}
+
+func TestCryptoModuleReadsLegacyAndHeaderPayloads(t *testing.T) {
+	assert := assert.New(t)
+	gcm := NewAesGcmCryptoModule("enigma", false)
+	legacy := NewLegacyCryptoModule("enigma", false)
+
+	legacyPayload, err := base64.StdEncoding.DecodeString(utils.EncryptString("enigma", "yay!", false))
+	assert.Nil(err)
+	decrypted, err := gcm.Decrypt(legacyPayload)
+	assert.Nil(err)
+	assert.Equal("yay!", string(decrypted))
+
+	gcmPayload, err := gcm.Encrypt([]byte("yay!"))
+	assert.Nil(err)
+	decrypted, err = legacy.Decrypt(gcmPayload)
+	assert.Nil(err)
+	assert.Equal("yay!", string(decrypted))
+
+	_, err = NewCryptoModule(utils.NewAesGcmCryptor("enigma")).Decrypt(legacyPayload)
+	assert.NotNil(err)
+}
+
+func TestCryptoModuleUnknownCryptor(t *testing.T) {
+	assert := assert.New(t)
+
+	header, _ := utils.EncodeCryptoHeader("XXXX", nil)
+	_, err := NewAesGcmCryptoModule("enigma", true).Decrypt(append(header, 1, 2))
+	assert.Contains(err.Error(), "unknown cryptor")
+}
+
+func TestCryptoModuleStream(t *testing.T) {
+	assert := assert.New(t)
+	content := bytes.Repeat([]byte("file content "), 10)
+
+	for _, module := range []CryptoModule{
+		NewAesGcmCryptoModule("enigma", false),
+		NewLegacyCryptoModule("enigma", false),
+	} {
+		encrypted, err := module.EncryptStream(bytes.NewReader(content))
+		assert.Nil(err)
+		decrypted, err := module.DecryptStream(encrypted)
+		assert.Nil(err)
+		plain, _ := ioutil.ReadAll(decrypted)
+		assert.Equal(content, plain)
+	}
+}
+
+func TestCryptoModuleDecryptStreamIsStreamed(t *testing.T) {
+	assert := assert.New(t)
+	content := bytes.Repeat([]byte("file content "), 1000)
+	module := NewLegacyCryptoModule("enigma", false)
+	encrypted, _ := module.EncryptStream(bytes.NewReader(content))
+	data, _ := ioutil.ReadAll(encrypted)
+
+	// the source fails before its end, the decrypted start is still read
+	source := io.MultiReader(bytes.NewReader(data[:len(data)/2]), &droppedReader{})
+	decrypted, err := module.DecryptStream(source)
+	assert.Nil(err)
+	start := make([]byte, 100)
+	_, err = io.ReadFull(decrypted, start)
+	assert.Nil(err)
+	assert.Equal(content[:100], start)
+	_, err = ioutil.ReadAll(decrypted)
+	assert.Equal(io.ErrUnexpectedEOF, err)
+}
+
+func TestCryptoModuleDecryptsLegacyFile(t *testing.T) {
+	assert := assert.New(t)
+	content := bytes.Repeat([]byte("file content "), 10)
+
+	file, err := ioutil.TempFile("", "crypto")
+	assert.Nil(err)
+	defer os.Remove(file.Name())
+	file.Write(content)
+	file.Seek(0, io.SeekStart)
+
+	var encrypted bytes.Buffer
+	utils.EncryptFile("enigma", []byte{}, &encrypted, file)
+	file.Close()
+
+	decrypted, err := NewAesGcmCryptoModule("enigma", false).DecryptStream(&encrypted)
+	assert.Nil(err)
+	plain, _ := ioutil.ReadAll(decrypted)
+	assert.Equal(content, plain)
+
+	legacyEncrypted, err := NewLegacyCryptoModule("enigma", false).EncryptStream(bytes.NewReader(content))
+	assert.Nil(err)
+	r, w := io.Pipe()
+	data, _ := ioutil.ReadAll(legacyEncrypted)
+	utils.DecryptFile("enigma", int64(len(data)), bytes.NewReader(data), w)
+	plain, _ = ioutil.ReadAll(r)
+	assert.Equal(content, plain)
+}
+
+func TestParseCipherInterfaceCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CryptoModule = NewAesGcmCryptoModule("enigma", true)
+
+	encrypted, err := encryptString(pn.Config.CryptoModule, `{"text":"hi"}`)
+	assert.Nil(err)
+	intf, err := parseCipherInterface(encrypted, "ch", pn.Config)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"text": "hi"}, intf)
+
+	legacy := utils.EncryptString("enigma", `"yay!"`, true)
+	intf, err = parseCipherInterface(legacy, "ch", pn.Config)
+	assert.Nil(err)
+	assert.Equal("yay!", intf)
+}
+
+func TestPublishCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "ignored"
+	pn.Config.CryptoModule = NewAesGcmCryptoModule("enigma", true)
+
+	opts := newPublishOpts(pn, pn.ctx)
+	opts.Channel = "ch"
+	opts.Message = "hey"
+	opts.Serialize = true
+	opts.UsePost = true
+
+	body, err := opts.buildBody()
+	assert.Nil(err)
+	var encrypted string
+	assert.Nil(json.Unmarshal(body, &encrypted))
+
+	intf, err := parseCipherInterface(encrypted, "ch", pn.Config)
+	assert.Nil(err)
+	assert.Equal("hey", intf)
+}
+
+func TestCipherKeyResolver(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	private := NewAesGcmCryptoModule("enigma", true)
+	pn.Config.CipherKey = "ignored"
+	pn.Config.CipherKeyResolver = func(channel string) CryptoModule {
+		if channel == "private" {
+			return private
+		}
+		return nil
+	}
+
+	opts := newPublishOpts(pn, pn.ctx)
+	opts.Channel = "public"
+	opts.Message = "hey"
+	opts.Serialize = true
+	opts.UsePost = true
+	body, err := opts.buildBody()
+	assert.Nil(err)
+	assert.Equal(`"hey"`, string(body))
+
+	opts.Channel = "private"
+	body, err = opts.buildBody()
+	assert.Nil(err)
+	var encrypted string
+	assert.Nil(json.Unmarshal(body, &encrypted))
+
+	intf, err := parseCipherInterface(encrypted, "private", pn.Config)
+	assert.Nil(err)
+	assert.Equal("hey", intf)
+	intf, err = parseCipherInterface(encrypted, "public", pn.Config)
+	assert.Nil(err)
+	assert.Equal(encrypted, intf)
+}
+
+func TestCipherKeyResolverSignal(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+
+	opts := newSignalOpts(pn, pn.ctx)
+	opts.Channel = "private"
+	opts.Message = "hey"
+	opts.UsePost = true
+	body, err := opts.buildBody()
+	assert.Nil(err)
+	assert.Equal(`"hey"`, string(body))
+
+	pn.Config.CipherKeyResolver = func(channel string) CryptoModule {
+		return NewAesGcmCryptoModule("enigma", true)
+	}
+	body, err = opts.buildBody()
+	assert.Nil(err)
+	var encrypted string
+	assert.Nil(json.Unmarshal(body, &encrypted))
+
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 10})
+	pn.AddListener(listener)
+	processSubscribePayload(pn.subscriptionManager, subscribeMessage{
+		Channel:         "private",
+		MessageType:     PNMessageTypeSignal,
+		Payload:         encrypted,
+		PublishMetaData: publishMetadata{PublishTimetoken: "15"},
+	})
+	select {
+	case signal := <-listener.Signal:
+		assert.Equal("hey", signal.Message)
+	case <-time.After(time.Second):
+		assert.Fail("signal not delivered")
+	}
+}
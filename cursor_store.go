@@ -0,0 +1,300 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SubscribeCursor is the position of the subscribe loop: the timetoken and
+// region of the next subscribe request.
+type SubscribeCursor struct {
+	Timetoken int64 `json:"timetoken"`
+	Region    int8  `json:"region"`
+}
+
+// CursorStore persists the SubscribeCursor of the last fully processed
+// subscribe response. It is set in Config.CursorStore.
+//
+// With a CursorStore the cursor moves past a subscribe response only after
+// every message, signal and file event of it was acknowledged with Ack(), and
+// a Subscribe() made while the loop has no timetoken resumes from the stored
+// cursor. Together they give at-least-once processing across restarts.
+//
+// The events which never reach a consumer are released as if acknowledged:
+// the ones without a listener, the ones dropped by the overflow policy of a
+// buffered listener and the pending ones of unsubscribed channels. So are the
+// events of the oldest response when more than maxPendingCursorBatches
+// responses wait for acknowledgements.
+//
+// Load returns nil when nothing was saved yet. Save is called from the
+// goroutine calling Ack().
+type CursorStore interface {
+	Load() (*SubscribeCursor, error)
+	Save(cursor SubscribeCursor) error
+}
+
+// MemoryCursorStore is a CursorStore which keeps the cursor in memory, it
+// survives the PubNub instance but not the process.
+type MemoryCursorStore struct {
+	sync.RWMutex
+	cursor *SubscribeCursor
+}
+
+// NewMemoryCursorStore creates an empty MemoryCursorStore.
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{}
+}
+
+// Load implements CursorStore.
+func (s *MemoryCursorStore) Load() (*SubscribeCursor, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.cursor == nil {
+		return nil, nil
+	}
+	cursor := *s.cursor
+	return &cursor, nil
+}
+
+// Save implements CursorStore.
+func (s *MemoryCursorStore) Save(cursor SubscribeCursor) error {
+	s.Lock()
+	s.cursor = &cursor
+	s.Unlock()
+	return nil
+}
+
+// FileCursorStore is a CursorStore which keeps the cursor as JSON in a file.
+// The file is replaced atomically, a crash never leaves a partial cursor.
+type FileCursorStore struct {
+	sync.Mutex
+	path string
+}
+
+// NewFileCursorStore creates a FileCursorStore saving to path. The directory
+// of path must exist.
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{
+		path: path,
+	}
+}
+
+// Load implements CursorStore.
+func (s *FileCursorStore) Load() (*SubscribeCursor, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &SubscribeCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// Save implements CursorStore.
+func (s *FileCursorStore) Save(cursor SubscribeCursor) error {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// maxPendingCursorBatches is the number of subscribe responses waiting for
+// acknowledgements above which the oldest one is released.
+const maxPendingCursorBatches = 1000
+
+// cursorTracker saves the cursor of the subscribe responses in order, once
+// all their messages were acknowledged.
+type cursorTracker struct {
+	sync.Mutex
+	pubnub  *PubNub
+	batches []*cursorBatch
+}
+
+// cursorBatch is a subscribe response waiting for acknowledgements.
+type cursorBatch struct {
+	tracker   *cursorTracker
+	cursor    SubscribeCursor
+	pending   int
+	committed bool
+	acks      []*cursorAck
+}
+
+// cursorAck acknowledges one message of a cursorBatch, once. subscription
+// is the channel, channel group or wildcard the message was received through.
+type cursorAck struct {
+	once         sync.Once
+	batch        *cursorBatch
+	subscription string
+}
+
+func newCursorTracker(pubnub *PubNub) *cursorTracker {
+	return &cursorTracker{
+		pubnub: pubnub,
+	}
+}
+
+// begin registers a subscribe response with the subscriptions of the
+// messages it delivers and returns one ack per message. It must be called
+// before the messages are queued.
+func (t *cursorTracker) begin(subscriptions []string) (*cursorBatch, []*cursorAck) {
+	batch := &cursorBatch{
+		tracker: t,
+		pending: len(subscriptions),
+	}
+	acks := make([]*cursorAck, len(subscriptions))
+	for i := range acks {
+		acks[i] = &cursorAck{
+			batch:        batch,
+			subscription: subscriptions[i],
+		}
+	}
+	batch.acks = acks
+
+	t.Lock()
+	t.batches = append(t.batches, batch)
+	var oldest *cursorBatch
+	if len(t.batches) > maxPendingCursorBatches {
+		oldest = t.batches[0]
+	}
+	t.Unlock()
+
+	if oldest != nil {
+		t.pubnub.Config.Log.Println("cursor: too many responses waiting for Ack(), releasing the oldest", oldest.cursor.Timetoken)
+		for _, a := range oldest.acks {
+			a.ack()
+		}
+	}
+
+	return batch, acks
+}
+
+// release acknowledges the pending messages received through the
+// subscriptions, which were unsubscribed.
+func (t *cursorTracker) release(subscriptions []string) {
+	names := make(map[string]bool, len(subscriptions))
+	for _, name := range subscriptions {
+		names[name] = true
+	}
+
+	t.Lock()
+	acks := []*cursorAck{}
+	for _, batch := range t.batches {
+		for _, a := range batch.acks {
+			if names[a.subscription] {
+				acks = append(acks, a)
+			}
+		}
+	}
+	t.Unlock()
+
+	for _, a := range acks {
+		a.ack()
+	}
+}
+
+// commit sets the cursor following the response. The cursor is saved once
+// the batch and all batches before it are fully acknowledged.
+func (t *cursorTracker) commit(batch *cursorBatch, cursor SubscribeCursor) {
+	t.Lock()
+	batch.cursor = cursor
+	batch.committed = true
+	t.Unlock()
+
+	t.flush()
+}
+
+func (t *cursorTracker) ack(batch *cursorBatch) {
+	t.Lock()
+	batch.pending--
+	t.Unlock()
+
+	t.flush()
+}
+
+func (t *cursorTracker) flush() {
+	t.Lock()
+	defer t.Unlock()
+
+	var done *cursorBatch
+	for len(t.batches) > 0 && t.batches[0].committed && t.batches[0].pending <= 0 {
+		// a response without a valid timetoken has nothing to save
+		if t.batches[0].cursor.Timetoken != 0 {
+			done = t.batches[0]
+		}
+		t.batches = t.batches[1:]
+	}
+	if done == nil {
+		return
+	}
+
+	store := t.pubnub.Config.CursorStore
+	if store == nil {
+		return
+	}
+	if err := store.Save(done.cursor); err != nil {
+		t.pubnub.Config.Log.Println("CursorStore.Save: err", err)
+	}
+}
+
+func (a *cursorAck) ack() {
+	if a == nil {
+		return
+	}
+	a.once.Do(func() {
+		a.batch.tracker.ack(a.batch)
+	})
+}
+
+// Ack acknowledges that the message was processed. With a
+// Config.CursorStore the subscribe cursor only moves past acknowledged
+// messages, without one Ack does nothing.
+func (m *PNMessage) Ack() {
+	m.ack.ack()
+}
+
+// Ack acknowledges that the file event was processed. With a
+// Config.CursorStore the subscribe cursor only moves past acknowledged
+// events, without one Ack does nothing.
+func (f *PNFilesEvent) Ack() {
+	f.ack.ack()
+}
@@ -0,0 +1,220 @@
+package pubnub
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCursorStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewMemoryCursorStore()
+
+	cursor, err := store.Load()
+	assert.Nil(err)
+	assert.Nil(cursor)
+
+	assert.Nil(store.Save(SubscribeCursor{Timetoken: 15, Region: 4}))
+	cursor, err = store.Load()
+	assert.Nil(err)
+	assert.Equal(&SubscribeCursor{Timetoken: 15, Region: 4}, cursor)
+}
+
+func TestFileCursorStore(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "cursor")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cursor.json")
+
+	store := NewFileCursorStore(path)
+	cursor, err := store.Load()
+	assert.Nil(err)
+	assert.Nil(cursor)
+
+	assert.Nil(store.Save(SubscribeCursor{Timetoken: 15, Region: 4}))
+	assert.Nil(store.Save(SubscribeCursor{Timetoken: 16, Region: 2}))
+
+	cursor, err = NewFileCursorStore(path).Load()
+	assert.Nil(err)
+	assert.Equal(&SubscribeCursor{Timetoken: 16, Region: 2}, cursor)
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(1, len(files))
+}
+
+func TestFileCursorStoreCorrupted(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "cursor")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cursor.json")
+	ioutil.WriteFile(path, []byte("{"), 0600)
+
+	_, err = NewFileCursorStore(path).Load()
+	assert.NotNil(err)
+}
+
+func TestCursorTrackerSavesAfterAck(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	pn.Config.CursorStore = store
+	tracker := newCursorTracker(pn)
+
+	batch1, acks1 := tracker.begin([]string{"ch1", "ch1"})
+	tracker.commit(batch1, SubscribeCursor{Timetoken: 10})
+	batch2, _ := tracker.begin(nil)
+	tracker.commit(batch2, SubscribeCursor{Timetoken: 20})
+
+	cursor, _ := store.Load()
+	assert.Nil(cursor)
+
+	message := &PNMessage{ack: acks1[0]}
+	message.Ack()
+	message.Ack()
+	cursor, _ = store.Load()
+	assert.Nil(cursor)
+
+	(&PNFilesEvent{ack: acks1[1]}).Ack()
+	cursor, _ = store.Load()
+	assert.Equal(&SubscribeCursor{Timetoken: 20}, cursor)
+}
+
+func TestCursorTrackerSkipsEmptyCursor(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	pn.Config.CursorStore = store
+	tracker := newCursorTracker(pn)
+
+	batch, _ := tracker.begin(nil)
+	tracker.commit(batch, SubscribeCursor{Timetoken: 10})
+	batch, _ = tracker.begin(nil)
+	tracker.commit(batch, SubscribeCursor{})
+
+	cursor, _ := store.Load()
+	assert.Equal(&SubscribeCursor{Timetoken: 10}, cursor)
+}
+
+func TestAckWithoutCursorStore(t *testing.T) {
+	(&PNMessage{}).Ack()
+	(&PNFilesEvent{}).Ack()
+}
+
+func TestResumeFromCursorStore(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	store.Save(SubscribeCursor{Timetoken: 15, Region: 3})
+	pn.Config.CursorStore = store
+
+	m := pn.subscriptionManager
+	m.Lock()
+	m.resumeFromCursorStore()
+	assert.Equal(int64(15), m.timetoken)
+	assert.Equal(int8(3), m.region)
+	m.Unlock()
+}
+
+func TestIsAckable(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isAckable(subscribeMessage{Channel: "ch"}))
+	assert.True(isAckable(subscribeMessage{Channel: "ch", MessageType: PNMessageTypeSignal}))
+	assert.True(isAckable(subscribeMessage{Channel: "ch", MessageType: PNMessageTypeFile}))
+	assert.False(isAckable(subscribeMessage{Channel: "ch", MessageType: PNMessageTypeObjects}))
+	assert.False(isAckable(subscribeMessage{Channel: "ch-pnpres"}))
+}
+
+func TestCursorTrackerReleasesUnsubscribed(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	pn.Config.CursorStore = store
+	tracker := newCursorTracker(pn)
+
+	batch, acks := tracker.begin([]string{"ch1", "cg1", "ch2"})
+	tracker.commit(batch, SubscribeCursor{Timetoken: 10})
+
+	tracker.release([]string{"ch1", "cg1"})
+	cursor, _ := store.Load()
+	assert.Nil(cursor)
+
+	(&PNMessage{ack: acks[2]}).Ack()
+	cursor, _ = store.Load()
+	assert.Equal(&SubscribeCursor{Timetoken: 10}, cursor)
+}
+
+func TestCursorTrackerBoundsBatches(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	pn.Config.CursorStore = store
+	tracker := newCursorTracker(pn)
+
+	for i := 1; i <= maxPendingCursorBatches+1; i++ {
+		batch, _ := tracker.begin([]string{"ch1"})
+		tracker.commit(batch, SubscribeCursor{Timetoken: int64(i)})
+	}
+
+	// the oldest response was released
+	cursor, _ := store.Load()
+	assert.Equal(&SubscribeCursor{Timetoken: 1}, cursor)
+	tracker.Lock()
+	assert.Equal(maxPendingCursorBatches, len(tracker.batches))
+	tracker.Unlock()
+}
+
+func TestCursorAckReleasedWithoutListener(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	pn.Config.CursorStore = store
+	tracker := newCursorTracker(pn)
+
+	batch, acks := tracker.begin([]string{"ch1", "ch1"})
+	tracker.commit(batch, SubscribeCursor{Timetoken: 10})
+
+	lm := pn.subscriptionManager.listenerManager
+	lm.announceMessage(&PNMessage{Channel: "ch1", ack: acks[0]})
+	lm.announceFile(&PNFilesEvent{Channel: "ch1", ack: acks[1]})
+
+	cursor, _ := store.Load()
+	assert.Equal(&SubscribeCursor{Timetoken: 10}, cursor)
+}
+
+func TestCursorAckReleasedOnOverflow(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	store := NewMemoryCursorStore()
+	pn.Config.CursorStore = store
+	tracker := newCursorTracker(pn)
+
+	batch, acks := tracker.begin([]string{"ch1", "ch1", "ch1"})
+	tracker.commit(batch, SubscribeCursor{Timetoken: 10})
+
+	lm := pn.subscriptionManager.listenerManager
+	listener := NewListenerWithOptions(ListenerOptions{
+		BufferSize:     1,
+		OverflowPolicy: PNDropOldestOverflowPolicy,
+	})
+	lm.addListener(listener)
+
+	// the first message is held by the dispatcher, the second one is
+	// dropped for the third
+	lm.announceMessage(&PNMessage{Channel: "ch1", ack: acks[0]})
+	assert.Eventually(func() bool { return listener.Stats().QueueDepth == 0 }, time.Second, time.Millisecond)
+	lm.announceMessage(&PNMessage{Channel: "ch1", ack: acks[1]})
+	lm.announceMessage(&PNMessage{Channel: "ch1", ack: acks[2]})
+
+	receiveMessage(t, listener).Ack()
+	receiveMessage(t, listener).Ack()
+	cursor, _ := store.Load()
+	assert.Equal(&SubscribeCursor{Timetoken: 10}, cursor)
+	assert.Equal(uint64(1), listener.Stats().Dropped)
+}
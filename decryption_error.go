@@ -0,0 +1,54 @@
+package pubnub
+
+import (
+	"fmt"
+)
+
+// DecryptionError is the error of a message which could not be decrypted,
+// because it is corrupted or was encrypted with another key. It is the
+// ErrorData of the PNDecryptionErrorCategory status and the Error of the
+// PNMessage, PNFilesEvent, FetchResponseItem and HistoryResponseItem.
+type DecryptionError struct {
+	Channel   string
+	Timetoken int64
+	// Ciphertext is the message as received.
+	Ciphertext interface{}
+	Err        error
+}
+
+func (e *DecryptionError) Error() string {
+	return fmt.Sprintf("pubnub: can't decrypt message %d on channel %s: %s", e.Timetoken, e.Channel, e.Err)
+}
+
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// decryptPayload decrypts the message of the channel. When it fails the
+// message is returned as received with a *DecryptionError.
+func decryptPayload(payload interface{}, channel string, timetoken int64, pnConf *Config) (interface{}, *DecryptionError) {
+	decrypted, err := parseCipherInterface(payload, channel, pnConf)
+	if err != nil {
+		return payload, &DecryptionError{
+			Channel:    channel,
+			Timetoken:  timetoken,
+			Ciphertext: payload,
+			Err:        err,
+		}
+	}
+	return decrypted, nil
+}
+
+// announceDecryptionError announces the PNDecryptionErrorCategory status of a
+// message, which is still delivered with the error.
+func (m *SubscriptionManager) announceDecryptionError(err *DecryptionError) {
+	pnStatus := &PNStatus{
+		Category:         PNDecryptionErrorCategory,
+		ErrorData:        err,
+		Error:            true,
+		Operation:        PNSubscribeOperation,
+		AffectedChannels: []string{err.Channel},
+	}
+	m.pubnub.Config.Log.Println("decryption error:", err)
+	m.listenerManager.announceStatus(pnStatus)
+}
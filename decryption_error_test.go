@@ -0,0 +1,137 @@
+package pubnub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pubnub/go/v7/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptPayload(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	pn.Config.UseRandomInitializationVector = false
+
+	msg, err := decryptPayload(utils.EncryptString("enigma", `"yay!"`, false), "ch", 15, pn.Config)
+	assert.Nil(err)
+	assert.Equal("yay!", msg)
+
+	foreign := utils.EncryptString("other", `"yay!"`, false)
+	msg, err = decryptPayload(foreign, "ch", 15, pn.Config)
+	assert.Equal(foreign, msg)
+	assert.Equal("ch", err.Channel)
+	assert.Equal(int64(15), err.Timetoken)
+	assert.Equal(foreign, err.Ciphertext)
+	assert.NotNil(errors.Unwrap(err))
+}
+
+func TestSubscribeDecryptionErrorStatus(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	pn.Config.UseRandomInitializationVector = false
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 10})
+	pn.AddListener(listener)
+
+	processSubscribePayload(pn.subscriptionManager, subscribeMessage{
+		Channel:         "ch",
+		Payload:         "not encrypted",
+		PublishMetaData: publishMetadata{PublishTimetoken: "15"},
+	})
+	processSubscribePayload(pn.subscriptionManager, subscribeMessage{
+		Channel:         "ch",
+		Payload:         utils.EncryptString("enigma", `"yay!"`, false),
+		PublishMetaData: publishMetadata{PublishTimetoken: "16"},
+	})
+
+	select {
+	case status := <-listener.Status:
+		assert.Equal(PNDecryptionErrorCategory, status.Category)
+		assert.True(status.Error)
+		var decryptionErr *DecryptionError
+		assert.True(errors.As(status.ErrorData, &decryptionErr))
+		assert.Equal("not encrypted", decryptionErr.Ciphertext)
+		assert.Equal(int64(15), decryptionErr.Timetoken)
+	case <-time.After(time.Second):
+		assert.Fail("status not delivered")
+	}
+	// the message is still delivered, as received and with the error
+	msg := receiveMessage(t, listener)
+	assert.Equal("not encrypted", msg.Message)
+	var decryptionErr *DecryptionError
+	assert.True(errors.As(msg.Error, &decryptionErr))
+	assert.Equal(int64(15), decryptionErr.Timetoken)
+
+	msg = receiveMessage(t, listener)
+	assert.Equal("yay!", msg.Message)
+	assert.Equal(int64(16), msg.Timetoken)
+	assert.Nil(msg.Error)
+}
+
+func TestSubscribeFileDecryptionError(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 10})
+	pn.AddListener(listener)
+
+	processSubscribePayload(pn.subscriptionManager, subscribeMessage{
+		Channel:         "ch",
+		Payload:         "not encrypted",
+		MessageType:     PNMessageTypeFile,
+		PublishMetaData: publishMetadata{PublishTimetoken: "15"},
+	})
+
+	select {
+	case file := <-listener.File:
+		var decryptionErr *DecryptionError
+		assert.True(errors.As(file.Error, &decryptionErr))
+		assert.Equal("ch", file.Channel)
+		assert.Equal(int64(15), file.Timetoken)
+		assert.Equal("", file.File.PNFile.ID)
+	case <-time.After(time.Second):
+		assert.Fail("file event not delivered")
+	}
+}
+
+func TestFetchResponseDecryptionError(t *testing.T) {
+	assert := assert.New(t)
+
+	jsonString := []byte(`{"status": 200, "error": false, "error_message": "", "channels": {"test":[{"message": "bm90IGVuY3J5cHRlZA==", "timetoken": "15"}, {"message": {"text": "hey"}, "timetoken": "16"}]}}`)
+	resp, _, err := newFetchResponse(jsonString, initFetchOpts("enigma"), fakeResponseState)
+	assert.Nil(err)
+
+	items := resp.Messages["test"]
+	decryptionErr, ok := items[0].Error.(*DecryptionError)
+	assert.True(ok)
+	assert.Equal("test", decryptionErr.Channel)
+	assert.Equal(int64(15), decryptionErr.Timetoken)
+	assert.Equal("bm90IGVuY3J5cHRlZA==", items[0].Message)
+	assert.Nil(items[1].Error)
+}
+
+func TestHistoryResponseDecryptionError(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	pn.Config.UseRandomInitializationVector = false
+	opts := newHistoryOpts(pn, pn.ctx)
+	opts.Channel = "ch"
+	opts.IncludeTimetoken = true
+
+	encrypted := utils.EncryptString("enigma", `"yay!"`, false)
+	jsonString := []byte(`[[{"message":"` + encrypted + `","timetoken":15},{"message":"garbage","timetoken":16}],15,16]`)
+	resp, _, err := newHistoryResponse(jsonString, opts, fakeResponseState)
+	assert.Nil(err)
+
+	assert.Nil(resp.Messages[0].Error)
+	assert.Equal("yay!", resp.Messages[0].Message)
+	decryptionErr, ok := resp.Messages[1].Error.(*DecryptionError)
+	assert.True(ok)
+	assert.Equal("ch", decryptionErr.Channel)
+	assert.Equal(int64(16), decryptionErr.Timetoken)
+	assert.Equal("garbage", resp.Messages[1].Message)
+}
@@ -0,0 +1,122 @@
+package pubnub
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// dedupKey identifies a published message: the same publish timetoken from
+// the same client on the same channel is the same message.
+type dedupKey struct {
+	timetoken int64
+	issuer    string
+	channel   string
+}
+
+type dedupEntry struct {
+	key     dedupKey
+	expires time.Time
+}
+
+// dedupCache remembers the messages delivered by the subscribe loop, so
+// that the ones redelivered after a reconnection or a region failover are
+// filtered. It holds at most size entries, evicting the least recently seen
+// one, and forgets entries older than ttl when ttl is positive.
+type dedupCache struct {
+	sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[dedupKey]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+func newDedupCache(size int, ttl time.Duration) *dedupCache {
+	return &dedupCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[dedupKey]*list.Element, size),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// seen records the key and returns true when it was recorded before and has
+// not expired since.
+func (c *dedupCache) seen(key dedupKey) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.now()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*dedupEntry)
+		if c.ttl <= 0 || now.Before(entry.expires) {
+			c.order.MoveToFront(element)
+			return true
+		}
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+
+	c.evictExpired(now)
+	for c.order.Len() >= c.size {
+		c.removeOldest()
+	}
+
+	c.entries[key] = c.order.PushFront(&dedupEntry{
+		key:     key,
+		expires: now.Add(c.ttl),
+	})
+	return false
+}
+
+func (c *dedupCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.order.Len()
+}
+
+// evictExpired removes the expired entries from the back of the list. The
+// list is ordered by last use, so an expired entry can hide behind a live
+// one, it is then removed when it is looked up or falls off the back.
+func (c *dedupCache) evictExpired(now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	for c.order.Len() > 0 {
+		entry := c.order.Back().Value.(*dedupEntry)
+		if now.Before(entry.expires) {
+			return
+		}
+		c.removeOldest()
+	}
+}
+
+func (c *dedupCache) removeOldest() {
+	element := c.order.Back()
+	if element == nil {
+		return
+	}
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*dedupEntry).key)
+}
+
+// isDuplicate reports whether the message was delivered before, using the
+// dedup cache configured with Config.DedupCacheSize.
+func (m *SubscriptionManager) isDuplicate(channel, issuer string, timetoken int64) bool {
+	size := m.pubnub.Config.DedupCacheSize
+	if size <= 0 {
+		return false
+	}
+	m.dedupCacheOnce.Do(func() {
+		ttl := time.Duration(m.pubnub.Config.DedupCacheTTL) * time.Second
+		m.dedupCache = newDedupCache(size, ttl)
+	})
+	return m.dedupCache.seen(dedupKey{
+		timetoken: timetoken,
+		issuer:    issuer,
+		channel:   channel,
+	})
+}
@@ -0,0 +1,95 @@
+package pubnub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupCacheSeen(t *testing.T) {
+	assert := assert.New(t)
+	c := newDedupCache(10, 0)
+
+	key := dedupKey{timetoken: 1, issuer: "u1", channel: "ch1"}
+	assert.False(c.seen(key))
+	assert.True(c.seen(key))
+
+	assert.False(c.seen(dedupKey{timetoken: 1, issuer: "u2", channel: "ch1"}))
+	assert.False(c.seen(dedupKey{timetoken: 1, issuer: "u1", channel: "ch2"}))
+	assert.False(c.seen(dedupKey{timetoken: 2, issuer: "u1", channel: "ch1"}))
+}
+
+func TestDedupCacheLRU(t *testing.T) {
+	assert := assert.New(t)
+	c := newDedupCache(2, 0)
+
+	k1 := dedupKey{timetoken: 1}
+	k2 := dedupKey{timetoken: 2}
+	k3 := dedupKey{timetoken: 3}
+	c.seen(k1)
+	c.seen(k2)
+	// k1 becomes the most recently seen, k2 is evicted by k3
+	assert.True(c.seen(k1))
+	c.seen(k3)
+
+	assert.Equal(2, c.len())
+	assert.True(c.seen(k1))
+	assert.True(c.seen(k3))
+	assert.False(c.seen(k2))
+}
+
+func TestDedupCacheTTL(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Unix(1000, 0)
+	c := newDedupCache(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	k1 := dedupKey{timetoken: 1}
+	c.seen(k1)
+	now = now.Add(30 * time.Second)
+	assert.True(c.seen(k1))
+
+	c.seen(dedupKey{timetoken: 2})
+	now = now.Add(31 * time.Second)
+	assert.False(c.seen(k1))
+
+	now = now.Add(time.Minute)
+	c.seen(dedupKey{timetoken: 3})
+	assert.Equal(1, c.len())
+}
+
+func TestSubscriptionManagerIsDuplicate(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	assert.False(pn.subscriptionManager.isDuplicate("ch1", "u1", 1))
+	assert.False(pn.subscriptionManager.isDuplicate("ch1", "u1", 1))
+
+	pn = NewPubNub(NewDemoConfig())
+	pn.Config.DedupCacheSize = 10
+	assert.False(pn.subscriptionManager.isDuplicate("ch1", "u1", 1))
+	assert.True(pn.subscriptionManager.isDuplicate("ch1", "u1", 1))
+}
+
+func TestProcessSubscribePayloadFiltersDuplicates(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.DedupCacheSize = 10
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 10})
+	pn.AddListener(listener)
+
+	message := subscribeMessage{
+		Channel:         "ch1",
+		IssuingClientID: "u1",
+		Payload:         "hi",
+		PublishMetaData: publishMetadata{PublishTimetoken: "15"},
+	}
+	processSubscribePayload(pn.subscriptionManager, message)
+	processSubscribePayload(pn.subscriptionManager, message)
+	message.PublishMetaData.PublishTimetoken = "16"
+	processSubscribePayload(pn.subscriptionManager, message)
+
+	assert.Equal(int64(15), receiveMessage(t, listener).Timetoken)
+	assert.Equal(int64(16), receiveMessage(t, listener).Timetoken)
+}
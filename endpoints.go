@@ -1,11 +1,10 @@
 package pubnub
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -17,8 +16,9 @@ import (
 )
 
 type endpointOpts struct {
-	pubnub *PubNub
-	ctx    Context
+	pubnub       *PubNub
+	ctx          Context
+	disableRetry bool
 }
 
 type endpoint interface {
@@ -30,11 +30,12 @@ type endpoint interface {
 	buildPath() (string, error)
 	buildQuery() (*url.Values, error)
 	buildBody() ([]byte, error)
-	buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error)
+	buildBodyMultipartFileUpload() (io.Reader, string, int64, error)
 	httpMethod() string
 	operationType() OperationType
 	telemetryManager() *TelemetryManager
 	tokenManager() *TokenManager
+	retryDisabled() bool
 }
 
 func (o *endpointOpts) config() *Config {
@@ -65,6 +66,10 @@ func (o *endpointOpts) tokenManager() *TokenManager {
 	return o.pubnub.tokenManager
 }
 
+func (o *endpointOpts) retryDisabled() bool {
+	return o.disableRetry
+}
+
 func (o *endpointOpts) isAuthRequired() bool {
 	return true
 }
@@ -77,8 +82,8 @@ func (o *endpointOpts) connectTimeout() int {
 	return o.pubnub.Config.ConnectTimeout
 }
 
-func (o *endpointOpts) buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error) {
-	return bytes.Buffer{}, nil, 0, errors.New("Not required")
+func (o *endpointOpts) buildBodyMultipartFileUpload() (io.Reader, string, int64, error) {
+	return nil, "", 0, errors.New("Not required")
 }
 
 func (o *endpointOpts) httpMethod() string {
@@ -16,6 +16,12 @@ type OperationType int
 // ReconnectionPolicy is used as an enum to catgorize the reconnection policies
 type ReconnectionPolicy int
 
+// SubscribeState is used as an enum to catgorize the states of the subscribe loop
+type SubscribeState int
+
+// ListenerOverflowPolicy is used as an enum to catgorize what a buffered listener does when its buffer is full
+type ListenerOverflowPolicy int
+
 // PNPushType is used as an enum to catgorize the available Push Types
 type PNPushType int
 
@@ -177,6 +183,55 @@ const (
 	PNReconnectionAttemptsExhausted
 	// PNRequestMessageCountExceededCategory is fired when the MessageQueueOverflowCount limit is exceeded by the number of messages received in a single subscribe request
 	PNRequestMessageCountExceededCategory
+	// PNTooManyRequestsCategory as the StatusCategory means the server rejected the request with 429 Too Many Requests.
+	PNTooManyRequestsCategory
+	// PNServerErrorCategory as the StatusCategory means the server responded with a 5xx status code.
+	PNServerErrorCategory
+	// PNDNSFailureCategory as the StatusCategory means the origin host name could not be resolved.
+	PNDNSFailureCategory
+	// PNTLSFailureCategory as the StatusCategory means the TLS handshake or certificate verification failed.
+	PNTLSFailureCategory
+	// PNNetworkIssuesCategory as the StatusCategory means the request failed because of a network error
+	// which is neither a timeout, a DNS failure nor a TLS failure (for ex. connection refused or reset).
+	PNNetworkIssuesCategory
+	// PNConnectingCategory as the StatusCategory means the subscribe loop started a handshake with a new channel mix.
+	PNConnectingCategory
+	// PNListenerEventsDroppedCategory as the StatusCategory means the buffer of a listener was full
+	// and events were dropped according to its ListenerOverflowPolicy.
+	PNListenerEventsDroppedCategory
+	// PNGapFillCategory as the StatusCategory reports the messages recovered from history after a reconnection.
+	// Applicable only when EnableGapFill is set in the config.
+	PNGapFillCategory
+	// PNDecryptionErrorCategory as the StatusCategory means a message received by the subscribe loop could not be decrypted,
+	// ErrorData is the *DecryptionError and the message is not delivered to the listeners.
+	PNDecryptionErrorCategory
+)
+
+const (
+	// PNBlockOverflowPolicy makes the SDK wait until the listener has room in its buffer.
+	// It slows down the subscribe loop to the pace of the slowest listener.
+	PNBlockOverflowPolicy ListenerOverflowPolicy = 1 + iota
+	// PNDropOldestOverflowPolicy drops the oldest buffered event to make room for the new one.
+	PNDropOldestOverflowPolicy
+	// PNDropNewestOverflowPolicy drops the new event and keeps the buffered ones.
+	PNDropNewestOverflowPolicy
+)
+
+const (
+	// PNSubscribeStateUnsubscribed is the state of the subscribe loop when there are no channels or channel groups to subscribe to.
+	PNSubscribeStateUnsubscribed SubscribeState = 1 + iota
+	// PNSubscribeStateHandshaking is the state of the subscribe loop while the first request for a new channel mix is in flight.
+	PNSubscribeStateHandshaking
+	// PNSubscribeStateReceiving is the state of the subscribe loop while it receives messages.
+	PNSubscribeStateReceiving
+	// PNSubscribeStateReconnecting is the state of the subscribe loop after a recoverable failure,
+	// while the reconnection manager waits for the network to come back.
+	PNSubscribeStateReconnecting
+	// PNSubscribeStateStopped is the state of the subscribe loop after the PubNub instance was destroyed.
+	PNSubscribeStateStopped
+	// PNSubscribeStateFailed is the state of the subscribe loop after a failure which can't be recovered from,
+	// like PNAccessDeniedCategory or PNReconnectionAttemptsExhausted.
+	PNSubscribeStateFailed
 )
 
 const (
@@ -447,12 +502,80 @@ func (c StatusCategory) String() string {
 	case PNNoStubMatchedCategory:
 		return "No Stub Matched"
 
+	case PNTooManyRequestsCategory:
+		return "Too Many Requests"
+
+	case PNServerErrorCategory:
+		return "Server Error"
+
+	case PNDNSFailureCategory:
+		return "DNS Failure"
+
+	case PNTLSFailureCategory:
+		return "TLS Failure"
+
+	case PNNetworkIssuesCategory:
+		return "Network Issues"
+
+	case PNConnectingCategory:
+		return "Connecting"
+
+	case PNListenerEventsDroppedCategory:
+		return "Listener Events Dropped"
+
+	case PNGapFillCategory:
+		return "Gap Fill"
+
+	case PNDecryptionErrorCategory:
+		return "Decryption Error"
+
 	default:
 		return "No Stub Matched"
 
 	}
 }
 
+func (s SubscribeState) String() string {
+	switch s {
+	case PNSubscribeStateUnsubscribed:
+		return "Unsubscribed"
+
+	case PNSubscribeStateHandshaking:
+		return "Handshaking"
+
+	case PNSubscribeStateReceiving:
+		return "Receiving"
+
+	case PNSubscribeStateReconnecting:
+		return "Reconnecting"
+
+	case PNSubscribeStateStopped:
+		return "Stopped"
+
+	case PNSubscribeStateFailed:
+		return "Failed"
+
+	default:
+		return "Unknown"
+	}
+}
+
+func (p ListenerOverflowPolicy) String() string {
+	switch p {
+	case PNBlockOverflowPolicy:
+		return "Block"
+
+	case PNDropOldestOverflowPolicy:
+		return "Drop Oldest"
+
+	case PNDropNewestOverflowPolicy:
+		return "Drop Newest"
+
+	default:
+		return "Unknown"
+	}
+}
+
 func (t OperationType) String() string {
 	switch t {
 	case PNSubscribeOperation:
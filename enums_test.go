@@ -37,6 +37,34 @@ func TestStatusCategoryString(t *testing.T) {
 	assert.Equal("Reconnected", PNReconnectedCategory.String())
 	assert.Equal("Reconnection Attempts Exhausted", PNReconnectionAttemptsExhausted.String())
 	assert.Equal("No Stub Matched", PNNoStubMatchedCategory.String())
+	assert.Equal("Too Many Requests", PNTooManyRequestsCategory.String())
+	assert.Equal("Server Error", PNServerErrorCategory.String())
+	assert.Equal("DNS Failure", PNDNSFailureCategory.String())
+	assert.Equal("TLS Failure", PNTLSFailureCategory.String())
+	assert.Equal("Network Issues", PNNetworkIssuesCategory.String())
+	assert.Equal("Connecting", PNConnectingCategory.String())
+	assert.Equal("Listener Events Dropped", PNListenerEventsDroppedCategory.String())
+	assert.Equal("Gap Fill", PNGapFillCategory.String())
+	assert.Equal("Decryption Error", PNDecryptionErrorCategory.String())
+}
+
+func TestListenerOverflowPolicyString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Block", PNBlockOverflowPolicy.String())
+	assert.Equal("Drop Oldest", PNDropOldestOverflowPolicy.String())
+	assert.Equal("Drop Newest", PNDropNewestOverflowPolicy.String())
+}
+
+func TestSubscribeStateString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Unsubscribed", PNSubscribeStateUnsubscribed.String())
+	assert.Equal("Handshaking", PNSubscribeStateHandshaking.String())
+	assert.Equal("Receiving", PNSubscribeStateReceiving.String())
+	assert.Equal("Reconnecting", PNSubscribeStateReconnecting.String())
+	assert.Equal("Stopped", PNSubscribeStateStopped.String())
+	assert.Equal("Failed", PNSubscribeStateFailed.String())
 }
 
 func TestOperationTypeString(t *testing.T) {
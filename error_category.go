@@ -0,0 +1,135 @@
+package pubnub
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+// categorizeError derives the StatusCategory of a failed request from the
+// typed error returned by executeRequest.
+//
+// Server errors are classified by HTTP status code:
+// - 400 - PNBadRequestCategory
+// - 403 - PNAccessDeniedCategory
+// - 408 - PNTimeoutCategory
+// - 429 - PNTooManyRequestsCategory
+// - 530 - PNNoStubMatchedCategory (mock server only)
+// - 5xx - PNServerErrorCategory
+//
+// Connection errors are classified by the context cause first and the
+// network cause second:
+// - context cancelled - PNCancelledCategory
+// - context deadline or network timeout - PNTimeoutCategory
+// - host lookup failure - PNDNSFailureCategory
+// - TLS handshake or certificate failure - PNTLSFailureCategory
+// - any other network failure - PNNetworkIssuesCategory
+//
+// Everything else is PNUnknownCategory.
+func categorizeError(err error) StatusCategory {
+	if err == nil {
+		return PNUnknownCategory
+	}
+
+	var serverErr *pnerr.ServerError
+	if errors.As(err, &serverErr) {
+		return categorizeStatusCode(serverErr.StatusCode)
+	}
+
+	var connErr *pnerr.ConnectionError
+	if errors.As(err, &connErr) {
+		return categorizeConnectionError(connErr)
+	}
+
+	return PNUnknownCategory
+}
+
+// statusCodeOf returns the HTTP status code carried by a server error, 0 for
+// any other error.
+func statusCodeOf(err error) int {
+	var serverErr *pnerr.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.StatusCode
+	}
+	return 0
+}
+
+func categorizeStatusCode(statusCode int) StatusCategory {
+	switch {
+	case statusCode == 400:
+		return PNBadRequestCategory
+	case statusCode == 403:
+		return PNAccessDeniedCategory
+	case statusCode == 408:
+		return PNTimeoutCategory
+	case statusCode == 429:
+		return PNTooManyRequestsCategory
+	case statusCode == 530:
+		return PNNoStubMatchedCategory
+	case statusCode >= 500 && statusCode < 600:
+		return PNServerErrorCategory
+	default:
+		return PNUnknownCategory
+	}
+}
+
+func categorizeConnectionError(err *pnerr.ConnectionError) StatusCategory {
+	if err.ContextError != nil {
+		if errors.Is(err.ContextError, context.DeadlineExceeded) {
+			return PNTimeoutCategory
+		}
+		return PNCancelledCategory
+	}
+
+	cause := err.OrigError
+	if errors.Is(cause, context.Canceled) {
+		return PNCancelledCategory
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(cause, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return PNTimeoutCategory
+		}
+		return PNDNSFailureCategory
+	}
+
+	if isTLSError(cause) {
+		return PNTLSFailureCategory
+	}
+
+	var netErr net.Error
+	if errors.As(cause, &netErr) && netErr.Timeout() {
+		return PNTimeoutCategory
+	}
+
+	return PNNetworkIssuesCategory
+}
+
+func isTLSError(err error) bool {
+	var recordHeaderErr tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &recordHeaderErr) ||
+		errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certificateInvalidErr)
+}
+
+// isUnrecoverableCategory reports whether a subscribe loop which failed with
+// the category should give up on its channels instead of waiting for the
+// reconnection manager to bring the network back.
+func isUnrecoverableCategory(category StatusCategory) bool {
+	switch category {
+	case PNAccessDeniedCategory, PNBadRequestCategory, PNNoStubMatchedCategory:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package pubnub
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestCategorizeServerErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[int]StatusCategory{
+		400: PNBadRequestCategory,
+		403: PNAccessDeniedCategory,
+		404: PNUnknownCategory,
+		408: PNTimeoutCategory,
+		429: PNTooManyRequestsCategory,
+		500: PNServerErrorCategory,
+		502: PNServerErrorCategory,
+		530: PNNoStubMatchedCategory,
+	}
+	for code, category := range cases {
+		err := &pnerr.ServerError{StatusCode: code}
+		assert.Equal(category, categorizeError(err), code)
+	}
+}
+
+func TestCategorizeServerErrorIgnoresBody(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &pnerr.ServerError{StatusCode: 200, Body: []byte("403 Forbidden 400 Bad Request")}
+	assert.Equal(PNUnknownCategory, categorizeError(err))
+}
+
+func TestCategorizeConnectionErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://ps.pndsn.com/403", Err: err}
+	}
+
+	assert.Equal(PNDNSFailureCategory, categorizeError(pnerr.NewConnectionError("",
+		urlErr(&net.DNSError{Err: "no such host", Name: "ps.pndsn.com"}))))
+	assert.Equal(PNTimeoutCategory, categorizeError(pnerr.NewConnectionError("",
+		urlErr(&net.DNSError{Err: "timeout", Name: "ps.pndsn.com", IsTimeout: true}))))
+	assert.Equal(PNTLSFailureCategory, categorizeError(pnerr.NewConnectionError("",
+		urlErr(x509.UnknownAuthorityError{}))))
+	assert.Equal(PNTimeoutCategory, categorizeError(pnerr.NewConnectionError("",
+		urlErr(timeoutError{}))))
+	assert.Equal(PNNetworkIssuesCategory, categorizeError(pnerr.NewConnectionError("",
+		urlErr(errors.New("connection refused")))))
+	assert.Equal(PNCancelledCategory, categorizeError(pnerr.NewConnectionError("",
+		urlErr(context.Canceled))))
+}
+
+func TestCategorizeConnectionErrorContextCause(t *testing.T) {
+	assert := assert.New(t)
+
+	cause := errors.New("request canceled")
+
+	assert.Equal(PNCancelledCategory, categorizeError(
+		pnerr.NewConnectionErrorWithContext("", cause, context.Canceled)))
+	assert.Equal(PNTimeoutCategory, categorizeError(
+		pnerr.NewConnectionErrorWithContext("", cause, context.DeadlineExceeded)))
+}
+
+func TestCategorizeOtherErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(PNUnknownCategory, categorizeError(nil))
+	assert.Equal(PNUnknownCategory, categorizeError(errors.New("403 Forbidden")))
+	assert.Equal(PNUnknownCategory, categorizeError(pnerr.NewValidationError("Publish", StrMissingChannel)))
+}
+
+func TestUnrecoverableCategory(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isUnrecoverableCategory(PNAccessDeniedCategory))
+	assert.True(isUnrecoverableCategory(PNBadRequestCategory))
+	assert.True(isUnrecoverableCategory(PNNoStubMatchedCategory))
+	assert.False(isUnrecoverableCategory(PNTooManyRequestsCategory))
+	assert.False(isUnrecoverableCategory(PNServerErrorCategory))
+	assert.False(isUnrecoverableCategory(PNDNSFailureCategory))
+	assert.False(isUnrecoverableCategory(PNTLSFailureCategory))
+}
@@ -0,0 +1,129 @@
+// Command history exports the history of channels to JSON Lines and replays
+// such an export through Publish.
+//
+//	history -sub sub-c-... -channels ch1,ch2 -export history.jsonl
+//	history -pub pub-c-... -sub sub-c-... -replay history.jsonl -map ch1=ch1-copy -rate 10
+//
+// An export to an existing file resumes after the last message written.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	pubnub "github.com/pubnub/go/v7"
+)
+
+func main() {
+	publishKey := flag.String("pub", "demo", "publish key")
+	subscribeKey := flag.String("sub", "demo", "subscribe key")
+	cipherKey := flag.String("cipher", "", "cipher key of the messages")
+	userID := flag.String("user", "history-cli", "user id")
+	exportFile := flag.String("export", "", "file to export the history to")
+	replayFile := flag.String("replay", "", "file to replay the history from")
+	channels := flag.String("channels", "", "comma separated channels to export")
+	start := flag.Int64("start", 0, "newest timetoken exported, 0 for now")
+	end := flag.Int64("end", 0, "oldest timetoken exported, 0 for the first message")
+	actions := flag.Bool("actions", false, "export the message actions")
+	channelMap := flag.String("map", "", "comma separated from=to channels of the replay")
+	rate := flag.Float64("rate", 10, "most messages replayed per second, 0 for no limit")
+	skipFiles := flag.Bool("skip-files", false, "don't replay the file events")
+	flag.Parse()
+
+	config := pubnub.NewConfigWithUserId(pubnub.UserId(*userID))
+	config.PublishKey = *publishKey
+	config.SubscribeKey = *subscribeKey
+	config.CipherKey = *cipherKey
+	pn := pubnub.NewPubNub(config)
+
+	switch {
+	case *exportFile != "":
+		if *channels == "" {
+			log.Fatal("-channels is required to export")
+		}
+		n, err := export(pn, *exportFile, pubnub.HistoryExportOptions{
+			Channels:              strings.Split(*channels, ","),
+			Start:                 *start,
+			End:                   *end,
+			IncludeMessageActions: *actions,
+		})
+		fmt.Printf("exported %d messages\n", n)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case *replayFile != "":
+		n, err := replay(pn, *replayFile, pubnub.HistoryReplayOptions{
+			ChannelMap: parseChannelMap(*channelMap),
+			Rate:       *rate,
+			SkipFiles:  *skipFiles,
+		})
+		fmt.Printf("replayed %d messages\n", n)
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func export(pn *pubnub.PubNub, name string, opts pubnub.HistoryExportOptions) (int, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	opts.Resume, err = pubnub.LastExportedTimetokens(f)
+	if err != nil {
+		return 0, err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if size > 0 {
+		// a line cut by an interrupted export is ended, it is then skipped
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			return 0, err
+		}
+		if last[0] != '\n' {
+			if _, err := f.Write([]byte("\n")); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return pn.ExportHistory(f, opts)
+}
+
+func replay(pn *pubnub.PubNub, name string, opts pubnub.HistoryReplayOptions) (int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return pn.ReplayHistory(f, opts)
+}
+
+func parseChannelMap(value string) map[string]string {
+	channelMap := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if from, to, ok := cut(pair, "="); ok {
+			channelMap[from] = to
+		}
+	}
+	return channelMap
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
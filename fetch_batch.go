@@ -0,0 +1,224 @@
+package pubnub
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// fetchBatchConcurrency is the most batches of a Fetch run at the same time.
+const fetchBatchConcurrency = 4
+
+// needsBatches is true when the channels or the count are over what one
+// request returns.
+func (o *fetchOpts) needsBatches() bool {
+	return len(o.Channels) > maxCountFetchMoreThanOneChannel || o.Count > o.maxCount()
+}
+
+// FetchBatchError is returned by a Fetch run in batches when none of its
+// requests succeeded. When only some fail, the Fetch succeeds and their
+// channels are in the Errors of the FetchResponse.
+type FetchBatchError struct {
+	// Errors is the error of each channel.
+	Errors map[string]error
+}
+
+func (e *FetchBatchError) Error() string {
+	return fmt.Sprintf("pubnub: fetch failed for all %d channels: %s", len(e.Errors), e.first())
+}
+
+// Unwrap returns the error of the first channel, in alphabetical order.
+func (e *FetchBatchError) Unwrap() error {
+	return e.first()
+}
+
+func (e *FetchBatchError) first() error {
+	channels := make([]string, 0, len(e.Errors))
+	for channel := range e.Errors {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	if len(channels) == 0 {
+		return nil
+	}
+	return e.Errors[channels[0]]
+}
+
+// executeBatches fetches the channels in batches of
+// maxCountFetchMoreThanOneChannel run concurrently, then pages each channel
+// until Count messages are fetched or its range is exhausted. The status is
+// the one of a successful batch, if any.
+func (o *fetchOpts) executeBatches() (*FetchResponse, StatusResponse, error) {
+	if err := o.validate(); err != nil {
+		return emptyFetchResp, StatusResponse{Operation: PNFetchMessagesOperation}, err
+	}
+
+	resp := &FetchResponse{
+		Messages: make(map[string][]FetchResponseItem),
+		Errors:   make(map[string]error),
+	}
+	var status StatusResponse
+	succeeded := false
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchBatchConcurrency)
+
+	for begin := 0; begin < len(o.Channels); begin += maxCountFetchMoreThanOneChannel {
+		end := begin + maxCountFetchMoreThanOneChannel
+		if end > len(o.Channels) {
+			end = len(o.Channels)
+		}
+		batch := o.Channels[begin:end]
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			messages, errs, batchStatus, err := o.fetchBatch(batch)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !succeeded {
+				status = batchStatus
+				succeeded = err == nil
+			}
+			for channel, items := range messages {
+				resp.Messages[channel] = items
+			}
+			for channel, err := range errs {
+				resp.Errors[channel] = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !succeeded {
+		return emptyFetchResp, status, &FetchBatchError{Errors: resp.Errors}
+	}
+	return resp, status, nil
+}
+
+// fetchBatch fetches the first page of the channels in one request, then the
+// next pages of each channel which has more. It returns the error of the
+// first request, the errors of the next pages are per channel.
+func (o *fetchOpts) fetchBatch(channels []string) (map[string][]FetchResponseItem, map[string]error, StatusResponse, error) {
+	messages := make(map[string][]FetchResponseItem)
+	errs := make(map[string]error)
+
+	first := o.page(channels)
+	page, status, err := first.execute()
+	if err != nil {
+		for _, channel := range channels {
+			errs[channel] = err
+		}
+		return messages, errs, status, err
+	}
+
+	for _, channel := range channels {
+		items := page.Messages[channel]
+		messages[channel] = items
+		if len(items) < first.requestedCount() {
+			continue
+		}
+		if err := o.fetchChannelPages(channel, messages); err != nil {
+			errs[channel] = err
+		}
+	}
+	return messages, errs, status, nil
+}
+
+// fetchChannelPages requests the next pages of the channel, older messages
+// first unless Reverse is set, until Count messages are fetched or the range
+// is exhausted.
+func (o *fetchOpts) fetchChannelPages(channel string, messages map[string][]FetchResponseItem) error {
+	for {
+		items := messages[channel]
+		remaining := o.totalCount() - len(items)
+		if remaining <= 0 || len(items) == 0 {
+			return nil
+		}
+
+		next := o.page([]string{channel})
+		next.Count = remaining
+		if o.Reverse {
+			// the end is inclusive, the page continues after the newest message
+			newest, err := strconv.ParseInt(items[len(items)-1].Timetoken, 10, 64)
+			if err != nil {
+				return err
+			}
+			next.End = newest + 1
+			next.setEnd = true
+		} else {
+			// the start is exclusive, the page continues before the oldest
+			// message
+			oldest, err := strconv.ParseInt(items[0].Timetoken, 10, 64)
+			if err != nil {
+				return err
+			}
+			next.Start = oldest
+			next.setStart = true
+		}
+
+		page, _, err := next.execute()
+		if err != nil {
+			return err
+		}
+		pageItems := page.Messages[channel]
+		if o.Reverse {
+			messages[channel] = append(items, pageItems...)
+		} else {
+			messages[channel] = append(pageItems, items...)
+		}
+		if len(pageItems) < next.requestedCount() {
+			return nil
+		}
+	}
+}
+
+// page returns the options of a request for the channels with the options
+// of the Fetch.
+func (o *fetchOpts) page(channels []string) *fetchOpts {
+	page := newFetchOpts(o.pubnub, o.ctx, fetchOpts{
+		Channels:           channels,
+		Start:              o.Start,
+		End:                o.End,
+		WithMessageActions: o.WithMessageActions,
+		WithMeta:           o.WithMeta,
+		Reverse:            o.Reverse,
+		QueryParam:         o.QueryParam,
+		setStart:           o.setStart,
+		setEnd:             o.setEnd,
+		keepEncrypted:      o.keepEncrypted,
+		Transport:          o.Transport,
+	})
+	page.WithUUID = o.WithUUID
+	page.WithMessageType = o.WithMessageType
+	page.Count = o.totalCount()
+	return page
+}
+
+// totalCount returns the number of messages to fetch per channel.
+func (o *fetchOpts) totalCount() int {
+	if o.Count > 0 {
+		return o.Count
+	}
+	return o.maxCount()
+}
+
+// requestedCount returns the max requested by buildQuery.
+func (o *fetchOpts) requestedCount() int {
+	if o.Count > 0 && o.Count <= o.maxCount() {
+		return o.Count
+	}
+	return o.maxCount()
+}
+
+func (o *fetchOpts) execute() (*FetchResponse, StatusResponse, error) {
+	rawJSON, status, err := executeRequest(o)
+	if err != nil {
+		return emptyFetchResp, status, err
+	}
+	return newFetchResponse(rawJSON, o, status)
+}
@@ -0,0 +1,150 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// historyTransport answers the Fetch requests from the messages of each
+// channel, timetokens 1 to n.
+type historyTransport struct {
+	sync.Mutex
+	counts   map[string]int
+	failing  map[string]bool
+	requests []*http.Request
+}
+
+func (t *historyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	t.requests = append(t.requests, req)
+	t.Unlock()
+
+	path := req.URL.Opaque
+	channels := strings.Split(path[strings.LastIndex(path, "/")+1:], ",")
+	query := req.URL.Query()
+	max, _ := strconv.Atoi(query.Get("max"))
+	start, errStart := strconv.Atoi(query.Get("start"))
+
+	result := map[string][]map[string]interface{}{}
+	for _, channel := range channels {
+		if t.failing[channel] {
+			resp := newSequenceResponse(500, `{"status":500,"error":true}`, nil)
+			resp.Request = req
+			return resp, nil
+		}
+		newest := t.counts[channel]
+		if errStart == nil && start-1 < newest {
+			newest = start - 1
+		}
+		oldest := newest - max + 1
+		if query.Get("reverse") == "true" {
+			// the oldest messages of the range first
+			oldest, _ = strconv.Atoi(query.Get("end"))
+			if oldest < 1 {
+				oldest = 1
+			}
+			if oldest+max-1 < newest {
+				newest = oldest + max - 1
+			}
+		}
+		items := []map[string]interface{}{}
+		for tt := oldest; tt <= newest; tt++ {
+			if tt < 1 {
+				continue
+			}
+			items = append(items, map[string]interface{}{"message": tt, "timetoken": strconv.Itoa(tt), "meta": map[string]interface{}{"n": tt}})
+		}
+		result[channel] = items
+	}
+	body, _ := json.Marshal(map[string]interface{}{"status": 200, "channels": result})
+	resp := newSequenceResponse(200, string(body), nil)
+	resp.Request = req
+	return resp, nil
+}
+
+func TestFetchBatchesChannels(t *testing.T) {
+	assert := assert.New(t)
+	transport := &historyTransport{counts: map[string]int{}}
+	channels := []string{}
+	for i := 0; i < 60; i++ {
+		channel := fmt.Sprintf("ch%d", i)
+		channels = append(channels, channel)
+		transport.counts[channel] = 3
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	resp, _, err := pn.Fetch().Channels(channels).Execute()
+
+	assert.Nil(err)
+	assert.Len(resp.Messages, 60)
+	assert.Len(resp.Errors, 0)
+	assert.Len(resp.Messages["ch59"], 3)
+	assert.Len(transport.requests, 3)
+}
+
+func TestFetchPagesPastMaxCount(t *testing.T) {
+	assert := assert.New(t)
+	transport := &historyTransport{counts: map[string]int{"ch": 250}}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	resp, _, err := pn.Fetch().Channels([]string{"ch"}).Count(150).Execute()
+
+	assert.Nil(err)
+	items := resp.Messages["ch"]
+	assert.Len(items, 150)
+	assert.Equal("101", items[0].Timetoken)
+	assert.Equal("250", items[149].Timetoken)
+	assert.Len(transport.requests, 2)
+	assert.Equal("151", transport.requests[1].URL.Query().Get("start"))
+	assert.Equal("50", transport.requests[1].URL.Query().Get("max"))
+}
+
+func TestFetchBatchErrorsPerChannel(t *testing.T) {
+	assert := assert.New(t)
+	transport := &historyTransport{counts: map[string]int{}, failing: map[string]bool{"ch0": true}}
+	channels := []string{}
+	for i := 0; i < 30; i++ {
+		channel := fmt.Sprintf("ch%d", i)
+		channels = append(channels, channel)
+		transport.counts[channel] = 1
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	resp, _, err := pn.Fetch().Channels(channels).Execute()
+
+	assert.Nil(err)
+	assert.Len(resp.Errors, 25)
+	assert.NotNil(resp.Errors["ch0"])
+	assert.Nil(resp.Errors["ch29"])
+	assert.Len(resp.Messages["ch29"], 1)
+}
+
+func TestFetchBatchAllFail(t *testing.T) {
+	assert := assert.New(t)
+	transport := &historyTransport{counts: map[string]int{}, failing: map[string]bool{"ch0": true, "ch25": true}}
+	channels := []string{}
+	for i := 0; i < 30; i++ {
+		channels = append(channels, fmt.Sprintf("ch%d", i))
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, _, err := pn.Fetch().Channels(channels).Execute()
+
+	var batchErr *FetchBatchError
+	assert.True(errors.As(err, &batchErr))
+	assert.Len(batchErr.Errors, 30)
+	assert.NotNil(errors.Unwrap(err))
+	assert.Contains(err.Error(), "fetch failed for all 30 channels")
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
@@ -60,6 +61,16 @@ func (b *fetchBuilder) End(end int64) *fetchBuilder {
 	return b
 }
 
+// Since fetches the messages published at t or later, it sets End.
+func (b *fetchBuilder) Since(t time.Time) *fetchBuilder {
+	return b.End(TimetokenFromTime(t).Int64())
+}
+
+// Until fetches the messages published before t, it sets Start.
+func (b *fetchBuilder) Until(t time.Time) *fetchBuilder {
+	return b.Start(TimetokenFromTime(t).Int64())
+}
+
 // Count sets the number of items to return in the Fetch request.
 func (b *fetchBuilder) Count(count int) *fetchBuilder {
 	b.opts.Count = count
@@ -109,8 +120,14 @@ func (b *fetchBuilder) Transport(tr http.RoundTripper) *fetchBuilder {
 	return b
 }
 
-// Execute runs the Fetch request.
+// Execute runs the Fetch request. When there are more channels or a larger
+// Count than one request returns, it runs batches of requests and the errors
+// are reported per channel in FetchResponse.Errors.
 func (b *fetchBuilder) Execute() (*FetchResponse, StatusResponse, error) {
+	if b.opts.needsBatches() {
+		return b.opts.executeBatches()
+	}
+
 	rawJSON, status, err := executeRequest(b.opts)
 	if err != nil {
 		return emptyFetchResp, status, err
@@ -152,6 +169,11 @@ type fetchOpts struct {
 	setStart bool
 	setEnd   bool
 
+	// keepEncrypted returns the messages as stored, for the gap filler which
+	// hands them to the subscribe path that decrypts them and parses the
+	// files.
+	keepEncrypted bool
+
 	Transport http.RoundTripper
 }
 
@@ -195,15 +217,7 @@ func (o *fetchOpts) buildQuery() (*url.Values, error) {
 		q.Set("end", strconv.FormatInt(o.End, 10))
 	}
 
-	maxCount := maxCountFetch
-
-	if o.WithMessageActions {
-		maxCount = maxCountHistoryWithMessageActions
-	}
-
-	if len(o.Channels) > 1 {
-		maxCount = maxCountFetchMoreThanOneChannel
-	}
+	maxCount := o.maxCount()
 
 	if o.Count > 0 && o.Count <= maxCount {
 		q.Set("max", strconv.Itoa(o.Count))
@@ -221,6 +235,17 @@ func (o *fetchOpts) buildQuery() (*url.Values, error) {
 	return q, nil
 }
 
+// maxCount returns the most messages per channel returned by one request.
+func (o *fetchOpts) maxCount() int {
+	if o.WithMessageActions {
+		return maxCountHistoryWithMessageActions
+	}
+	if len(o.Channels) > 1 {
+		return maxCountFetchMoreThanOneChannel
+	}
+	return maxCountFetch
+}
+
 func (o *fetchOpts) operationType() OperationType {
 	return PNFetchMessagesOperation
 }
@@ -290,13 +315,21 @@ func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]
 
 			for _, val := range histResponseMap {
 				if histResponse, ok3 := val.(map[string]interface{}); ok3 {
-					msg, _ := parseCipherInterface(histResponse["message"], o.pubnub.Config)
+					msg := histResponse["message"]
+					var decryptionErr *DecryptionError
+					if !o.keepEncrypted {
+						timetoken, _ := strconv.ParseInt(histResponse["timetoken"].(string), 10, 64)
+						msg, decryptionErr = decryptPayload(msg, channel, timetoken, o.pubnub.Config)
+					}
 
 					histItem := FetchResponseItem{
 						Message:   msg,
 						Timetoken: histResponse["timetoken"].(string),
 						Meta:      histResponse["meta"],
 					}
+					if decryptionErr != nil {
+						histItem.Error = decryptionErr
+					}
 					if d, ok := histResponse["message_type"]; ok {
 						switch v := d.(type) {
 						case float64:
@@ -321,7 +354,7 @@ func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]
 						histItem.UUID = d.(string)
 					}
 					histItem.MessageActions = o.parseMessageActions(histResponse["actions"])
-					if filesPayload, okFile := msg.(map[string]interface{}); okFile {
+					if filesPayload, okFile := msg.(map[string]interface{}); okFile && !o.keepEncrypted {
 						f, m := ParseFileInfo(filesPayload)
 
 						if f.Name != "" && f.ID != "" {
@@ -382,6 +415,9 @@ func newFetchResponse(jsonBytes []byte, o *fetchOpts,
 // FetchResponse is the response to Fetch request. It contains a map of type FetchResponseItem
 type FetchResponse struct {
 	Messages map[string][]FetchResponseItem
+	// Errors are the errors of the channels whose messages could not all be
+	// fetched, when the Fetch ran in batches.
+	Errors map[string]error
 }
 
 // FetchResponseItem contains the message and the associated timetoken.
@@ -393,6 +429,9 @@ type FetchResponseItem struct {
 	Timetoken      string                                    `json:"timetoken"`
 	UUID           string                                    `json:"uuid"`
 	MessageType    int                                       `json:"message_type"`
+	// Error is a *DecryptionError when the message could not be decrypted,
+	// Message is then the message as stored.
+	Error error `json:"-"`
 }
 
 // PNHistoryMessageActionsTypeMap is the struct used in the Fetch request that includes Message Actions
@@ -3,11 +3,11 @@ package pubnub
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
 
-	"github.com/pubnub/go/v7/utils"
+	"github.com/pubnub/go/v7/pnerr"
 )
 
 var emptyDownloadFileResponse *PNDownloadFileResponse
@@ -16,6 +16,10 @@ const downloadFilePath = "/v1/files/%s/channels/%s/files/%s/%s"
 
 const downloadFileLimit = 100
 
+// downloadFileResumeAttempts is the default number of times To resumes a
+// download after the connection dropped.
+const downloadFileResumeAttempts = 3
+
 type downloadFileBuilder struct {
 	opts *downloadFileOpts
 }
@@ -25,7 +29,11 @@ func newDownloadFileBuilder(pubnub *PubNub) *downloadFileBuilder {
 }
 
 func newDownloadFileOpts(pubnub *PubNub, ctx Context) *downloadFileOpts {
-	return &downloadFileOpts{endpointOpts: endpointOpts{pubnub: pubnub, ctx: ctx}}
+	return &downloadFileOpts{
+		endpointOpts:   endpointOpts{pubnub: pubnub, ctx: ctx},
+		ExpectedSize:   -1,
+		ResumeAttempts: downloadFileResumeAttempts,
+	}
 }
 func newDownloadFileBuilderWithContext(pubnub *PubNub,
 	context Context) *downloadFileBuilder {
@@ -71,8 +79,93 @@ func (b *downloadFileBuilder) Transport(tr http.RoundTripper) *downloadFileBuild
 	return b
 }
 
+// Progress sets the function called by To as the file is received, with the
+// bytes received and the size of the file, -1 when it is unknown.
+func (b *downloadFileBuilder) Progress(progress func(received, total int64)) *downloadFileBuilder {
+	b.opts.Progress = progress
+
+	return b
+}
+
+// ExpectedSize sets the size of the file, as listed by ListFiles. To fails
+// with a *DownloadTruncatedError when fewer bytes are received.
+func (b *downloadFileBuilder) ExpectedSize(size int64) *downloadFileBuilder {
+	b.opts.ExpectedSize = size
+
+	return b
+}
+
+// VerifySize makes To look the size of the file up with ListFiles when
+// ExpectedSize is not set.
+func (b *downloadFileBuilder) VerifySize(verify bool) *downloadFileBuilder {
+	b.opts.VerifySize = verify
+
+	return b
+}
+
+// ResumeAttempts sets how many times To resumes the download from the last
+// byte received after the connection dropped, 3 by default.
+func (b *downloadFileBuilder) ResumeAttempts(attempts int) *downloadFileBuilder {
+	b.opts.ResumeAttempts = attempts
+
+	return b
+}
+
+// To downloads the file to w, decrypting it as it is received. It returns the
+// number of bytes written to w. A download which can't be completed fails
+// with a *DownloadTruncatedError.
+func (b *downloadFileBuilder) To(w io.Writer) (int64, StatusResponse, error) {
+	stat := b.opts.status()
+	if err := b.opts.validate(); err != nil {
+		return 0, stat, err
+	}
+
+	total := b.opts.ExpectedSize
+	if total < 0 && b.opts.VerifySize {
+		size, err := b.opts.listedSize()
+		if err != nil {
+			return 0, stat, err
+		}
+		total = size
+	}
+
+	resp, err := b.opts.get(0)
+	if err != nil {
+		b.opts.pubnub.Config.Log.Printf("err %s", err)
+		return 0, stat, err
+	}
+	stat.StatusCode = resp.StatusCode
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		return 0, stat, pnerr.NewServerError(resp.StatusCode, resp.Body)
+	}
+	if total < 0 {
+		total = resp.ContentLength
+	}
+
+	download := &resumableDownload{
+		opts:  b.opts,
+		body:  resp.Body,
+		total: total,
+	}
+	defer download.Close()
+
+	var r io.Reader = download
+	if module := b.opts.pubnub.Config.fileCryptoModule(b.opts.Channel, b.opts.CipherKey); module != nil {
+		r, err = module.DecryptStream(download)
+		if err != nil {
+			b.opts.pubnub.Config.Log.Printf("err in decrypting file %s", err)
+			return 0, stat, err
+		}
+	}
+
+	written, err := io.Copy(w, r)
+	return written, stat, err
+}
+
+// Execute requests the file. The File of the response is decrypted as it is
+// read and is an io.ReadCloser, close it to release the connection.
 func (b *downloadFileBuilder) Execute() (*PNDownloadFileResponse, StatusResponse, error) {
-	u, _ := buildURL(b.opts)
 	stat := StatusResponse{
 		AffectedChannels: []string{b.opts.Channel},
 		AuthKey:          b.opts.config().AuthKey,
@@ -83,37 +176,27 @@ func (b *downloadFileBuilder) Execute() (*PNDownloadFileResponse, StatusResponse
 		Origin:           b.opts.config().Origin,
 		UUID:             b.opts.config().UUID,
 	}
-	b.opts.pubnub.Config.Log.Printf("u.RequestURI(): %s", u.RequestURI())
-	resp, err := b.opts.client().Get(u.RequestURI())
+	resp, err := b.opts.get(0)
 	if err != nil {
 		b.opts.pubnub.Config.Log.Printf("err %s", err)
 		return nil, stat, err
 	}
 	if resp.StatusCode != 200 {
+		resp.Body.Close()
 		stat.StatusCode = resp.StatusCode
 		return nil, stat, err
 	}
-	contentLenEnc, err := strconv.ParseInt(string(resp.Header.Get("Content-Length")), 10, 64)
-	if err != nil {
-		b.opts.pubnub.Config.Log.Printf("err in parsing content length %s", err)
-		return nil, stat, err
-	}
-
 	var respDL *PNDownloadFileResponse
-	if b.opts.CipherKey != "" {
-		r, w := io.Pipe()
-		utils.DecryptFile(b.opts.CipherKey, contentLenEnc, resp.Body, w)
-		respDL = &PNDownloadFileResponse{
-			File: r,
+	if module := b.opts.pubnub.Config.fileCryptoModule(b.opts.Channel, b.opts.CipherKey); module != nil {
+		r, err := module.DecryptStream(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			b.opts.pubnub.Config.Log.Printf("err in decrypting file %s", err)
+			return nil, stat, err
 		}
-
-	} else if b.opts.pubnub.Config.CipherKey != "" {
-		r, w := io.Pipe()
-		utils.DecryptFile(b.opts.pubnub.Config.CipherKey, contentLenEnc, resp.Body, w)
 		respDL = &PNDownloadFileResponse{
-			File: r,
+			File: &decryptedFile{Reader: r, body: resp.Body},
 		}
-
 	} else {
 		respDL = &PNDownloadFileResponse{
 			File: resp.Body,
@@ -122,6 +205,17 @@ func (b *downloadFileBuilder) Execute() (*PNDownloadFileResponse, StatusResponse
 	return respDL, stat, nil
 }
 
+// decryptedFile reads the decrypted file and closes the body it is decrypted
+// from.
+type decryptedFile struct {
+	io.Reader
+	body io.Closer
+}
+
+func (f *decryptedFile) Close() error {
+	return f.body.Close()
+}
+
 type downloadFileOpts struct {
 	endpointOpts
 	Channel    string
@@ -130,6 +224,11 @@ type downloadFileOpts struct {
 	Name       string
 	QueryParam map[string]string
 
+	Progress       func(received, total int64)
+	ExpectedSize   int64
+	VerifySize     bool
+	ResumeAttempts int
+
 	Transport http.RoundTripper
 }
 
@@ -170,6 +269,150 @@ func (o *downloadFileOpts) operationType() OperationType {
 	return PNDownloadFileOperation
 }
 
+func (o *downloadFileOpts) status() StatusResponse {
+	return StatusResponse{
+		AffectedChannels: []string{o.Channel},
+		AuthKey:          o.config().AuthKey,
+		Category:         PNUnknownCategory,
+		Operation:        PNDownloadFileOperation,
+		TLSEnabled:       o.config().Secure,
+		Origin:           o.config().Origin,
+		UUID:             o.config().UUID,
+	}
+}
+
+// get requests the file from the offset, with a Range header when it is not
+// 0. Cancelling the context of the opts aborts the request and the reading
+// of its body.
+func (o *downloadFileOpts) get(offset int64) (*http.Response, error) {
+	u, err := buildURL(o)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", u.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	if o.ctx != nil {
+		req = setRequestContext(req, o.ctx)
+	}
+	return o.client().Do(req)
+}
+
+// listedSize returns the size of the file listed by ListFiles.
+func (o *downloadFileOpts) listedSize() (int64, error) {
+	files := newListFilesBuilderWithContext(o.pubnub, o.ctx).Channel(o.Channel).Iterator()
+	for files.Next(o.ctx) {
+		if file := files.Item(); file.ID == o.ID {
+			return int64(file.Size), nil
+		}
+	}
+	if err := files.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("file %s is not listed on channel %s", o.ID, o.Channel)
+}
+
+// DownloadTruncatedError is returned by To when fewer bytes than the size of
+// the file were received, after the resume attempts.
+type DownloadTruncatedError struct {
+	// Expected is -1 when the size of the file is unknown.
+	Expected int64
+	Received int64
+	Err      error
+}
+
+func (e *DownloadTruncatedError) Error() string {
+	return fmt.Sprintf("pubnub: download truncated, received %d of %d bytes: %s", e.Received, e.Expected, e.Err)
+}
+
+func (e *DownloadTruncatedError) Unwrap() error {
+	return e.Err
+}
+
+// resumableDownload reads the body of the download and requests the rest of
+// the file from the last byte received when the connection drops.
+type resumableDownload struct {
+	opts     *downloadFileOpts
+	body     io.ReadCloser
+	total    int64
+	received int64
+	attempts int
+	err      error
+}
+
+func (d *resumableDownload) Read(p []byte) (int, error) {
+	for {
+		if d.err == nil {
+			var n int
+			n, d.err = d.body.Read(p)
+			if n > 0 {
+				d.received += int64(n)
+				if d.opts.Progress != nil {
+					d.opts.Progress(d.received, d.total)
+				}
+				if d.total >= 0 && d.received > d.total {
+					return 0, fmt.Errorf("pubnub: download larger than %d bytes", d.total)
+				}
+				return n, nil
+			}
+			if d.err == nil {
+				return 0, nil
+			}
+		}
+
+		if d.err == io.EOF && (d.total < 0 || d.received == d.total) {
+			return 0, io.EOF
+		}
+		if d.err == io.EOF {
+			d.err = io.ErrUnexpectedEOF
+		}
+		if d.opts.ctx != nil && d.opts.ctx.Err() != nil {
+			// cancelled, not dropped
+			return 0, d.opts.ctx.Err()
+		}
+		if d.attempts >= d.opts.ResumeAttempts {
+			return 0, &DownloadTruncatedError{Expected: d.total, Received: d.received, Err: d.err}
+		}
+		d.attempts++
+		d.opts.pubnub.Config.Log.Printf("resuming download at %d after: %s", d.received, d.err)
+		d.resume()
+	}
+}
+
+// resume requests the rest of the file, d.err is set when it fails.
+func (d *resumableDownload) resume() {
+	d.body.Close()
+	resp, err := d.opts.get(d.received)
+	if err != nil {
+		d.err = err
+		return
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// the range is ignored, the bytes already received are skipped
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, d.received); err != nil {
+			resp.Body.Close()
+			d.err = err
+			return
+		}
+	default:
+		d.err = pnerr.NewServerError(resp.StatusCode, resp.Body)
+		resp.Body.Close()
+		return
+	}
+	d.body = resp.Body
+	d.err = nil
+}
+
+func (d *resumableDownload) Close() error {
+	return d.body.Close()
+}
+
 // PNDownloadFileResponse is the File Upload API Response for Get Spaces
 type PNDownloadFileResponse struct {
 	status int       `json:"status"`
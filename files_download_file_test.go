@@ -0,0 +1,220 @@
+package pubnub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// droppedReader returns data then fails as a dropped connection does.
+type droppedReader struct {
+	data []byte
+}
+
+func (r *droppedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func newDroppedResponse(statusCode int, data []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    statusCode,
+		Header:        http.Header{},
+		ContentLength: -1,
+		Body:          ioutil.NopCloser(&droppedReader{data: data}),
+	}
+}
+
+func newDownloadFileTestBuilder(transport http.RoundTripper) *downloadFileBuilder {
+	config := NewDemoConfig()
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: transport})
+	return pn.DownloadFile().Channel("ch").ID("id").Name("file.txt")
+}
+
+func TestDownloadFileToResumesDroppedConnection(t *testing.T) {
+	assert := assert.New(t)
+	content := []byte("0123456789abcdef")
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newDroppedResponse(200, content[:6]),
+			newSequenceResponse(206, string(content[6:]), nil),
+		},
+	}
+	progress := []int64{}
+
+	w := &bytes.Buffer{}
+	n, _, err := newDownloadFileTestBuilder(transport).
+		ExpectedSize(int64(len(content))).
+		Progress(func(received, total int64) {
+			assert.Equal(int64(len(content)), total)
+			progress = append(progress, received)
+		}).
+		To(w)
+
+	assert.Nil(err)
+	assert.Equal(int64(len(content)), n)
+	assert.Equal(content, w.Bytes())
+	assert.Equal([]int64{6, 16}, progress)
+	assert.Len(transport.requests, 2)
+	assert.Equal("", transport.requests[0].Header.Get("Range"))
+	assert.Equal("bytes=6-", transport.requests[1].Header.Get("Range"))
+}
+
+func TestDownloadFileToSkipsReceivedBytesWhenRangeIgnored(t *testing.T) {
+	assert := assert.New(t)
+	content := []byte("0123456789abcdef")
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newDroppedResponse(200, content[:6]),
+			newSequenceResponse(200, string(content), nil),
+		},
+	}
+
+	w := &bytes.Buffer{}
+	_, _, err := newDownloadFileTestBuilder(transport).ExpectedSize(int64(len(content))).To(w)
+
+	assert.Nil(err)
+	assert.Equal(content, w.Bytes())
+}
+
+func TestDownloadFileToTruncated(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, "0123", nil),
+			newSequenceResponse(206, "", nil),
+		},
+	}
+
+	w := &bytes.Buffer{}
+	_, _, err := newDownloadFileTestBuilder(transport).
+		ExpectedSize(16).
+		ResumeAttempts(1).
+		To(w)
+
+	var truncated *DownloadTruncatedError
+	assert.True(errors.As(err, &truncated))
+	assert.Equal(int64(16), truncated.Expected)
+	assert.Equal(int64(4), truncated.Received)
+	assert.Equal(io.ErrUnexpectedEOF, truncated.Err)
+	assert.Len(transport.requests, 2)
+}
+
+func TestDownloadFileToVerifySize(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":[{"name":"other.txt","id":"other","size":3,"created":""}],"next":"n1","count":1}`, nil),
+			newSequenceResponse(200, `{"status":200,"data":[{"name":"file.txt","id":"id","size":8,"created":""}],"count":1}`, nil),
+			newSequenceResponse(200, "0123", nil),
+			newSequenceResponse(206, "", nil),
+		},
+	}
+
+	w := &bytes.Buffer{}
+	_, _, err := newDownloadFileTestBuilder(transport).
+		VerifySize(true).
+		ResumeAttempts(1).
+		To(w)
+
+	var truncated *DownloadTruncatedError
+	assert.True(errors.As(err, &truncated))
+	assert.Equal(int64(8), truncated.Expected)
+	assert.Equal("n1", transport.requests[1].URL.Query().Get("next"))
+}
+
+func TestDownloadFileToDecrypts(t *testing.T) {
+	assert := assert.New(t)
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	module := NewLegacyCryptoModule("enigma", true)
+	r, err := module.EncryptStream(bytes.NewReader(content))
+	assert.Nil(err)
+	encrypted, _ := ioutil.ReadAll(r)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newDroppedResponse(200, encrypted[:100]),
+			newSequenceResponse(206, string(encrypted[100:]), nil),
+		},
+	}
+
+	w := &bytes.Buffer{}
+	n, _, err := newDownloadFileTestBuilder(transport).
+		CipherKey("enigma").
+		ExpectedSize(int64(len(encrypted))).
+		To(w)
+
+	assert.Nil(err)
+	assert.Equal(int64(len(content)), n)
+	assert.Equal(content, w.Bytes())
+}
+
+func TestDownloadFileExecuteStreams(t *testing.T) {
+	assert := assert.New(t)
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	module := NewLegacyCryptoModule("enigma", true)
+	r, err := module.EncryptStream(bytes.NewReader(content))
+	assert.Nil(err)
+	encrypted, _ := ioutil.ReadAll(r)
+	body := &closeRecorder{Reader: bytes.NewReader(encrypted)}
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			{StatusCode: 200, Header: http.Header{}, ContentLength: -1, Body: body},
+		},
+	}
+
+	resp, _, err := newDownloadFileTestBuilder(transport).CipherKey("enigma").Execute()
+	assert.Nil(err)
+
+	// the file is not read in memory by Execute
+	assert.True(body.Len() > 0)
+	decrypted, err := ioutil.ReadAll(resp.File)
+	assert.Nil(err)
+	assert.Equal(content, decrypted)
+
+	closer, ok := resp.File.(io.Closer)
+	assert.True(ok)
+	assert.Nil(closer.Close())
+	assert.True(body.closed)
+}
+
+func TestDownloadFileToCancelled(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := contextWithCancel(backgroundContext)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newDroppedResponse(200, []byte("012345")),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	n, _, err := pn.DownloadFileWithContext(ctx).Channel("ch").ID("id").Name("file.txt").
+		Progress(func(received, total int64) { cancel() }).
+		To(&bytes.Buffer{})
+
+	assert.Equal(int64(6), n)
+	assert.True(errors.Is(err, context.Canceled))
+	assert.Len(transport.requests, 1)
+}
+
+// closeRecorder is a response body recording that it was closed.
+type closeRecorder struct {
+	*bytes.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
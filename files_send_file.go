@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -84,6 +85,38 @@ func (b *sendFileBuilder) File(f *os.File) *sendFileBuilder {
 	return b
 }
 
+// Reader sets the content of the file, streamed from r as it is uploaded
+// instead of a File. size is the number of bytes of r, -1 when it is
+// unknown: the content is then read in memory to find it.
+//
+// Only the files sent without encryption or with the legacy AES-CBC cryptor
+// are streamed. The AES-256-GCM cryptor has no streaming form, it encrypts
+// the whole file in memory, so does any size of -1.
+func (b *sendFileBuilder) Reader(r io.Reader, size int64) *sendFileBuilder {
+	b.opts.Reader = r
+	b.opts.Size = size
+
+	return b
+}
+
+// Progress sets the callback called as the file is uploaded, with the bytes
+// sent and the size of the upload request body.
+func (b *sendFileBuilder) Progress(progress func(sent, total int64)) *sendFileBuilder {
+	b.opts.Progress = progress
+
+	return b
+}
+
+// DeleteOnPublishFailure deletes the uploaded file with DeleteFile when its
+// message can't be published after FileMessagePublishRetryLimit tries. Else
+// the file is kept and the *FileMessagePublishError returned can publish the
+// message again.
+func (b *sendFileBuilder) DeleteOnPublishFailure(deleteFile bool) *sendFileBuilder {
+	b.opts.DeleteOnPublishFailure = deleteFile
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *sendFileBuilder) QueryParam(queryParam map[string]string) *sendFileBuilder {
 	b.opts.QueryParam = queryParam
@@ -114,12 +147,17 @@ type sendFileOpts struct {
 	Name        string
 	Message     string
 	File        *os.File
+	Reader      io.Reader
+	Size        int64
+	Progress    func(sent, total int64)
 	CipherKey   string
 	TTL         int
 	Meta        interface{}
 	ShouldStore bool
 	QueryParam  map[string]string
 
+	DeleteOnPublishFailure bool
+
 	Transport http.RoundTripper
 }
 
@@ -135,6 +173,10 @@ func (o *sendFileOpts) validate() error {
 	if o.Name == "" {
 		return newValidationError(o, StrMissingFileName)
 	}
+
+	if o.File == nil && o.Reader == nil {
+		return newValidationError(o, StrMissingFile)
+	}
 	return nil
 }
 
@@ -192,7 +234,6 @@ type PNSendFileResponse struct {
 	Data      PNFileData `json:"data"`
 }
 
-// TODO Add retry on publish failure
 func newPNSendFileResponse(jsonBytes []byte, o *sendFileOpts,
 	status StatusResponse) (*PNSendFileResponse, StatusResponse, error) {
 
@@ -210,6 +251,12 @@ func newPNSendFileResponse(jsonBytes []byte, o *sendFileOpts,
 	} else {
 		s = newSendFileToS3Builder(o.pubnub)
 	}
+	s.opts.channel = o.Channel
+	s.opts.name = o.Name
+	if o.Reader != nil {
+		s.Reader(o.Reader, o.Size)
+	}
+	s.Progress(o.Progress)
 	_, s3ResponseStatus, errS3Response := s.File(o.File).CipherKey(o.CipherKey).FileUploadRequestData(respForS3.FileUploadRequest).Execute()
 	if s3ResponseStatus.StatusCode != 204 {
 		o.pubnub.Config.Log.Printf("s3ResponseStatus: %d", s3ResponseStatus.StatusCode)
@@ -240,7 +287,7 @@ func newPNSendFileResponse(jsonBytes []byte, o *sendFileOpts,
 		if errPubFileResponse != nil {
 			if tryCount >= maxCount {
 				pubFileResponseStatus.AdditionalData = file
-				return emptySendFileResponse, pubFileResponseStatus, errPubFileResponse
+				return emptySendFileResponse, pubFileResponseStatus, o.publishFailed(message, errPubFileResponse)
 			}
 			continue
 		} else {
@@ -257,3 +304,74 @@ func newPNSendFileResponse(jsonBytes []byte, o *sendFileOpts,
 
 	return resp, status, nil
 }
+
+// publishFailed deletes the uploaded file when DeleteOnPublishFailure is set
+// and returns the *FileMessagePublishError of err.
+func (o *sendFileOpts) publishFailed(message PNPublishFileMessage, err error) *FileMessagePublishError {
+	publishErr := &FileMessagePublishError{
+		Channel:     o.Channel,
+		ID:          message.PNFile.ID,
+		Name:        message.PNFile.Name,
+		Message:     message,
+		TTL:         o.TTL,
+		Meta:        o.Meta,
+		ShouldStore: o.ShouldStore,
+		Err:         err,
+		pubnub:      o.pubnub,
+	}
+	if !o.DeleteOnPublishFailure {
+		return publishErr
+	}
+
+	_, _, errDelete := o.pubnub.DeleteFile().Channel(o.Channel).ID(publishErr.ID).Name(publishErr.Name).Execute()
+	if errDelete != nil {
+		o.pubnub.Config.Log.Printf("err deleting file %s after publish failure: %s", publishErr.ID, errDelete)
+		publishErr.DeleteErr = errDelete
+	} else {
+		publishErr.Deleted = true
+	}
+	return publishErr
+}
+
+// FileMessagePublishError is returned by SendFile when the file was uploaded
+// but its message could not be published. Unless the file was deleted, the
+// message is published again with RetryPublish.
+type FileMessagePublishError struct {
+	Channel     string
+	ID          string
+	Name        string
+	Message     PNPublishFileMessage
+	TTL         int
+	Meta        interface{}
+	ShouldStore bool
+	// Deleted is true when the file was deleted by DeleteOnPublishFailure,
+	// DeleteErr is the error of the deletion otherwise.
+	Deleted   bool
+	DeleteErr error
+	Err       error
+
+	pubnub *PubNub
+}
+
+func (e *FileMessagePublishError) Error() string {
+	return fmt.Sprintf("pubnub: file %s uploaded to channel %s but its message was not published: %s", e.ID, e.Channel, e.Err)
+}
+
+func (e *FileMessagePublishError) Unwrap() error {
+	return e.Err
+}
+
+// RetryPublish publishes the message of the uploaded file again.
+func (e *FileMessagePublishError) RetryPublish() (*PNSendFileResponse, StatusResponse, error) {
+	if e.Deleted {
+		return emptySendFileResponse, StatusResponse{}, fmt.Errorf("pubnub: file %s was deleted", e.ID)
+	}
+	pubResp, status, err := e.pubnub.PublishFileMessage().TTL(e.TTL).Meta(e.Meta).ShouldStore(e.ShouldStore).Channel(e.Channel).Message(e.Message).Execute()
+	if err != nil {
+		return emptySendFileResponse, status, err
+	}
+	return &PNSendFileResponse{
+		Timestamp: pubResp.Timestamp,
+		Data:      PNFileData{ID: e.ID},
+	}, status, nil
+}
@@ -1,7 +1,10 @@
 package pubnub
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	h "github.com/pubnub/go/v7/tests/helpers"
@@ -66,3 +69,75 @@ func TestSendFileResponseValueError(t *testing.T) {
 	_, _, err := newPNSendFileResponse(jsonBytes, opts, StatusResponse{})
 	assert.Equal("pubnub/parsing: Error unmarshalling response: {s}", err.Error())
 }
+
+func newSendFilePublishFailureTransport(last string) *sequenceTransport {
+	return &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":{"id":"fid","name":"file.txt"},"file_upload_request":{"url":"https://s3.example.com/","method":"POST","form_fields":[]}}`, nil),
+			newSequenceResponse(204, "", nil),
+			newSequenceResponse(400, `{"status":400,"error":true}`, nil),
+			newSequenceResponse(400, `{"status":400,"error":true}`, nil),
+			newSequenceResponse(200, last, nil),
+		},
+	}
+}
+
+func TestSendFilePublishFailureKeepsFile(t *testing.T) {
+	assert := assert.New(t)
+	config := NewDemoConfig()
+	config.FileMessagePublishRetryLimit = 2
+	pn := NewPubNub(config)
+	transport := newSendFilePublishFailureTransport(`[1,"Sent","15000000000000000"]`)
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, _, err := pn.SendFile().Channel("ch").Name("file.txt").Message("hi").
+		Reader(strings.NewReader("content"), 7).Execute()
+
+	var publishErr *FileMessagePublishError
+	assert.True(errors.As(err, &publishErr))
+	assert.Equal("ch", publishErr.Channel)
+	assert.Equal("fid", publishErr.ID)
+	assert.Equal("file.txt", publishErr.Name)
+	assert.False(publishErr.Deleted)
+	assert.Len(transport.requests, 4)
+
+	resp, _, err := publishErr.RetryPublish()
+	assert.Nil(err)
+	assert.Equal("fid", resp.Data.ID)
+	assert.Equal(int64(15000000000000000), resp.Timestamp)
+	assert.Contains(transport.requests[4].URL.String(), "/publish-file/")
+}
+
+func TestSendFileDeleteOnPublishFailure(t *testing.T) {
+	assert := assert.New(t)
+	config := NewDemoConfig()
+	config.FileMessagePublishRetryLimit = 2
+	pn := NewPubNub(config)
+	transport := newSendFilePublishFailureTransport(`{"status":200}`)
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, _, err := pn.SendFile().Channel("ch").Name("file.txt").
+		Reader(strings.NewReader("content"), 7).DeleteOnPublishFailure(true).Execute()
+
+	var publishErr *FileMessagePublishError
+	assert.True(errors.As(err, &publishErr))
+	assert.True(publishErr.Deleted)
+	assert.Len(transport.requests, 5)
+	assert.Equal("DELETE", transport.requests[4].Method)
+	assert.Contains(transport.requests[4].URL.String(), "/files/fid/file.txt")
+
+	_, _, err = publishErr.RetryPublish()
+	assert.NotNil(err)
+}
+
+func TestSendFileValidateMissingFile(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	_, _, err := pn.SendFile().Channel("ch").Name("file.txt").Execute()
+	assert.Contains(err.Error(), StrMissingFile)
+
+	o := newSendFileBuilder(pn)
+	o.Channel("ch").Name("file.txt").Reader(strings.NewReader("content"), 7)
+	assert.Nil(o.opts.validate())
+}
@@ -1,8 +1,10 @@
 package pubnub
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
@@ -11,7 +13,6 @@ import (
 	"os"
 
 	"github.com/pubnub/go/v7/pnerr"
-	"github.com/pubnub/go/v7/utils"
 )
 
 var emptySendFileToS3Response *PNSendFileToS3Response
@@ -52,6 +53,25 @@ func (b *sendFileToS3Builder) File(f *os.File) *sendFileToS3Builder {
 	return b
 }
 
+// Reader sets the content of the file, streamed from r as it is uploaded.
+// size is the number of bytes of r, -1 when it is unknown: the content is
+// then read in memory to find it. The files encrypted with the AES-256-GCM
+// cryptor are read in memory too.
+func (b *sendFileToS3Builder) Reader(r io.Reader, size int64) *sendFileToS3Builder {
+	b.opts.reader = r
+	b.opts.size = size
+
+	return b
+}
+
+// Progress sets the callback called as the request body is sent, with the
+// bytes sent and the size of the body.
+func (b *sendFileToS3Builder) Progress(progress func(sent, total int64)) *sendFileToS3Builder {
+	b.opts.progress = progress
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *sendFileToS3Builder) QueryParam(queryParam map[string]string) *sendFileToS3Builder {
 	b.opts.QueryParam = queryParam
@@ -83,6 +103,13 @@ type sendFileToS3Opts struct {
 	QueryParam            map[string]string
 	CipherKey             string
 	Transport             http.RoundTripper
+
+	// channel selects the CryptoModule when Config.CipherKeyResolver is set
+	channel  string
+	reader   io.Reader
+	size     int64
+	name     string
+	progress func(sent, total int64)
 }
 
 func (o *sendFileToS3Opts) validate() error {
@@ -101,21 +128,48 @@ func (o *sendFileToS3Opts) buildQuery() (*url.Values, error) {
 	return &url.Values{}, nil
 }
 
-func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error) {
-
-	fileInfo, _ := o.File.Stat()
-	s := fileInfo.Size()
-	buffer := make([]byte, 512)
-	_, err := o.File.Read(buffer)
+// buildBodyMultipartFileUpload returns the multipart body streaming the file,
+// encrypted as it is read, with its content type and length.
+func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (io.Reader, string, int64, error) {
+	file, size, name, err := o.source()
 	if err != nil {
-		return bytes.Buffer{}, nil, s, err
+		return nil, "", 0, err
 	}
-	o.File.Seek(0, 0)
-	contentType := http.DetectContentType(buffer)
 
-	var fileBody bytes.Buffer
-	writer := multipart.NewWriter(&fileBody)
+	// the content type is detected without consuming the file
+	buffered := bufio.NewReaderSize(file, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, "", 0, err
+	}
+	contentType := http.DetectContentType(head)
+	var content io.Reader = buffered
+
+	if module := o.pubnub.Config.fileCryptoModule(o.channel, o.CipherKey); module != nil {
+		encrypted, errEncrypt := module.EncryptStream(content)
+		if errEncrypt != nil {
+			o.pubnub.Config.Log.Printf("ERROR: encrypt file: %s\n", errEncrypt.Error())
+			return nil, "", 0, errEncrypt
+		}
+		content = encrypted
+		var ok bool
+		if size, ok = encryptedStreamSize(module, encrypted, size); !ok {
+			size = -1
+		}
+	}
+	if size < 0 {
+		// the length of the body is required, the content is spooled to
+		// find it
+		data, errRead := ioutil.ReadAll(content)
+		if errRead != nil {
+			return nil, "", 0, errRead
+		}
+		content = bytes.NewReader(data)
+		size = int64(len(data))
+	}
 
+	var framing bytes.Buffer
+	writer := multipart.NewWriter(&framing)
 	for _, v := range o.FileUploadRequestData.FormFields {
 		o.pubnub.Config.Log.Printf("FormFields: Key: %s Value: %s\n", v.Key, v.Value)
 		if v.Key == "Content-Type" {
@@ -123,35 +177,61 @@ func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (bytes.Buffer, *multip
 		}
 		_ = writer.WriteField(v.Key, v.Value)
 	}
-
-	filePart, errFilePart := writer.CreateFormFile("file", fileInfo.Name())
-
-	if errFilePart != nil {
+	if _, errFilePart := writer.CreateFormFile("file", name); errFilePart != nil {
 		o.pubnub.Config.Log.Printf("ERROR: writer CreateFormFile: %s\n", errFilePart.Error())
-		return bytes.Buffer{}, writer, s, errFilePart
+		return nil, "", 0, errFilePart
 	}
-
-	if o.CipherKey != "" {
-		utils.EncryptFile(o.CipherKey, []byte{}, filePart, o.File)
-	} else if o.pubnub.Config.CipherKey != "" {
-		utils.EncryptFile(o.pubnub.Config.CipherKey, []byte{}, filePart, o.File)
-	} else {
-		_, errIOCopy := io.Copy(filePart, o.File)
-
-		if errIOCopy != nil {
-			o.pubnub.Config.Log.Printf("ERROR: io Copy error: %s\n", errIOCopy.Error())
-			return bytes.Buffer{}, writer, s, errIOCopy
+	header := append([]byte{}, framing.Bytes()...)
+	framing.Reset()
+	if errWriterClose := writer.Close(); errWriterClose != nil {
+		o.pubnub.Config.Log.Printf("ERROR: Writer close: %s\n", errWriterClose.Error())
+		return nil, "", 0, errWriterClose
+	}
+	trailer := framing.Bytes()
+
+	total := int64(len(header)) + size + int64(len(trailer))
+	var body io.Reader = io.MultiReader(bytes.NewReader(header), content, bytes.NewReader(trailer))
+	if o.progress != nil {
+		body = &progressReader{
+			r:        body,
+			total:    total,
+			progress: o.progress,
 		}
 	}
+	return body, writer.FormDataContentType(), total, nil
+}
 
-	errWriterClose := writer.Close()
-	if errWriterClose != nil {
-		o.pubnub.Config.Log.Printf("ERROR: Writer close: %s\n", errWriterClose.Error())
-		return bytes.Buffer{}, writer, s, errWriterClose
+// source returns the file to upload, its size, -1 when it is unknown, and its
+// name.
+func (o *sendFileToS3Opts) source() (io.Reader, int64, string, error) {
+	if o.reader != nil {
+		return o.reader, o.size, o.name, nil
+	}
+	if o.File == nil {
+		return nil, 0, "", errors.New("pubnub: no file to upload")
+	}
+	fileInfo, err := o.File.Stat()
+	if err != nil {
+		return nil, 0, "", err
 	}
+	return o.File, fileInfo.Size(), fileInfo.Name(), nil
+}
 
-	return fileBody, writer, s, nil
+// progressReader reports the bytes read from r.
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
 
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
 }
 
 func (o *sendFileToS3Opts) httpMethod() string {
@@ -0,0 +1,115 @@
+package pubnub
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readUploadBody parses the multipart body and returns its form fields and
+// file content, checking its length.
+func readUploadBody(t *testing.T, o *sendFileToS3Opts) (map[string]string, []byte) {
+	assert := assert.New(t)
+
+	body, contentType, length, err := o.buildBodyMultipartFileUpload()
+	assert.Nil(err)
+	data, err := ioutil.ReadAll(body)
+	assert.Nil(err)
+	assert.Equal(length, int64(len(data)))
+
+	_, params, err := mime.ParseMediaType(contentType)
+	assert.Nil(err)
+	fields := make(map[string]string)
+	var file []byte
+	reader := multipart.NewReader(bytes.NewReader(data), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		value, _ := ioutil.ReadAll(part)
+		if part.FormName() == "file" {
+			file = value
+		} else {
+			fields[part.FormName()] = string(value)
+		}
+	}
+	return fields, file
+}
+
+func newTestSendFileToS3Builder(pn *PubNub) *sendFileToS3Builder {
+	return newSendFileToS3Builder(pn).FileUploadRequestData(PNFileUploadRequest{
+		FormFields: []PNFormField{
+			{Key: "key", Value: "file-key"},
+			{Key: "Content-Type", Value: ""},
+		},
+	})
+}
+
+func TestSendFileToS3Reader(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	content := bytes.Repeat([]byte("streamed content "), 100)
+
+	var sent, total int64
+	b := newTestSendFileToS3Builder(pn).Reader(bytes.NewReader(content), int64(len(content))).
+		Progress(func(s, t int64) {
+			assert.True(s > sent)
+			sent, total = s, t
+		})
+
+	fields, file := readUploadBody(t, b.opts)
+	assert.Equal(content, file)
+	assert.Equal("file-key", fields["key"])
+	assert.Equal("text/plain; charset=utf-8", fields["Content-Type"])
+	assert.Equal(total, sent)
+}
+
+func TestSendFileToS3ReaderUnknownSize(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	content := []byte("short")
+
+	_, file := readUploadBody(t, newTestSendFileToS3Builder(pn).Reader(bytes.NewReader(content), -1).opts)
+	assert.Equal(content, file)
+}
+
+func TestSendFileToS3ReaderEncrypted(t *testing.T) {
+	assert := assert.New(t)
+	content := bytes.Repeat([]byte("streamed content "), 100)
+
+	for _, module := range []CryptoModule{
+		NewLegacyCryptoModule("enigma", false),
+		NewAesGcmCryptoModule("enigma", false),
+		NewKeyring(KeyringKey{ID: "k1", Module: NewLegacyCryptoModule("enigma", false)}),
+	} {
+		pn := NewPubNub(NewDemoConfig())
+		pn.Config.CryptoModule = module
+
+		_, file := readUploadBody(t, newTestSendFileToS3Builder(pn).Reader(bytes.NewReader(content), int64(len(content))).opts)
+		decrypted, err := module.DecryptStream(bytes.NewReader(file))
+		assert.Nil(err)
+		plain, _ := ioutil.ReadAll(decrypted)
+		assert.Equal(content, plain)
+	}
+}
+
+func TestEncryptedStreamSize(t *testing.T) {
+	assert := assert.New(t)
+	module := NewKeyring(KeyringKey{ID: "k1", Module: NewLegacyCryptoModule("enigma", false)})
+
+	encrypted, _ := module.EncryptStream(bytes.NewReader(make([]byte, 100)))
+	size, ok := encryptedStreamSize(module, encrypted, 100)
+	assert.True(ok)
+	data, _ := ioutil.ReadAll(encrypted)
+	assert.Equal(int64(len(data)), size)
+
+	_, ok = encryptedStreamSize(module, encrypted, -1)
+	assert.False(ok)
+}
@@ -106,6 +106,14 @@ func (b *fireBuilder) Transport(tr http.RoundTripper) *fireBuilder {
 	return b
 }
 
+// DisableRetry when true sends the Fire request only once, even if Config.RetryPolicy
+// would retry it. Use it when a duplicate message is worse than a lost one.
+func (b *fireBuilder) DisableRetry(disable bool) *fireBuilder {
+	b.opts.disableRetry = disable
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *fireBuilder) QueryParam(queryParam map[string]string) *fireBuilder {
 	b.opts.QueryParam = queryParam
@@ -157,8 +165,11 @@ func (o *fireOpts) buildPath() (string, error) {
 	var message []byte
 	var err error
 
-	if cipherKey := o.pubnub.Config.CipherKey; cipherKey != "" {
-		msg := utils.EncryptString(cipherKey, string(message), o.pubnub.Config.UseRandomInitializationVector)
+	if module := o.pubnub.Config.cryptoModuleFor(o.Channel); module != nil {
+		msg, err := encryptString(module, string(message))
+		if err != nil {
+			return "", err
+		}
 
 		o.Message = []byte(msg)
 	}
@@ -222,8 +233,11 @@ func (o *fireOpts) buildBody() ([]byte, error) {
 			}
 		}
 
-		if cipherKey := o.pubnub.Config.CipherKey; cipherKey != "" {
-			enc := utils.EncryptString(cipherKey, string(msg), o.pubnub.Config.UseRandomInitializationVector)
+		if module := o.pubnub.Config.cryptoModuleFor(o.Channel); module != nil {
+			enc, err := encryptString(module, string(msg))
+			if err != nil {
+				return []byte{}, err
+			}
 			msg, err := utils.ValueAsString(enc)
 			if err != nil {
 				return []byte{}, err
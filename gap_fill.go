@@ -0,0 +1,273 @@
+package pubnub
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gapFiller recovers the messages published while the subscribe loop was
+// reconnecting. It remembers the timetoken of the last message delivered on
+// each channel, fetches the newer ones from history after a reconnection and
+// drops the live messages which were delivered already.
+//
+// Only messages and files are tracked, signals and the other events are not
+// stored in history.
+type gapFiller struct {
+	sync.Mutex
+	pubnub *PubNub
+	marks  map[string]gapFillMark
+}
+
+// gapFillMark is the last timetoken delivered on a channel and the channel
+// group or wildcard it was received through, if any.
+type gapFillMark struct {
+	timetoken    int64
+	subscription string
+}
+
+// gapFillResult is the outcome of a gap fill.
+type gapFillResult struct {
+	channels  []string
+	recovered int
+	lost      int
+	err       error
+}
+
+func newGapFiller(pubnub *PubNub) *gapFiller {
+	return &gapFiller{
+		pubnub: pubnub,
+		marks:  make(map[string]gapFillMark),
+	}
+}
+
+// track records a message delivered on the channel. It returns false when a
+// message with the same or a newer timetoken was delivered on the channel
+// before, so the message is a duplicate.
+func (g *gapFiller) track(channel, subscriptionMatch string, timetoken int64) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	if mark, ok := g.marks[channel]; ok && timetoken <= mark.timetoken {
+		return false
+	}
+	g.marks[channel] = gapFillMark{
+		timetoken:    timetoken,
+		subscription: subscriptionMatch,
+	}
+	return true
+}
+
+// seed marks the channels which have no mark yet with the timetoken the
+// subscribe loop reached, so the messages published on a channel that was
+// quiet before the disconnection are recovered too. Presence channels and
+// wildcards are not stored in history and are skipped.
+func (g *gapFiller) seed(channels []string, timetoken int64) {
+	if timetoken == 0 {
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	for _, channel := range channels {
+		if strings.HasSuffix(channel, "-pnpres") || strings.HasSuffix(channel, ".*") {
+			continue
+		}
+		if _, ok := g.marks[channel]; !ok {
+			g.marks[channel] = gapFillMark{timetoken: timetoken}
+		}
+	}
+}
+
+// pending returns the marks of the channels which are still subscribed,
+// directly or through a channel group or a wildcard.
+func (g *gapFiller) pending(channels, groups []string) map[string]gapFillMark {
+	subscribed := make(map[string]bool, len(channels)+len(groups))
+	for _, ch := range channels {
+		subscribed[ch] = true
+	}
+	for _, cg := range groups {
+		subscribed[cg] = true
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	pending := make(map[string]gapFillMark)
+	for channel, mark := range g.marks {
+		name := channel
+		if mark.subscription != "" {
+			name = mark.subscription
+		}
+		if subscribed[name] {
+			pending[channel] = mark
+		} else {
+			delete(g.marks, channel)
+		}
+	}
+	return pending
+}
+
+// fill fetches the messages published after the marks of the subscribed
+// channels and pushes them in timetoken order to push. At most
+// Config.GapFillMaxMessages are fetched per channel, the messages left over
+// and the ones of the channels which failed are counted as lost.
+func (g *gapFiller) fill(channels, groups []string, push func(message subscribeMessage)) gapFillResult {
+	pending := g.pending(channels, groups)
+	result := gapFillResult{
+		channels: make([]string, 0, len(pending)),
+	}
+	if len(pending) == 0 {
+		return result
+	}
+
+	now := time.Now().UnixNano() / 100
+	recovered := []subscribeMessage{}
+	for channel, mark := range pending {
+		result.channels = append(result.channels, channel)
+
+		messages, complete, err := g.fetchSince(channel, mark.subscription, mark.timetoken, now)
+		recovered = append(recovered, messages...)
+		result.recovered += len(messages)
+		if err != nil {
+			g.pubnub.Config.Log.Println("gap fill: fetch failed", channel, err)
+			result.err = err
+		}
+		if !complete {
+			result.lost += g.countLost(channel, mark.timetoken, now, len(messages))
+		}
+	}
+	sort.Strings(result.channels)
+
+	sort.SliceStable(recovered, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(recovered[i].PublishMetaData.PublishTimetoken, 10, 64)
+		tj, _ := strconv.ParseInt(recovered[j].PublishMetaData.PublishTimetoken, 10, 64)
+		return ti < tj
+	})
+	for _, message := range recovered {
+		push(message)
+	}
+
+	return result
+}
+
+// fetchSince pages through the history of the channel from now back to
+// since. The messages are matched to the channel group or wildcard the
+// channel was received through. It returns false when it stopped before
+// reaching since.
+func (g *gapFiller) fetchSince(channel, subscriptionMatch string, since, now int64) ([]subscribeMessage, bool, error) {
+	limit := g.pubnub.Config.GapFillMaxMessages
+	messages := []subscribeMessage{}
+	start := now
+
+	for len(messages) < limit {
+		opts := newFetchOpts(g.pubnub, g.pubnub.ctx, fetchOpts{
+			Channels:      []string{channel},
+			Start:         start,
+			End:           since,
+			Count:         maxCountFetch,
+			setStart:      true,
+			setEnd:        true,
+			keepEncrypted: true,
+		})
+		rawJSON, status, err := executeRequest(opts)
+		if err != nil {
+			return messages, false, err
+		}
+		resp, _, err := newFetchResponse(rawJSON, opts, status)
+		if err != nil {
+			return messages, false, err
+		}
+
+		items := resp.Messages[channel]
+		page := make([]subscribeMessage, 0, len(items))
+		oldest := start
+		for _, item := range items {
+			timetoken, err := strconv.ParseInt(item.Timetoken, 10, 64)
+			if err != nil || timetoken <= since {
+				continue
+			}
+			if timetoken < oldest {
+				oldest = timetoken
+			}
+			page = append(page, subscribeMessage{
+				Channel:           channel,
+				SubscriptionMatch: subscriptionMatch,
+				IssuingClientID:   item.UUID,
+				Payload:           item.Message,
+				UserMetadata:      item.Meta,
+				MessageType:       PNMessageType(item.MessageType),
+				PublishMetaData:   publishMetadata{PublishTimetoken: item.Timetoken},
+			})
+		}
+
+		// pages are fetched from the newest to the oldest
+		messages = append(page, messages...)
+		if len(items) < maxCountFetch || oldest == start {
+			return trimOldest(messages, limit), true, nil
+		}
+		start = oldest
+	}
+
+	return trimOldest(messages, limit), false, nil
+}
+
+// trimOldest keeps the newest limit messages.
+func trimOldest(messages []subscribeMessage, limit int) []subscribeMessage {
+	if len(messages) > limit {
+		return messages[len(messages)-limit:]
+	}
+	return messages
+}
+
+// countLost returns how many of the messages published on the channel
+// between since and now were not recovered, using the message counts. The
+// messages published after now are left to the subscribe loop.
+func (g *gapFiller) countLost(channel string, since, now int64, recovered int) int {
+	counts := make([]int, 0, 2)
+	for _, timetoken := range []int64{since, now} {
+		resp, _, err := g.pubnub.MessageCounts().Channels([]string{channel}).
+			ChannelsTimetoken([]int64{timetoken}).Execute()
+		if err != nil {
+			g.pubnub.Config.Log.Println("gap fill: message counts failed", channel, err)
+			return 0
+		}
+		counts = append(counts, resp.Channels[channel])
+	}
+	if lost := counts[0] - counts[1] - recovered; lost > 0 {
+		return lost
+	}
+	return 0
+}
+
+// fillGap recovers the messages missed by the subscribe loop while it was
+// reconnecting and announces a PNGapFillCategory status.
+func (m *SubscriptionManager) fillGap() {
+	result := m.gapFiller.fill(m.stateManager.prepareChannelList(false),
+		m.stateManager.prepareGroupList(false),
+		func(message subscribeMessage) {
+			m.messages <- message
+		})
+	if len(result.channels) == 0 {
+		return
+	}
+
+	pnStatus := &PNStatus{
+		Category:          PNGapFillCategory,
+		Operation:         PNSubscribeOperation,
+		AffectedChannels:  result.channels,
+		RecoveredMessages: result.recovered,
+		LostMessages:      result.lost,
+	}
+	if result.err != nil {
+		pnStatus.Error = true
+		pnStatus.ErrorData = fmt.Errorf("gap fill incomplete: %w", result.err)
+		pnStatus.StatusCode = statusCodeOf(result.err)
+	}
+	m.pubnub.Config.Log.Println("Status:", pnStatus)
+	m.listenerManager.announceStatus(pnStatus)
+}
@@ -0,0 +1,179 @@
+package pubnub
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGapFillerTrack(t *testing.T) {
+	assert := assert.New(t)
+	g := newGapFiller(NewPubNub(NewDemoConfig()))
+
+	assert.True(g.track("ch1", "", 10))
+	assert.True(g.track("ch1", "", 11))
+	assert.False(g.track("ch1", "", 11))
+	assert.False(g.track("ch1", "", 5))
+	assert.True(g.track("ch2", "", 5))
+}
+
+func TestGapFillerPending(t *testing.T) {
+	assert := assert.New(t)
+	g := newGapFiller(NewPubNub(NewDemoConfig()))
+
+	g.track("ch1", "", 10)
+	g.track("ch2", "", 20)
+	g.track("ch3", "cg1", 30)
+	g.track("ch4", "cg2", 40)
+
+	pending := g.pending([]string{"ch1"}, []string{"cg1"})
+	assert.Equal(map[string]gapFillMark{
+		"ch1": {timetoken: 10},
+		"ch3": {timetoken: 30, subscription: "cg1"},
+	}, pending)
+
+	// marks of unsubscribed channels are dropped
+	assert.True(g.track("ch2", "", 15))
+}
+
+func TestGapFillerFill(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"error":false,"error_message":"","channels":{"ch1":[{"message":"a","timetoken":"12","uuid":"u1"},{"message":"b","timetoken":"14","uuid":"u2","meta":{"k":"v"}}]}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	g := newGapFiller(pn)
+	g.track("ch1", "", 10)
+
+	pushed := []subscribeMessage{}
+	result := g.fill([]string{"ch1"}, []string{}, func(message subscribeMessage) {
+		pushed = append(pushed, message)
+	})
+
+	assert.Nil(result.err)
+	assert.Equal([]string{"ch1"}, result.channels)
+	assert.Equal(2, result.recovered)
+	assert.Equal(0, result.lost)
+	assert.Equal(2, len(pushed))
+	assert.Equal("a", pushed[0].Payload)
+	assert.Equal("12", pushed[0].PublishMetaData.PublishTimetoken)
+	assert.Equal("u2", pushed[1].IssuingClientID)
+	assert.Equal(map[string]interface{}{"k": "v"}, pushed[1].UserMetadata)
+
+	assert.Equal(1, len(transport.requests))
+	query := transport.requests[0].URL.Query()
+	assert.Equal("10", query.Get("end"))
+	assert.NotEqual("", query.Get("start"))
+}
+
+func TestGapFillerFillCountsLost(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(500, "", nil),
+			newSequenceResponse(200, `{"status":200,"error":false,"error_message":"","channels":{"ch1":5}}`, nil),
+			// published after the fill started, left to the subscribe loop
+			newSequenceResponse(200, `{"status":200,"error":false,"error_message":"","channels":{"ch1":2}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	g := newGapFiller(pn)
+	g.track("ch1", "", 10)
+
+	result := g.fill([]string{"ch1"}, []string{}, func(message subscribeMessage) {
+		assert.Fail("nothing to push")
+	})
+
+	assert.NotNil(result.err)
+	assert.Equal(0, result.recovered)
+	assert.Equal(3, result.lost)
+
+	if assert.Equal(3, len(transport.requests)) {
+		assert.Equal("10", transport.requests[1].URL.Query().Get("timetoken"))
+		assert.Equal(transport.requests[0].URL.Query().Get("start"), transport.requests[2].URL.Query().Get("timetoken"))
+	}
+}
+
+func TestGapFillerFillChannelGroup(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"error":false,"error_message":"","channels":{"ch1":[{"message":"a","timetoken":"12","uuid":"u1"}]}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	g := newGapFiller(pn)
+	g.track("ch1", "cg1", 10)
+
+	pushed := []subscribeMessage{}
+	result := g.fill([]string{}, []string{"cg1"}, func(message subscribeMessage) {
+		pushed = append(pushed, message)
+	})
+
+	assert.Nil(result.err)
+	assert.Equal([]string{"ch1"}, result.channels)
+	if assert.Equal(1, len(pushed)) {
+		assert.Equal("ch1", pushed[0].Channel)
+		assert.Equal("cg1", pushed[0].SubscriptionMatch)
+	}
+}
+
+func TestGapFillerFillNothingTracked(t *testing.T) {
+	assert := assert.New(t)
+	g := newGapFiller(NewPubNub(NewDemoConfig()))
+
+	result := g.fill([]string{"ch1"}, []string{}, func(message subscribeMessage) {})
+	assert.Equal(0, len(result.channels))
+}
+
+func TestGapFillerSeed(t *testing.T) {
+	assert := assert.New(t)
+	g := newGapFiller(NewPubNub(NewDemoConfig()))
+
+	g.track("ch1", "", 20)
+	g.seed([]string{"ch1", "ch2", "ch2-pnpres", "a.*"}, 10)
+	g.seed([]string{"ch2"}, 0)
+
+	pending := g.pending([]string{"ch1", "ch2", "ch2-pnpres", "a.*"}, []string{})
+	assert.Equal(map[string]gapFillMark{"ch1": {timetoken: 20}, "ch2": {timetoken: 10}}, pending)
+
+	// the live messages after the seed are not duplicates
+	assert.True(g.track("ch2", "", 11))
+}
+
+func TestGapFillerFillFile(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"error":false,"error_message":"","channels":{"ch1":[{"message":{"message":{"text":"hi"},"file":{"id":"f1","name":"a.txt"}},"message_type":4,"timetoken":"12","uuid":"u1"}]}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	g := newGapFiller(pn)
+	g.seed([]string{"ch1"}, 10)
+
+	pushed := []subscribeMessage{}
+	result := g.fill([]string{"ch1"}, []string{}, func(message subscribeMessage) {
+		pushed = append(pushed, message)
+	})
+
+	assert.Nil(result.err)
+	assert.Equal(1, result.recovered)
+	assert.Equal(1, len(pushed))
+	assert.Equal(PNMessageTypeFile, pushed[0].MessageType)
+
+	// the payload is kept raw for the subscribe path to parse
+	payload, ok := pushed[0].Payload.(map[string]interface{})
+	assert.True(ok)
+	file, message := ParseFileInfo(payload)
+	assert.Equal("f1", file.ID)
+	assert.Equal("a.txt", file.Name)
+	assert.Equal("hi", message.Text)
+}
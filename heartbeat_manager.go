@@ -21,7 +21,6 @@ type HeartbeatManager struct {
 	runIndependentOfSubscribe bool
 	hbRunning                 bool
 	queryParam                map[string]string
-	state                     map[string]interface{}
 }
 
 func newHeartbeatManager(pn *PubNub, context Context) *HeartbeatManager {
@@ -197,10 +196,10 @@ func (m *HeartbeatManager) performHeartbeatLoop() error {
 	m.RLock()
 	presenceChannels := m.prepareList(m.heartbeatChannels)
 	presenceGroups := m.prepareList(m.heartbeatGroups)
-	stateStorage = m.state
 	queryParam := m.queryParam
 	m.pubnub.Config.Log.Println("performHeartbeatLoop: count presenceChannels, presenceGroups", len(presenceChannels), len(presenceGroups))
 	m.RUnlock()
+	stateStorage = m.pubnub.subscriptionManager.stateManager.statePayload(presenceChannels, presenceGroups)
 
 	if (len(presenceChannels) == 0) && (len(presenceGroups) == 0) {
 		m.pubnub.Config.Log.Println("performHeartbeatLoop: count presenceChannels, presenceGroups nil")
@@ -228,10 +227,11 @@ func (m *HeartbeatManager) performHeartbeatLoop() error {
 	if err != nil {
 
 		pnStatus := &PNStatus{
-			Operation: PNHeartBeatOperation,
-			Category:  PNBadRequestCategory,
-			Error:     true,
-			ErrorData: err,
+			Operation:  PNHeartBeatOperation,
+			Category:   categorizeError(err),
+			Error:      true,
+			ErrorData:  err,
+			StatusCode: statusCodeOf(err),
 		}
 		m.pubnub.Config.Log.Println("performHeartbeatLoop: err", err, pnStatus)
 
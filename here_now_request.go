@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/pubnub/go/v7/pnerr"
@@ -17,6 +18,9 @@ var globalHereNowPath = "/v2/presence/sub_key/%s"
 
 var emptyHereNowResponse *HereNowResponse
 
+// hereNowMaxLimit is the most occupants per channel returned by the server.
+const hereNowMaxLimit = 1000
+
 type hereNowBuilder struct {
 	opts *hereNowOpts
 }
@@ -66,6 +70,20 @@ func (b *hereNowBuilder) IncludeUUIDs(uuid bool) *hereNowBuilder {
 	return b
 }
 
+// Limit sets the most occupants returned per channel, 1000 at most.
+func (b *hereNowBuilder) Limit(limit int) *hereNowBuilder {
+	b.opts.Limit = limit
+
+	return b
+}
+
+// Offset sets the number of occupants skipped per channel, for the next pages.
+func (b *hereNowBuilder) Offset(offset int) *hereNowBuilder {
+	b.opts.Offset = offset
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *hereNowBuilder) QueryParam(queryParam map[string]string) *hereNowBuilder {
 	b.opts.QueryParam = queryParam
@@ -80,7 +98,49 @@ func (b *hereNowBuilder) Execute() (*HereNowResponse, StatusResponse, error) {
 		return emptyHereNowResponse, status, err
 	}
 
-	return newHereNowResponse(rawJSON, b.opts.Channels, status)
+	resp, status, err := newHereNowResponse(rawJSON, b.opts.Channels, status)
+	if err != nil {
+		return resp, status, err
+	}
+	resp.Complete = b.opts.complete(resp)
+
+	return resp, status, nil
+}
+
+// Iterator returns the iterator over all the occupants of the channels,
+// requesting the pages of Limit occupants as they are needed. The iterator
+// pages a copy of the builder, which is left as it is.
+func (b *hereNowBuilder) Iterator() *HereNowIterator {
+	it := &HereNowIterator{}
+	opts := *b.opts
+	pages := &hereNowBuilder{opts: &opts}
+	it.pageIterator = newPageIterator(strconv.Itoa(opts.Offset), func(ctx Context, token string) (int, string, error) {
+		if ctx != nil {
+			pages.opts.ctx = ctx
+		}
+		pages.opts.Offset, _ = strconv.Atoi(token)
+		resp, _, err := pages.Execute()
+		if err != nil {
+			return 0, "", err
+		}
+
+		it.page = it.page[:0]
+		more := false
+		for _, channel := range resp.Channels {
+			for _, occupant := range channel.Occupants {
+				it.page = append(it.page, HereNowOccupant{Channel: channel.ChannelName, HereNowOccupantsData: occupant})
+			}
+			if pages.opts.Offset+len(channel.Occupants) < channel.Occupancy {
+				more = true
+			}
+		}
+		next := ""
+		if more {
+			next = strconv.Itoa(pages.opts.Offset + pages.opts.limit())
+		}
+		return len(it.page), next, nil
+	})
+	return it
 }
 
 func newHereNowOpts(pubnub *PubNub, ctx Context) *hereNowOpts {
@@ -101,6 +161,8 @@ type hereNowOpts struct {
 	IncludeState    bool
 	SetIncludeState bool
 	SetIncludeUUIDs bool
+	Limit           int
+	Offset          int
 	QueryParam      map[string]string
 
 	Transport http.RoundTripper
@@ -150,6 +212,13 @@ func (o *hereNowOpts) buildQuery() (*url.Values, error) {
 		q.Set("disable-uuids", "0")
 	}
 
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+
 	SetQueryParam(q, o.QueryParam)
 
 	return q, nil
@@ -159,12 +228,38 @@ func (o *hereNowOpts) operationType() OperationType {
 	return PNHereNowOperation
 }
 
+// limit returns the most occupants per channel of a response.
+func (o *hereNowOpts) limit() int {
+	if o.Limit > 0 && o.Limit < hereNowMaxLimit {
+		return o.Limit
+	}
+	return hereNowMaxLimit
+}
+
+// complete is true when the response holds all the occupants of its
+// channels, or when the UUIDs were not requested.
+func (o *hereNowOpts) complete(resp *HereNowResponse) bool {
+	if o.SetIncludeUUIDs && !o.IncludeUUIDs {
+		return true
+	}
+	for _, channel := range resp.Channels {
+		if o.Offset > 0 || len(channel.Occupants) < channel.Occupancy {
+			return false
+		}
+	}
+	return true
+}
+
 // HereNowResponse is the struct returned when the Execute function of HereNow is called.
 type HereNowResponse struct {
 	TotalChannels  int
 	TotalOccupancy int
 
 	Channels []HereNowChannelData
+
+	// Complete is false when occupants are missing from Channels, because
+	// of the Limit or Offset of the request. The Iterator returns them all.
+	Complete bool
 }
 
 // HereNowChannelData is the struct containing the occupancy details of the channels.
@@ -176,6 +271,46 @@ type HereNowChannelData struct {
 	Occupants []HereNowOccupantsData
 }
 
+// HereNowOccupant is an occupant returned by the HereNowIterator.
+type HereNowOccupant struct {
+	Channel string
+	HereNowOccupantsData
+}
+
+// HereNowIterator iterates over the occupants of channels, requesting the
+// pages of HereNow as they are needed.
+type HereNowIterator struct {
+	pageIterator
+	page []HereNowOccupant
+}
+
+// MaxItems stops the iteration after max occupants, 0 for no limit.
+func (it *HereNowIterator) MaxItems(max int) *HereNowIterator {
+	it.maxItems = max
+	return it
+}
+
+// Next moves to the next occupant, it returns false when there is none left
+// or the iteration stopped, see Err.
+func (it *HereNowIterator) Next(ctx Context) bool {
+	return it.advance(ctx)
+}
+
+// Item returns the current occupant.
+func (it *HereNowIterator) Item() HereNowOccupant {
+	return it.page[it.index]
+}
+
+// ForEach calls f for each occupant until it returns false.
+func (it *HereNowIterator) ForEach(ctx Context, f func(HereNowOccupant) bool) error {
+	for it.Next(ctx) {
+		if !f(it.Item()) {
+			break
+		}
+	}
+	return it.Err()
+}
+
 // HereNowOccupantsData is the struct containing the state and UUID of the occupants in the channel.
 type HereNowOccupantsData struct {
 	UUID string
@@ -1,7 +1,9 @@
 package pubnub
 
 import (
+	"net/http"
 	"net/url"
+	"sort"
 	"testing"
 
 	h "github.com/pubnub/go/v7/tests/helpers"
@@ -265,3 +267,49 @@ func TestNewHereNowResponseOccupancyZeroPayloadWithoutCh(t *testing.T) {
 	assert.Equal(0, r.TotalOccupancy)
 
 }
+
+func TestHereNowLimitOffset(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	b := pn.HereNow().Channels([]string{"ch1"}).Limit(2).Offset(4)
+	q, err := b.opts.buildQuery()
+
+	assert.Nil(err)
+	assert.Equal("2", q.Get("limit"))
+	assert.Equal("4", q.Get("offset"))
+}
+
+func TestHereNowIterator(t *testing.T) {
+	assert := assert.New(t)
+	first := `{"status":200,"payload":{"channels":{"ch1":{"uuids":[{"uuid":"a"},{"uuid":"b"}],"occupancy":3},"ch2":{"uuids":[{"uuid":"x"}],"occupancy":1}},"total_channels":2,"total_occupancy":4}}`
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, first, nil),
+			newSequenceResponse(200, first, nil),
+			newSequenceResponse(200, `{"status":200,"payload":{"channels":{"ch1":{"uuids":[{"uuid":"c"}],"occupancy":3}},"total_channels":2,"total_occupancy":4}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	resp, _, err := pn.HereNow().Channels([]string{"ch1", "ch2"}).Limit(2).Execute()
+	assert.Nil(err)
+	assert.False(resp.Complete)
+
+	transport.requests = nil
+	occupants := []string{}
+	b := pn.HereNow().Channels([]string{"ch1", "ch2"}).Limit(2)
+	err = b.Iterator().ForEach(nil, func(o HereNowOccupant) bool {
+		occupants = append(occupants, o.Channel+"/"+o.UUID)
+		return true
+	})
+
+	assert.Nil(err)
+	sort.Strings(occupants)
+	assert.Equal([]string{"ch1/a", "ch1/b", "ch1/c", "ch2/x"}, occupants)
+	assert.Len(transport.requests, 2)
+	assert.Equal("2", transport.requests[1].URL.Query().Get("offset"))
+	// the builder is left as it was
+	assert.Equal(0, b.opts.Offset)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/pubnub/go/v7/utils"
 )
@@ -50,6 +51,16 @@ func (b *historyDeleteBuilder) End(end int64) *historyDeleteBuilder {
 	return b
 }
 
+// Since deletes the messages published at t or later, it sets End.
+func (b *historyDeleteBuilder) Since(t time.Time) *historyDeleteBuilder {
+	return b.End(TimetokenFromTime(t).Int64())
+}
+
+// Until deletes the messages published before t, it sets Start.
+func (b *historyDeleteBuilder) Until(t time.Time) *historyDeleteBuilder {
+	return b.Start(TimetokenFromTime(t).Int64())
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *historyDeleteBuilder) QueryParam(queryParam map[string]string) *historyDeleteBuilder {
 	b.opts.QueryParam = queryParam
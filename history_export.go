@@ -0,0 +1,240 @@
+package pubnub
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// HistoryRecord is a line of a history export, in JSON Lines.
+type HistoryRecord struct {
+	Channel     string      `json:"channel"`
+	Timetoken   int64       `json:"timetoken"`
+	Message     interface{} `json:"message"`
+	Meta        interface{} `json:"meta,omitempty"`
+	UUID        string      `json:"uuid,omitempty"`
+	MessageType int         `json:"message_type,omitempty"`
+	// Actions are the message actions by type and value.
+	Actions map[string]map[string][]PNHistoryMessageActionTypeVal `json:"actions,omitempty"`
+	// File is set for the file events, Message is then their text.
+	File *PNFileDetails `json:"file,omitempty"`
+	// Error is set when the message could not be decrypted, Message is then
+	// the message as stored. These records are not replayed.
+	Error string `json:"error,omitempty"`
+}
+
+// HistoryExportOptions are the channels and time range exported by
+// ExportHistory.
+type HistoryExportOptions struct {
+	Channels []string
+	// Start and End are the newest and oldest timetokens of the range, 0
+	// for no bound.
+	Start int64
+	End   int64
+	// IncludeMessageActions exports the message actions. They are fetched
+	// one channel at a time, 25 messages per request.
+	IncludeMessageActions bool
+	// Resume is the last timetoken already exported per channel, the export
+	// continues after it. It is read from a previous export with
+	// LastExportedTimetokens.
+	Resume map[string]int64
+}
+
+// ExportHistory writes the messages of the channels to w in JSON Lines, one
+// HistoryRecord per message, oldest first per channel. The messages are
+// decrypted with the crypto settings of the Config. It returns the number of
+// records written.
+func (pn *PubNub) ExportHistory(w io.Writer, opts HistoryExportOptions) (int, error) {
+	encoder := json.NewEncoder(w)
+	written := 0
+	for _, channel := range opts.Channels {
+		n, err := pn.exportChannelHistory(encoder, channel, opts)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// exportChannelHistory pages forward from the oldest message of the range.
+func (pn *PubNub) exportChannelHistory(encoder *json.Encoder, channel string, opts HistoryExportOptions) (int, error) {
+	oldest := opts.End
+	if last, ok := opts.Resume[channel]; ok && last >= oldest {
+		// the end is inclusive
+		oldest = last + 1
+	}
+	count := maxCountFetch
+	if opts.IncludeMessageActions {
+		count = maxCountHistoryWithMessageActions
+	}
+
+	written := 0
+	for {
+		fetch := pn.Fetch().Channels([]string{channel}).Count(count).Reverse(true).
+			IncludeMeta(true).IncludeMessageActions(opts.IncludeMessageActions)
+		if oldest > 0 {
+			fetch.End(oldest)
+		}
+		if opts.Start > 0 {
+			fetch.Start(opts.Start)
+		}
+		resp, _, err := fetch.Execute()
+		if err != nil {
+			return written, err
+		}
+
+		items := resp.Messages[channel]
+		for _, item := range items {
+			record, err := newHistoryRecord(channel, item)
+			if err != nil {
+				return written, err
+			}
+			if err := encoder.Encode(record); err != nil {
+				return written, err
+			}
+			written++
+			oldest = record.Timetoken + 1
+		}
+		if len(items) < count {
+			return written, nil
+		}
+	}
+}
+
+func newHistoryRecord(channel string, item FetchResponseItem) (*HistoryRecord, error) {
+	timetoken, err := strconv.ParseInt(item.Timetoken, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timetoken %q on channel %s", item.Timetoken, channel)
+	}
+	record := &HistoryRecord{
+		Channel:     channel,
+		Timetoken:   timetoken,
+		Message:     item.Message,
+		UUID:        item.UUID,
+		MessageType: item.MessageType,
+	}
+	if item.Meta != "" {
+		record.Meta = item.Meta
+	}
+	if item.Error != nil {
+		record.Error = item.Error.Error()
+	}
+	if item.File.ID != "" {
+		file := item.File
+		record.File = &file
+		if message, ok := item.Message.(PNPublishMessage); ok {
+			record.Message = message.Text
+		}
+	}
+	if len(item.MessageActions) > 0 {
+		record.Actions = make(map[string]map[string][]PNHistoryMessageActionTypeVal, len(item.MessageActions))
+		for actionType, values := range item.MessageActions {
+			record.Actions[actionType] = values.ActionsTypeValues
+		}
+	}
+	return record, nil
+}
+
+// LastExportedTimetokens reads a history export and returns the last
+// timetoken written per channel, the HistoryExportOptions.Resume of the next
+// export. A truncated last line is ignored.
+func LastExportedTimetokens(r io.Reader) (map[string]int64, error) {
+	last := make(map[string]int64)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.Timetoken > last[record.Channel] {
+			last[record.Channel] = record.Timetoken
+		}
+	}
+	return last, scanner.Err()
+}
+
+// HistoryReplayOptions configure ReplayHistory.
+type HistoryReplayOptions struct {
+	// ChannelMap maps the exported channels to the channels published to,
+	// the other channels keep their name.
+	ChannelMap map[string]string
+	// Rate is the most messages published per second, 0 for no limit.
+	Rate float64
+	// SkipFiles skips the file events. They refer to the files stored on the
+	// keyset of the export, which are not copied.
+	SkipFiles bool
+}
+
+// ReplayHistory publishes the records of a history export, read from r, in
+// their order. The messages are encrypted with the crypto settings of the
+// Config, the message actions are not replayed. The records with an Error,
+// which hold messages that could not be decrypted, are skipped. It returns
+// the number of messages published and stops at the first error.
+func (pn *PubNub) ReplayHistory(r io.Reader, opts HistoryReplayOptions) (int, error) {
+	var interval time.Duration
+	if opts.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / opts.Rate)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 32*1024*1024)
+	published := 0
+	var last time.Time
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return published, fmt.Errorf("invalid history record on line %d: %s", line, err)
+		}
+		if record.File != nil && opts.SkipFiles {
+			continue
+		}
+		if record.Error != "" {
+			pn.Config.Log.Printf("replay: message %d on channel %s skipped: %s", record.Timetoken, record.Channel, record.Error)
+			continue
+		}
+
+		if interval > 0 {
+			if wait := interval - time.Since(last); wait > 0 {
+				time.Sleep(wait)
+			}
+			last = time.Now()
+		}
+
+		channel := record.Channel
+		if mapped, ok := opts.ChannelMap[channel]; ok {
+			channel = mapped
+		}
+		if err := pn.replayRecord(channel, &record); err != nil {
+			return published, fmt.Errorf("replay of message %d on channel %s failed: %s", record.Timetoken, record.Channel, err)
+		}
+		published++
+	}
+	return published, scanner.Err()
+}
+
+func (pn *PubNub) replayRecord(channel string, record *HistoryRecord) error {
+	if record.File != nil {
+		text, _ := record.Message.(string)
+		_, _, err := pn.PublishFileMessage().Channel(channel).Meta(record.Meta).ShouldStore(true).
+			Message(PNPublishFileMessage{
+				PNFile:    &PNFileInfoForPublish{ID: record.File.ID, Name: record.File.Name},
+				PNMessage: &PNPublishMessage{Text: text},
+			}).Execute()
+		return err
+	}
+
+	publish := pn.Publish().Channel(channel).Message(record.Message)
+	if record.Meta != nil {
+		publish.Meta(record.Meta)
+	}
+	_, _, err := publish.Execute()
+	return err
+}
@@ -0,0 +1,113 @@
+package pubnub
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportHistory(t *testing.T) {
+	assert := assert.New(t)
+	transport := &historyTransport{counts: map[string]int{"ch1": 150, "ch2": 2}}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	var out bytes.Buffer
+	n, err := pn.ExportHistory(&out, HistoryExportOptions{Channels: []string{"ch1", "ch2"}})
+
+	assert.Nil(err)
+	assert.Equal(152, n)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(lines, 152)
+	var first HistoryRecord
+	assert.Nil(json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal("ch1", first.Channel)
+	assert.Equal(int64(1), first.Timetoken)
+	assert.Equal(map[string]interface{}{"n": float64(1)}, first.Meta)
+	assert.Equal("101", transport.requests[1].URL.Query().Get("end"))
+
+	last, err := LastExportedTimetokens(&out)
+	assert.Nil(err)
+	assert.Equal(map[string]int64{"ch1": 150, "ch2": 2}, last)
+}
+
+func TestExportHistoryResume(t *testing.T) {
+	assert := assert.New(t)
+	transport := &historyTransport{counts: map[string]int{"ch1": 5}}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	var out bytes.Buffer
+	n, err := pn.ExportHistory(&out, HistoryExportOptions{
+		Channels: []string{"ch1"},
+		Resume:   map[string]int64{"ch1": 3},
+	})
+
+	assert.Nil(err)
+	assert.Equal(2, n)
+	assert.Equal("4", transport.requests[0].URL.Query().Get("end"))
+}
+
+func TestReplayHistory(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `[1,"Sent","15000000000000000"]`, nil),
+			newSequenceResponse(200, `[1,"Sent","15000000000000001"]`, nil),
+		},
+	}
+	config := NewDemoConfig()
+	config.CipherKey = "enigma"
+	config.UseRandomInitializationVector = false
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: transport})
+	export := `{"channel":"ch1","timetoken":1,"message":"hello","meta":{"n":1}}
+{"channel":"ch2","timetoken":2,"message":"text","file":{"name":"f.txt","id":"fid","URL":""}}
+{"channel":"ch1","timetoken":3,"message":{"a":1}}
+`
+
+	n, err := pn.ReplayHistory(strings.NewReader(export), HistoryReplayOptions{
+		ChannelMap: map[string]string{"ch1": "copy"},
+		SkipFiles:  true,
+	})
+
+	assert.Nil(err)
+	assert.Equal(2, n)
+	assert.Len(transport.requests, 2)
+	u := transport.requests[0].URL.String()
+	assert.Contains(u, "/copy/")
+	assert.NotContains(u, "hello")
+	assert.Contains(u, "meta=")
+}
+
+func TestHistoryRecordDecryptionError(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "enigma"
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"channels":{"ch1":[{"message":"not encrypted","timetoken":"1"}]}}`, nil),
+			newSequenceResponse(200, `[1,"Sent","15000000000000000"]`, nil),
+		},
+	}
+	pn.SetClient(&http.Client{Transport: transport})
+
+	var out bytes.Buffer
+	n, err := pn.ExportHistory(&out, HistoryExportOptions{Channels: []string{"ch1"}})
+	assert.Nil(err)
+	assert.Equal(1, n)
+	var record HistoryRecord
+	assert.Nil(json.Unmarshal(out.Bytes(), &record))
+	assert.Equal("not encrypted", record.Message)
+	assert.Contains(record.Error, "can't decrypt message 1 on channel ch1")
+
+	// the record is not republished
+	n, err = pn.ReplayHistory(&out, HistoryReplayOptions{})
+	assert.Nil(err)
+	assert.Equal(0, n)
+	assert.Len(transport.requests, 1)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strconv"
+	"time"
 
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
@@ -57,6 +58,16 @@ func (b *historyBuilder) End(end int64) *historyBuilder {
 	return b
 }
 
+// Since returns the messages published at t or later, it sets End.
+func (b *historyBuilder) Since(t time.Time) *historyBuilder {
+	return b.End(TimetokenFromTime(t).Int64())
+}
+
+// Until returns the messages published before t, it sets Start.
+func (b *historyBuilder) Until(t time.Time) *historyBuilder {
+	return b.Start(TimetokenFromTime(t).Int64())
+}
+
 // Count sets the number of items to return in the History request.
 func (b *historyBuilder) Count(count int) *historyBuilder {
 	b.opts.Count = count
@@ -202,6 +213,9 @@ type HistoryResponseItem struct {
 	Message   interface{}
 	Meta      interface{}
 	Timetoken int64
+	// Error is a *DecryptionError when the message could not be decrypted,
+	// Message is then the message as stored.
+	Error error `json:"-"`
 }
 
 func logAndCreateNewResponseParsingError(o *historyOpts, err error, jsonBody string, message string) *pnerr.ResponseParsingError {
@@ -224,7 +238,10 @@ func getHistoryItemsWithoutTimetoken(historyResponseRaw []byte, o *historyOpts,
 
 	for i, v := range historyResponseItems {
 		o.pubnub.Config.Log.Println(v)
-		items[i].Message, _ = parseCipherInterface(v, o.pubnub.Config)
+		var decryptionErr *DecryptionError
+		if items[i].Message, decryptionErr = decryptPayload(v, o.Channel, 0, o.pubnub.Config); decryptionErr != nil {
+			items[i].Error = decryptionErr
+		}
 	}
 	return items, nil
 }
@@ -237,7 +254,10 @@ func getHistoryItemsWithTimetoken(historyResponseItems []HistoryResponseItem, o
 	for i, v := range historyResponseItems {
 		if v.Message != nil {
 			o.pubnub.Config.Log.Println(v.Message)
-			items[i].Message, _ = parseCipherInterface(v.Message, o.pubnub.Config)
+			var decryptionErr *DecryptionError
+			if items[i].Message, decryptionErr = decryptPayload(v.Message, o.Channel, v.Timetoken, o.pubnub.Config); decryptionErr != nil {
+				items[i].Error = decryptionErr
+			}
 
 			o.pubnub.Config.Log.Println(v.Timetoken)
 			items[i].Timetoken = v.Timetoken
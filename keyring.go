@@ -0,0 +1,279 @@
+package pubnub
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	keyringSentinel    = "PNKR"
+	keyringMaxIDLength = 255
+)
+
+// KeyringKey is a key of a Keyring.
+type KeyringKey struct {
+	// ID is written before the payloads encrypted with the key, so that they
+	// are decrypted with it without trying the other keys. Leave it empty
+	// while the clients which don't use a keyring have to read the payloads.
+	ID     string
+	Module CryptoModule
+}
+
+// Keyring is a CryptoModule for the key rotation: it encrypts with its active
+// key and decrypts with the key named in the payload, or else with the first
+// of its keys which succeeds, the active one first.
+//
+// A wrong AES-CBC key is only detected by the padding, which a wrong key
+// passes about once in 256 tries. So without a key ID a message is only taken
+// from a key when it decrypts to JSON, and a file is only decrypted when one
+// key alone succeeds. Give the keys IDs as soon as all the clients use a
+// keyring.
+//
+// Set it as Config.CryptoModule, it then applies to publish, subscribe,
+// History, Fetch and the files.
+type Keyring struct {
+	sync.RWMutex
+	// keys[0] is the active key
+	keys []KeyringKey
+}
+
+// NewKeyring creates the Keyring encrypting with active and decrypting with
+// active and the previous keys, tried in that order.
+func NewKeyring(active KeyringKey, previous ...KeyringKey) *Keyring {
+	return &Keyring{
+		keys: append([]KeyringKey{active}, previous...),
+	}
+}
+
+// Rotate makes the key the active key. The previous active key is the first
+// one tried after it.
+func (k *Keyring) Rotate(active KeyringKey) {
+	k.Lock()
+	defer k.Unlock()
+
+	keys := []KeyringKey{active}
+	for _, key := range k.keys {
+		if active.ID != "" && key.ID == active.ID {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	k.keys = keys
+}
+
+// Remove removes the previous key with the ID, once no payload needs it. The
+// active key is not removed.
+func (k *Keyring) Remove(id string) {
+	k.Lock()
+	defer k.Unlock()
+
+	keys := k.keys[:1]
+	for _, key := range k.keys[1:] {
+		if key.ID != id {
+			keys = append(keys, key)
+		}
+	}
+	k.keys = keys
+}
+
+// Keys returns the keys, the active one first.
+func (k *Keyring) Keys() []KeyringKey {
+	k.RLock()
+	defer k.RUnlock()
+
+	return append([]KeyringKey{}, k.keys...)
+}
+
+// Encrypt encrypts with the active key.
+func (k *Keyring) Encrypt(data []byte) ([]byte, error) {
+	active := k.active()
+	encrypted, err := active.Module.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := encodeKeyID(active.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append(prefix, encrypted...), nil
+}
+
+// Decrypt decrypts with the key named in the payload, or else tries the keys
+// until one decrypts the payload to JSON, as the messages are.
+func (k *Keyring) Decrypt(data []byte) ([]byte, error) {
+	return k.decrypt(data, func(module CryptoModule, data []byte) ([]byte, error) {
+		return module.Decrypt(data)
+	}, json.Valid)
+}
+
+// EncryptStream encrypts with the active key.
+func (k *Keyring) EncryptStream(r io.Reader) (io.Reader, error) {
+	active := k.active()
+	encrypted, err := active.Module.EncryptStream(r)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := encodeKeyID(active.ID)
+	if err != nil {
+		return nil, err
+	}
+	return io.MultiReader(bytes.NewReader(prefix), encrypted), nil
+}
+
+// encryptedStreamSize returns the size of the stream returned by
+// EncryptStream for size bytes, when the active key knows it.
+func (k *Keyring) encryptedStreamSize(size int64) (int64, bool) {
+	active := k.active()
+	sizer, ok := active.Module.(streamSizer)
+	if !ok {
+		return 0, false
+	}
+	encrypted, ok := sizer.encryptedStreamSize(size)
+	if !ok {
+		return 0, false
+	}
+	prefix, err := encodeKeyID(active.ID)
+	if err != nil {
+		return 0, false
+	}
+	return int64(len(prefix)) + encrypted, true
+}
+
+// DecryptStream decrypts with the key named in the payload as the stream is
+// read, or else tries the keys on the whole content of r.
+func (k *Keyring) DecryptStream(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	sentinel, _ := buffered.Peek(len(keyringSentinel))
+	if bytes.Equal(sentinel, []byte(keyringSentinel)) {
+		prefix, err := buffered.Peek(len(keyringSentinel) + 1)
+		if err != nil {
+			return nil, errors.New("decrypt error: truncated key id")
+		}
+		prefix, err = buffered.Peek(len(prefix) + int(prefix[len(keyringSentinel)]))
+		if err != nil {
+			return nil, errors.New("decrypt error: truncated key id")
+		}
+		id, _, _, err := decodeKeyID(prefix)
+		if err != nil {
+			return nil, err
+		}
+		buffered.Discard(len(prefix))
+		for _, key := range k.Keys() {
+			if key.ID == id {
+				return key.Module.DecryptStream(buffered)
+			}
+		}
+		return nil, fmt.Errorf("decrypt error: unknown key id %q", id)
+	}
+
+	data, err := ioutil.ReadAll(buffered)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := k.decrypt(data, func(module CryptoModule, data []byte) ([]byte, error) {
+		r, err := module.DecryptStream(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(r)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decrypted), nil
+}
+
+func (k *Keyring) active() KeyringKey {
+	k.RLock()
+	defer k.RUnlock()
+
+	return k.keys[0]
+}
+
+// decrypt decrypts with the key named in the payload. Without a key ID it
+// returns the first plaintext accepted by valid, or with a nil valid the
+// plaintext of the only key which succeeds.
+func (k *Keyring) decrypt(data []byte, decrypt func(CryptoModule, []byte) ([]byte, error), valid func([]byte) bool) ([]byte, error) {
+	keys := k.Keys()
+
+	id, payload, ok, err := decodeKeyID(data)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for _, key := range keys {
+			if key.ID == id {
+				return decrypt(key.Module, payload)
+			}
+		}
+		return nil, fmt.Errorf("decrypt error: unknown key id %q", id)
+	}
+
+	if len(keys) == 1 {
+		// nothing to choose from
+		valid = nil
+	}
+
+	var firstErr error
+	var found []byte
+	matches := 0
+	for _, key := range keys {
+		decrypted, err := decrypt(key.Module, data)
+		if err == nil && valid != nil && !valid(decrypted) {
+			err = errors.New("decrypt error: decrypted payload is not valid JSON")
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if valid != nil {
+			return decrypted, nil
+		}
+		if matches == 0 {
+			found = decrypted
+		}
+		matches++
+	}
+	switch {
+	case matches == 1:
+		return found, nil
+	case matches > 1:
+		return nil, fmt.Errorf("decrypt error: %d keys of the keyring decrypt the payload, give the keys IDs", matches)
+	}
+	return nil, fmt.Errorf("decrypt error: no key of the keyring decrypts the payload: %s", firstErr)
+}
+
+// encodeKeyID creates the prefix naming the key of a payload: "PNKR", the
+// length of the ID and the ID. There is no prefix for an empty ID.
+func encodeKeyID(id string) ([]byte, error) {
+	if id == "" {
+		return []byte{}, nil
+	}
+	if len(id) > keyringMaxIDLength {
+		return nil, fmt.Errorf("key id too long %d", len(id))
+	}
+	prefix := bytes.NewBufferString(keyringSentinel)
+	prefix.WriteByte(byte(len(id)))
+	prefix.WriteString(id)
+	return prefix.Bytes(), nil
+}
+
+func decodeKeyID(data []byte) (id string, payload []byte, ok bool, err error) {
+	if !bytes.HasPrefix(data, []byte(keyringSentinel)) {
+		return "", data, false, nil
+	}
+	rest := data[len(keyringSentinel):]
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return "", nil, true, errors.New("decrypt error: truncated key id")
+	}
+	length := int(rest[0])
+	return string(rest[1 : 1+length]), rest[1+length:], true, nil
+}
@@ -0,0 +1,164 @@
+package pubnub
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pubnub/go/v7/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	assert := assert.New(t)
+	keyring := NewKeyring(KeyringKey{Module: NewAesGcmCryptoModule("key1", true)})
+
+	old, err := keyring.Encrypt([]byte(`"old"`))
+	assert.Nil(err)
+
+	keyring.Rotate(KeyringKey{Module: NewAesGcmCryptoModule("key2", true)})
+	current, err := keyring.Encrypt([]byte(`"new"`))
+	assert.Nil(err)
+
+	decrypted, err := keyring.Decrypt(old)
+	assert.Nil(err)
+	assert.Equal(`"old"`, string(decrypted))
+	decrypted, err = keyring.Decrypt(current)
+	assert.Nil(err)
+	assert.Equal(`"new"`, string(decrypted))
+
+	_, err = NewAesGcmCryptoModule("key1", true).Decrypt(current)
+	assert.NotNil(err)
+
+	_, err = NewKeyring(KeyringKey{Module: NewAesGcmCryptoModule("key3", true)}).Decrypt(current)
+	assert.Contains(err.Error(), "no key of the keyring")
+}
+
+func TestKeyringKeyID(t *testing.T) {
+	assert := assert.New(t)
+	keyring := NewKeyring(
+		KeyringKey{ID: "k2", Module: NewAesGcmCryptoModule("key2", true)},
+		KeyringKey{ID: "k1", Module: NewAesGcmCryptoModule("key1", true)},
+	)
+
+	encrypted, err := keyring.Encrypt([]byte("hi"))
+	assert.Nil(err)
+	assert.True(bytes.HasPrefix(encrypted, []byte("PNKR\x02k2PNED")))
+
+	keyring.Rotate(KeyringKey{ID: "k3", Module: NewAesGcmCryptoModule("key3", true)})
+	decrypted, err := keyring.Decrypt(encrypted)
+	assert.Nil(err)
+	assert.Equal("hi", string(decrypted))
+
+	keyring.Remove("k2")
+	_, err = keyring.Decrypt(encrypted)
+	assert.Contains(err.Error(), `unknown key id "k2"`)
+	assert.Equal(2, len(keyring.Keys()))
+	assert.Equal("k3", keyring.Keys()[0].ID)
+}
+
+func TestKeyringRotateMovesActiveKey(t *testing.T) {
+	assert := assert.New(t)
+	k1 := KeyringKey{ID: "k1", Module: NewAesGcmCryptoModule("key1", true)}
+	k2 := KeyringKey{ID: "k2", Module: NewAesGcmCryptoModule("key2", true)}
+	keyring := NewKeyring(k1, k2)
+
+	keyring.Rotate(k2)
+	keys := keyring.Keys()
+	assert.Equal(2, len(keys))
+	assert.Equal("k2", keys[0].ID)
+	assert.Equal("k1", keys[1].ID)
+
+	keyring.Remove("k2")
+	assert.Equal("k2", keyring.Keys()[0].ID)
+}
+
+func TestKeyringStream(t *testing.T) {
+	assert := assert.New(t)
+	keyring := NewKeyring(KeyringKey{ID: "k1", Module: NewLegacyCryptoModule("key1", false)})
+	content := []byte("file content")
+
+	encrypted, err := keyring.EncryptStream(bytes.NewReader(content))
+	assert.Nil(err)
+	keyring.Rotate(KeyringKey{Module: NewAesGcmCryptoModule("key2", false)})
+
+	decrypted, err := keyring.DecryptStream(encrypted)
+	assert.Nil(err)
+	plain, _ := ioutil.ReadAll(decrypted)
+	assert.Equal(content, plain)
+}
+
+func TestKeyringConfig(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CryptoModule = NewKeyring(
+		KeyringKey{Module: NewAesGcmCryptoModule("key2", true)},
+		KeyringKey{Module: NewLegacyCryptoModule("key1", true)},
+	)
+
+	intf, err := parseCipherInterface(utils.EncryptString("key1", `"old"`, true), "ch", pn.Config)
+	assert.Nil(err)
+	assert.Equal("old", intf)
+}
+
+func TestFileCryptoModuleRequestKey(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.CipherKey = "config"
+
+	fromConfig, _ := pn.Config.cryptoModule().EncryptStream(bytes.NewReader([]byte("a")))
+	module := pn.Config.fileCryptoModule("ch", "request")
+	fromRequest, _ := module.EncryptStream(bytes.NewReader([]byte("b")))
+
+	for content, encrypted := range map[string]io.Reader{"a": fromConfig, "b": fromRequest} {
+		decrypted, err := module.DecryptStream(encrypted)
+		assert.Nil(err)
+		plain, _ := ioutil.ReadAll(decrypted)
+		assert.Equal(content, string(plain))
+	}
+}
+
+// wrongKeyModule decrypts any payload to garbage, as a wrong AES-CBC key
+// does when the padding happens to be valid.
+type wrongKeyModule struct{}
+
+func (wrongKeyModule) Encrypt(data []byte) ([]byte, error) { return data, nil }
+func (wrongKeyModule) Decrypt(data []byte) ([]byte, error) { return []byte("\x8f\x01garbage"), nil }
+func (wrongKeyModule) EncryptStream(r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+func (wrongKeyModule) DecryptStream(r io.Reader) (io.Reader, error) {
+	return bytes.NewReader([]byte("\x8f\x01garbage")), nil
+}
+
+func TestKeyringTrialSkipsWrongKey(t *testing.T) {
+	assert := assert.New(t)
+	legacy := NewLegacyCryptoModule("key1", true)
+	keyring := NewKeyring(KeyringKey{Module: wrongKeyModule{}}, KeyringKey{Module: legacy})
+
+	encrypted, err := legacy.Encrypt([]byte(`{"text":"hi"}`))
+	assert.Nil(err)
+	decrypted, err := keyring.Decrypt(encrypted)
+	assert.Nil(err)
+	assert.Equal(`{"text":"hi"}`, string(decrypted))
+
+	_, err = NewKeyring(KeyringKey{Module: wrongKeyModule{}}, KeyringKey{Module: wrongKeyModule{}}).Decrypt(encrypted)
+	assert.Contains(err.Error(), "not valid JSON")
+}
+
+func TestKeyringStreamTrialAmbiguous(t *testing.T) {
+	assert := assert.New(t)
+	legacy := NewLegacyCryptoModule("key1", false)
+	encrypted, err := legacy.EncryptStream(bytes.NewReader([]byte("file content")))
+	assert.Nil(err)
+	data, _ := ioutil.ReadAll(encrypted)
+
+	_, err = NewKeyring(KeyringKey{Module: wrongKeyModule{}}, KeyringKey{Module: legacy}).DecryptStream(bytes.NewReader(data))
+	assert.Contains(err.Error(), "2 keys of the keyring decrypt the payload")
+
+	decrypted, err := NewKeyring(KeyringKey{Module: NewAesGcmCryptoModule("key2", false)}, KeyringKey{Module: legacy}).DecryptStream(bytes.NewReader(data))
+	assert.Nil(err)
+	plain, _ := ioutil.ReadAll(decrypted)
+	assert.Equal([]byte("file content"), plain)
+}
@@ -15,6 +15,10 @@ type Listener struct {
 	MembershipEvent     chan *PNMembershipEvent
 	MessageActionsEvent chan *PNMessageActionsEvent
 	File                chan *PNFilesEvent
+
+	// queue is the bounded buffer of the listeners created with
+	// NewListenerWithOptions, nil for the unbuffered ones.
+	queue *listenerQueue
 }
 
 // NewListener initates the listener to facilitate the event handling
@@ -40,6 +44,9 @@ type ListenerManager struct {
 	exitListener         chan bool
 	exitListenerAnnounce chan bool
 	pubnub               *PubNub
+	// subscriptions holds the subscribed Subscription objects, their listeners
+	// receive only the events matching their channels and groups.
+	subscriptions map[*Subscription]bool
 }
 
 func newListenerManager(ctx Context, pn *PubNub) *ListenerManager {
@@ -49,6 +56,7 @@ func newListenerManager(ctx Context, pn *PubNub) *ListenerManager {
 		exitListener:         make(chan bool),
 		exitListenerAnnounce: make(chan bool),
 		pubnub:               pn,
+		subscriptions:        make(map[*Subscription]bool),
 	}
 }
 
@@ -88,158 +96,145 @@ func (m *ListenerManager) copyListeners() map[*Listener]bool {
 	return lis
 }
 
-func (m *ListenerManager) announceStatus(status *PNStatus) {
-	go func() {
-		lis := m.copyListeners()
-	AnnounceStatusLabel:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceStatus exitListener")
-				break AnnounceStatusLabel
-			case l.Status <- status:
-			}
-		}
-		m.pubnub.Config.Log.Println("announceStatus exit")
-	}()
+func (m *ListenerManager) addSubscription(subscription *Subscription) {
+	m.Lock()
+	m.subscriptions[subscription] = true
+	m.Unlock()
 }
 
-func (m *ListenerManager) announceMessage(message *PNMessage) {
-	go func() {
-		lis := m.copyListeners()
-	AnnounceMessageLabel:
-		for l := range lis {
-			select {
-			case <-m.exitListenerAnnounce:
-				m.pubnub.Config.Log.Println("announceMessage exitListenerAnnounce")
-				break AnnounceMessageLabel
-			case l.Message <- message:
-			}
+func (m *ListenerManager) removeSubscription(subscription *Subscription) {
+	m.Lock()
+	delete(m.subscriptions, subscription)
+	m.Unlock()
+}
+
+// copyListenersFor copies the global listeners and the listeners of the
+// subscribed Subscriptions accepted by match. A listener shared by several
+// matching Subscriptions is returned once.
+func (m *ListenerManager) copyListenersFor(match func(s *Subscription) bool) map[*Listener]bool {
+	m.Lock()
+	lis := make(map[*Listener]bool)
+	for k, v := range m.listeners {
+		lis[k] = v
+	}
+	subscriptions := make([]*Subscription, 0, len(m.subscriptions))
+	for s := range m.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	m.Unlock()
+
+	for _, s := range subscriptions {
+		if match(s) {
+			s.copyListeners(lis)
 		}
+	}
+	return lis
+}
 
-	}()
+// copyListenersForEvent copies the listeners interested in an event received
+// on channel through subscriptionMatch.
+func (m *ListenerManager) copyListenersForEvent(channel, subscriptionMatch string, presence bool) map[*Listener]bool {
+	return m.copyListenersFor(func(s *Subscription) bool {
+		return s.matches(channel, subscriptionMatch, presence)
+	})
 }
 
-func (m *ListenerManager) announceSignal(message *PNMessage) {
-	go func() {
-		lis := m.copyListeners()
+func (m *ListenerManager) announceStatus(status *PNStatus) {
+	lis := m.copyListenersFor(func(s *Subscription) bool {
+		return s.affectedBy(status.AffectedChannels, status.AffectedChannelGroups)
+	})
+	channel := ""
+	if len(status.AffectedChannels) > 0 {
+		channel = status.AffectedChannels[0]
+	}
+	m.deliver(lis, listenerEvent{kind: listenerEventStatus, value: status, channel: channel}, m.exitListener)
+}
 
-	AnnounceSignalLabel:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceSignal exitListener")
-				break AnnounceSignalLabel
+func (m *ListenerManager) announceMessage(message *PNMessage) {
+	lis := m.copyListenersForEvent(message.Channel, message.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventMessage, value: message, channel: message.Channel}, m.exitListenerAnnounce)
+}
 
-			case l.Signal <- message:
-			}
-		}
-	}()
+func (m *ListenerManager) announceSignal(message *PNMessage) {
+	lis := m.copyListenersForEvent(message.Channel, message.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventSignal, value: message, channel: message.Channel}, m.exitListener)
 }
 
 func (m *ListenerManager) announceUUIDEvent(message *PNUUIDEvent) {
-	go func() {
-		lis := m.copyListeners()
-
-	AnnounceUUIDEventLabel:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceUUIDEvent exitListener")
-				break AnnounceUUIDEventLabel
-
-			case l.UUIDEvent <- message:
-				m.pubnub.Config.Log.Println("l.UUIDEvent", message)
-			}
-		}
-	}()
+	lis := m.copyListenersForEvent(message.Channel, message.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventUUID, value: message, channel: message.Channel}, m.exitListener)
 }
 
 func (m *ListenerManager) announceChannelEvent(message *PNChannelEvent) {
-	go func() {
-		lis := m.copyListeners()
-
-	AnnounceChannelEventLabel:
-		for l := range lis {
-			m.pubnub.Config.Log.Println("l.ChannelEvent", l)
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceChannelEvent exitListener")
-				break AnnounceChannelEventLabel
-
-			case l.ChannelEvent <- message:
-				m.pubnub.Config.Log.Println("l.ChannelEvent", message)
-			}
-		}
-	}()
+	lis := m.copyListenersForEvent(message.Channel, message.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventChannel, value: message, channel: message.Channel}, m.exitListener)
 }
 
 func (m *ListenerManager) announceMembershipEvent(message *PNMembershipEvent) {
-	go func() {
-		lis := m.copyListeners()
-
-	AnnounceMembershipEvent:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceMembershipEvent exitListener")
-				break AnnounceMembershipEvent
-
-			case l.MembershipEvent <- message:
-				m.pubnub.Config.Log.Println("l.MembershipEvent", message)
-			}
-		}
-	}()
+	lis := m.copyListenersForEvent(message.Channel, message.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventMembership, value: message, channel: message.Channel}, m.exitListener)
 }
 
 func (m *ListenerManager) announceMessageActionsEvent(message *PNMessageActionsEvent) {
-	go func() {
-		lis := m.copyListeners()
+	lis := m.copyListenersForEvent(message.Channel, message.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventMessageActions, value: message, channel: message.Channel}, m.exitListener)
+}
 
-	AnnounceMessageActionsEvent:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceMessageActionsEvent exitListener")
-				break AnnounceMessageActionsEvent
+func (m *ListenerManager) announcePresence(presence *PNPresence) {
+	lis := m.copyListenersForEvent(presence.Channel, presence.Subscription, true)
+	m.deliver(lis, listenerEvent{kind: listenerEventPresence, value: presence, channel: presence.Channel}, m.exitListener)
+}
 
-			case l.MessageActionsEvent <- message:
-				m.pubnub.Config.Log.Println("l.MessageActionsEvent", message)
-			}
-		}
-	}()
+func (m *ListenerManager) announceFile(file *PNFilesEvent) {
+	lis := m.copyListenersForEvent(file.Channel, file.Subscription, false)
+	m.deliver(lis, listenerEvent{kind: listenerEventFile, value: file, channel: file.Channel}, m.exitListener)
 }
 
-func (m *ListenerManager) announcePresence(presence *PNPresence) {
-	go func() {
-		lis := m.copyListeners()
+// deliver hands the event to the listeners. Buffered listeners get it through
+// their queue, in the order of the deliver calls, the other ones from a new
+// goroutine. The statuses are never queued, a consumer which doesn't read
+// them doesn't hold back the other events and the announcers never wait for
+// it. Closing exit aborts the pending deliveries. An event without listeners
+// is released, it doesn't hold back the subscribe cursor.
+func (m *ListenerManager) deliver(lis map[*Listener]bool, e listenerEvent, exit chan bool) {
+	e.exit = exit
+	if len(lis) == 0 {
+		e.release()
+		return
+	}
 
-	AnnouncePresenceLabel:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announcePresence exitListener")
-				break AnnouncePresenceLabel
+	unbuffered := make([]*Listener, 0, len(lis))
+	for l := range lis {
+		if l.queue == nil || e.kind == listenerEventStatus {
+			unbuffered = append(unbuffered, l)
+			continue
+		}
+		if l.queue.push(l, e) {
+			m.pubnub.Config.Log.Println("announce", e.kind, "dropped events on", e.channel)
+			m.announceDropped(l, newEventsDroppedStatus(l, e.channel), exit)
+		}
+	}
 
-			case l.Presence <- presence:
+	if len(unbuffered) == 0 {
+		return
+	}
+	go func() {
+		for _, l := range unbuffered {
+			if !l.send(e) {
+				m.pubnub.Config.Log.Println("announce", e.kind, "exitListener")
+				break
 			}
 		}
 	}()
 }
 
-func (m *ListenerManager) announceFile(file *PNFilesEvent) {
+// announceDropped sends the status directly to the listener, bypassing its
+// full buffer.
+func (m *ListenerManager) announceDropped(l *Listener, status *PNStatus, exit chan bool) {
 	go func() {
-		lis := m.copyListeners()
-
-	AnnounceFileLabel:
-		for l := range lis {
-			select {
-			case <-m.exitListener:
-				m.pubnub.Config.Log.Println("announceFile exitListener")
-				break AnnounceFileLabel
-
-			case l.File <- file:
-			}
+		select {
+		case <-exit:
+		case l.Status <- status:
 		}
 	}()
 }
@@ -258,6 +253,9 @@ type PNStatus struct {
 	ClientRequest         interface{} // Should be same for non-google environment
 	AffectedChannels      []string
 	AffectedChannelGroups []string
+	SubscribeState        SubscribeState // State of the subscribe loop when the status was announced by it, 0 otherwise.
+	RecoveredMessages     int            // Number of messages recovered from history, PNGapFillCategory only.
+	LostMessages          int            // Number of messages published during the outage which could not be recovered, PNGapFillCategory only.
 }
 
 // PNMessage is the Message Response for Subscribe
@@ -270,6 +268,11 @@ type PNMessage struct {
 	Subscription      string
 	Publisher         string
 	Timetoken         int64
+	// Error is a *DecryptionError when the message could not be decrypted,
+	// Message is then the message as received.
+	Error error
+
+	ack *cursorAck
 }
 
 // PNPresence is the Message Response for Presence
@@ -333,6 +336,8 @@ type PNMembershipEvent struct {
 	ChannelID         string
 	Description       string
 	Timestamp         string
+	Updated           string
+	ETag              string
 	Custom            map[string]interface{}
 	SubscribedChannel string
 	ActualChannel     string
@@ -360,4 +365,9 @@ type PNFilesEvent struct {
 	Subscription      string
 	Publisher         string
 	Timetoken         int64
+	// Error is a *DecryptionError when the event could not be decrypted,
+	// File is then empty.
+	Error error
+
+	ack *cursorAck
 }
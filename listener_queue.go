@@ -0,0 +1,295 @@
+package pubnub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ListenerOptions is used to set the optional parameters of a buffered
+// Listener.
+type ListenerOptions struct {
+	// BufferSize is the number of events kept for the listener while its
+	// consumer is busy.
+	BufferSize int
+	// OverflowPolicy decides what happens to new events when the buffer is
+	// full, PNBlockOverflowPolicy by default.
+	OverflowPolicy ListenerOverflowPolicy
+}
+
+// ListenerStats holds the delivery metrics of a buffered Listener.
+type ListenerStats struct {
+	// QueueDepth is the number of events waiting in the buffer.
+	QueueDepth int
+	// MaxQueueDepth is the highest QueueDepth seen so far.
+	MaxQueueDepth int
+	// Dropped is the number of events dropped because the buffer was full.
+	Dropped uint64
+}
+
+// NewListenerWithOptions creates a buffered listener. Unlike the listeners
+// created with NewListener, which get each event from its own goroutine, a
+// buffered listener keeps at most options.BufferSize undelivered events and
+// delivers them in the order they were received, from a single goroutine.
+// The statuses are not buffered, they are delivered like to the listeners
+// created with NewListener.
+//
+// When the buffer is full the event is handled according to
+// options.OverflowPolicy. Each run of dropped events is reported once with a
+// PNListenerEventsDroppedCategory status on the Status channel of the
+// listener.
+func NewListenerWithOptions(options ListenerOptions) *Listener {
+	listener := NewListener()
+
+	size := options.BufferSize
+	if size < 1 {
+		size = 1
+	}
+	policy := options.OverflowPolicy
+	if policy == 0 {
+		policy = PNBlockOverflowPolicy
+	}
+	listener.queue = &listenerQueue{
+		events: make(chan listenerEvent, size),
+		policy: policy,
+	}
+
+	return listener
+}
+
+// Stats returns the delivery metrics of a listener created with
+// NewListenerWithOptions, the zero value for the other listeners.
+func (l *Listener) Stats() ListenerStats {
+	if l.queue == nil {
+		return ListenerStats{}
+	}
+	return ListenerStats{
+		QueueDepth:    len(l.queue.events),
+		MaxQueueDepth: int(atomic.LoadInt64(&l.queue.maxDepth)),
+		Dropped:       atomic.LoadUint64(&l.queue.dropped),
+	}
+}
+
+type listenerEventKind int
+
+const (
+	listenerEventStatus listenerEventKind = 1 + iota
+	listenerEventMessage
+	listenerEventPresence
+	listenerEventSignal
+	listenerEventUUID
+	listenerEventChannel
+	listenerEventMembership
+	listenerEventMessageActions
+	listenerEventFile
+)
+
+func (k listenerEventKind) String() string {
+	switch k {
+	case listenerEventStatus:
+		return "Status"
+	case listenerEventMessage:
+		return "Message"
+	case listenerEventPresence:
+		return "Presence"
+	case listenerEventSignal:
+		return "Signal"
+	case listenerEventUUID:
+		return "UUIDEvent"
+	case listenerEventChannel:
+		return "ChannelEvent"
+	case listenerEventMembership:
+		return "MembershipEvent"
+	case listenerEventMessageActions:
+		return "MessageActionsEvent"
+	case listenerEventFile:
+		return "File"
+	default:
+		return "Unknown"
+	}
+}
+
+// listenerEvent is an event on its way to a Listener. exit aborts the
+// delivery when it is closed.
+type listenerEvent struct {
+	kind    listenerEventKind
+	value   interface{}
+	channel string
+	exit    <-chan bool
+}
+
+// release acknowledges the message or file event which no consumer will get,
+// so it doesn't hold back the subscribe cursor.
+func (e listenerEvent) release() {
+	switch v := e.value.(type) {
+	case *PNMessage:
+		v.ack.ack()
+	case *PNFilesEvent:
+		v.ack.ack()
+	}
+}
+
+// send delivers the event on the channel of the listener matching its kind.
+// It returns false when the delivery was aborted.
+func (l *Listener) send(e listenerEvent) bool {
+	switch e.kind {
+	case listenerEventStatus:
+		select {
+		case l.Status <- e.value.(*PNStatus):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventMessage:
+		select {
+		case l.Message <- e.value.(*PNMessage):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventPresence:
+		select {
+		case l.Presence <- e.value.(*PNPresence):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventSignal:
+		select {
+		case l.Signal <- e.value.(*PNMessage):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventUUID:
+		select {
+		case l.UUIDEvent <- e.value.(*PNUUIDEvent):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventChannel:
+		select {
+		case l.ChannelEvent <- e.value.(*PNChannelEvent):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventMembership:
+		select {
+		case l.MembershipEvent <- e.value.(*PNMembershipEvent):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventMessageActions:
+		select {
+		case l.MessageActionsEvent <- e.value.(*PNMessageActionsEvent):
+		case <-e.exit:
+			return false
+		}
+	case listenerEventFile:
+		select {
+		case l.File <- e.value.(*PNFilesEvent):
+		case <-e.exit:
+			return false
+		}
+	}
+	return true
+}
+
+// listenerQueue is the bounded buffer of a Listener. Events are pushed by the
+// ListenerManager and delivered by a dispatcher goroutine which runs only
+// while the queue is not empty.
+type listenerQueue struct {
+	// accessed atomically, kept first for alignment on 32 bit platforms
+	dropped  uint64
+	maxDepth int64
+
+	sync.Mutex
+	events   chan listenerEvent
+	policy   ListenerOverflowPolicy
+	running  bool
+	dropping bool
+}
+
+// push adds the event to the queue according to the overflow policy. It
+// returns true when the push started a new run of dropped events, which the
+// caller reports with a status.
+func (q *listenerQueue) push(l *Listener, e listenerEvent) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	dropped := false
+	switch q.policy {
+	case PNDropNewestOverflowPolicy:
+		select {
+		case q.events <- e:
+		default:
+			e.release()
+			dropped = true
+		}
+	case PNDropOldestOverflowPolicy:
+		for pushed := false; !pushed; {
+			select {
+			case q.events <- e:
+				pushed = true
+			default:
+				select {
+				case old := <-q.events:
+					old.release()
+					dropped = true
+				default:
+				}
+			}
+		}
+	default:
+		select {
+		case q.events <- e:
+		case <-e.exit:
+			return false
+		}
+	}
+
+	if depth := int64(len(q.events)); depth > atomic.LoadInt64(&q.maxDepth) {
+		atomic.StoreInt64(&q.maxDepth, depth)
+	}
+	if !q.running {
+		q.running = true
+		go q.dispatch(l)
+	}
+
+	if !dropped {
+		return false
+	}
+	atomic.AddUint64(&q.dropped, 1)
+	if q.dropping {
+		return false
+	}
+	q.dropping = true
+	return true
+}
+
+func (q *listenerQueue) dispatch(l *Listener) {
+	for {
+		select {
+		case e := <-q.events:
+			l.send(e)
+		default:
+			q.Lock()
+			if len(q.events) == 0 {
+				q.running = false
+				q.dropping = false
+				q.Unlock()
+				return
+			}
+			q.Unlock()
+		}
+	}
+}
+
+func newEventsDroppedStatus(l *Listener, channel string) *PNStatus {
+	status := &PNStatus{
+		Category:  PNListenerEventsDroppedCategory,
+		Operation: PNSubscribeOperation,
+		Error:     true,
+		ErrorData: fmt.Errorf("listener buffer of %d events is full, dropping events with the %s policy", cap(l.queue.events), l.queue.policy),
+	}
+	if channel != "" {
+		status.AffectedChannels = []string{channel}
+	}
+	return status
+}
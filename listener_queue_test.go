@@ -0,0 +1,162 @@
+package pubnub
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveMessage(t *testing.T, listener *Listener) *PNMessage {
+	select {
+	case msg := <-listener.Message:
+		return msg
+	case <-time.After(time.Second):
+		assert.Fail(t, "message not delivered")
+		return nil
+	}
+}
+
+func TestBufferedListenerDeliversInOrder(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 100})
+	lm.addListener(listener)
+
+	for i := 0; i < 50; i++ {
+		lm.announceMessage(&PNMessage{Channel: "ch1", Message: fmt.Sprint(i)})
+	}
+	for i := 0; i < 50; i++ {
+		msg := receiveMessage(t, listener)
+		assert.Equal(fmt.Sprint(i), msg.Message)
+	}
+
+	stats := listener.Stats()
+	assert.Equal(uint64(0), stats.Dropped)
+	assert.True(stats.MaxQueueDepth >= 1)
+}
+
+func TestBufferedListenerDropNewest(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	listener := NewListenerWithOptions(ListenerOptions{
+		BufferSize:     2,
+		OverflowPolicy: PNDropNewestOverflowPolicy,
+	})
+	lm.addListener(listener)
+
+	// the first message is taken by the dispatcher, which then waits for
+	// the consumer, two more fill the buffer
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "0"})
+	assert.Eventually(func() bool { return listener.Stats().QueueDepth == 0 }, time.Second, time.Millisecond)
+	for i := 1; i < 6; i++ {
+		lm.announceMessage(&PNMessage{Channel: "ch1", Message: fmt.Sprint(i)})
+	}
+
+	select {
+	case status := <-listener.Status:
+		assert.Equal(PNListenerEventsDroppedCategory, status.Category)
+		assert.Equal([]string{"ch1"}, status.AffectedChannels)
+	case <-time.After(time.Second):
+		assert.Fail("dropped status not announced")
+	}
+
+	assert.Equal(uint64(3), listener.Stats().Dropped)
+	assert.Equal(2, listener.Stats().MaxQueueDepth)
+	assert.Equal("0", receiveMessage(t, listener).Message)
+	assert.Equal("1", receiveMessage(t, listener).Message)
+	assert.Equal("2", receiveMessage(t, listener).Message)
+}
+
+func TestBufferedListenerDropOldest(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	listener := NewListenerWithOptions(ListenerOptions{
+		BufferSize:     2,
+		OverflowPolicy: PNDropOldestOverflowPolicy,
+	})
+	lm.addListener(listener)
+
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "0"})
+	assert.Eventually(func() bool { return listener.Stats().QueueDepth == 0 }, time.Second, time.Millisecond)
+	for i := 1; i < 6; i++ {
+		lm.announceMessage(&PNMessage{Channel: "ch1", Message: fmt.Sprint(i)})
+	}
+
+	assert.Equal(uint64(3), listener.Stats().Dropped)
+	assert.Equal("0", receiveMessage(t, listener).Message)
+	assert.Equal("4", receiveMessage(t, listener).Message)
+	assert.Equal("5", receiveMessage(t, listener).Message)
+}
+
+func TestBufferedListenerBlock(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 1})
+	lm.addListener(listener)
+
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "0"})
+	assert.Eventually(func() bool { return listener.Stats().QueueDepth == 0 }, time.Second, time.Millisecond)
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "1"})
+
+	done := make(chan bool)
+	go func() {
+		lm.announceMessage(&PNMessage{Channel: "ch1", Message: "2"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		assert.Fail("announce did not wait for the full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal("0", receiveMessage(t, listener).Message)
+	<-done
+	assert.Equal("1", receiveMessage(t, listener).Message)
+	assert.Equal("2", receiveMessage(t, listener).Message)
+	assert.Equal(uint64(0), listener.Stats().Dropped)
+}
+
+func TestUnbufferedListenerStats(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ListenerStats{}, NewListener().Stats())
+}
+
+func TestBufferedListenerStatusNotQueued(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	listener := NewListenerWithOptions(ListenerOptions{BufferSize: 1})
+	lm.addListener(listener)
+
+	// the Status channel is never read
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 3; i++ {
+			lm.announceStatus(&PNStatus{Category: PNConnectedCategory})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail("announceStatus waited for the consumer")
+	}
+
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "0"})
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "1"})
+	assert.Equal("0", receiveMessage(t, listener).Message)
+	assert.Equal("1", receiveMessage(t, listener).Message)
+}
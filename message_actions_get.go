@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/pubnub/go/v7/pnerr"
 )
@@ -52,6 +53,16 @@ func (b *getMessageActionsBuilder) End(timetoken string) *getMessageActionsBuild
 	return b
 }
 
+// Since returns the actions added at t or later, it sets End.
+func (b *getMessageActionsBuilder) Since(t time.Time) *getMessageActionsBuilder {
+	return b.End(TimetokenFromTime(t).String())
+}
+
+// Until returns the actions added before t, it sets Start.
+func (b *getMessageActionsBuilder) Until(t time.Time) *getMessageActionsBuilder {
+	return b.Start(TimetokenFromTime(t).String())
+}
+
 func (b *getMessageActionsBuilder) Limit(limit int) *getMessageActionsBuilder {
 	b.opts.Limit = limit
 
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pubnub/go/v7/pnerr"
 	"github.com/pubnub/go/v7/utils"
@@ -57,6 +58,16 @@ func (b *messageCountsBuilder) ChannelsTimetoken(channelsTimetoken []int64) *mes
 	return b
 }
 
+// ChannelsSince counts the messages published since the times, in order of
+// the channels list, it sets ChannelsTimetoken.
+func (b *messageCountsBuilder) ChannelsSince(times []time.Time) *messageCountsBuilder {
+	timetokens := make([]int64, len(times))
+	for i, t := range times {
+		timetokens[i] = TimetokenFromTime(t).Int64()
+	}
+	return b.ChannelsTimetoken(timetokens)
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *messageCountsBuilder) QueryParam(queryParam map[string]string) *messageCountsBuilder {
 	b.opts.QueryParam = queryParam
@@ -0,0 +1,623 @@
+package pubnub
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrObjectNotFound is returned by an ObjectsCache for a UUID or channel
+// removed by an event newer than the fetched metadata.
+var ErrObjectNotFound = errors.New("pubnub: object not found")
+
+// ObjectsCacheChange is a change of an ObjectsCache made by an Objects event.
+type ObjectsCacheChange struct {
+	// Type is PNObjectsUUIDEvent, PNObjectsChannelEvent or
+	// PNObjectsMembershipEvent.
+	Type PNObjectsEventType
+	// Event is PNObjectsEventSet or PNObjectsEventRemove.
+	Event     PNObjectsEvent
+	UUID      string
+	ChannelID string
+}
+
+// ObjectsCacheOptions is used to set the optional parameters of an
+// ObjectsCache.
+type ObjectsCacheOptions struct {
+	// OnChange is called with each change made by an event, from the
+	// goroutine of the cache.
+	OnChange func(ObjectsCacheChange)
+}
+
+// ObjectsCache keeps the UUID and channel metadata, the memberships and the
+// channel members read through it. They are requested from the Objects API
+// the first time they are read, then patched from the UUID, channel and
+// membership events of the subscribed channels. An event older than the
+// cached object, by Updated, is ignored. A removal is remembered for
+// objectsCacheRemovalTTL, or until the object is invalidated: an object set
+// meanwhile only comes back when it is newer than the removal.
+type ObjectsCache struct {
+	sync.RWMutex
+	pubnub   *PubNub
+	options  ObjectsCacheOptions
+	uuids    map[string]PNUUID
+	channels map[string]PNChannel
+	// memberships and members are keyed by UUID and channel, then by the
+	// channel and UUID of the membership.
+	memberships map[string]map[string]PNMemberships
+	members     map[string]map[string]PNChannelMembers
+	// pendingMemberships and pendingMembers collect the membership events
+	// received while the memberships and members are fetched.
+	pendingMemberships map[string]map[string]PNMemberships
+	pendingMembers     map[string]map[string]PNChannelMembers
+
+	// the removed objects, the membership ones by UUID then channel
+	uuidRemovals       map[string]objectVersion
+	channelRemovals    map[string]objectVersion
+	membershipRemovals map[string]map[string]objectVersion
+	lastPrune          time.Time
+
+	listener *Listener
+	exit     chan struct{}
+	stopOnce sync.Once
+}
+
+// objectVersion is the Updated and ETag of a removal and when it was
+// received.
+type objectVersion struct {
+	updated   string
+	eTag      string
+	removedAt time.Time
+}
+
+// objectsCacheRemovalTTL is how long a removal is remembered, long enough to
+// outlast the fetches and events sent before it.
+const objectsCacheRemovalTTL = 10 * time.Minute
+
+// objectsCacheBufferSize is the buffer of the listener of an ObjectsCache,
+// which never drops events.
+const objectsCacheBufferSize = 100
+
+// ObjectsCache creates an empty ObjectsCache, Start starts following the
+// Objects events.
+func (pn *PubNub) ObjectsCache(options ObjectsCacheOptions) *ObjectsCache {
+	return &ObjectsCache{
+		pubnub:             pn,
+		options:            options,
+		uuids:              make(map[string]PNUUID),
+		channels:           make(map[string]PNChannel),
+		memberships:        make(map[string]map[string]PNMemberships),
+		members:            make(map[string]map[string]PNChannelMembers),
+		pendingMemberships: make(map[string]map[string]PNMemberships),
+		pendingMembers:     make(map[string]map[string]PNChannelMembers),
+		uuidRemovals:       make(map[string]objectVersion),
+		channelRemovals:    make(map[string]objectVersion),
+		membershipRemovals: make(map[string]map[string]objectVersion),
+		listener:           NewListenerWithOptions(ListenerOptions{BufferSize: objectsCacheBufferSize}),
+		exit:               make(chan struct{}),
+	}
+}
+
+// Start adds the listener of the cache to the PubNub instance. The events
+// are those of the channels the instance subscribes to: the channel of a
+// UUID or of a channel for their metadata and memberships.
+func (c *ObjectsCache) Start() {
+	c.pubnub.AddListener(c.listener)
+	go c.run()
+}
+
+// Stop removes the listener of the cache, the cached objects are no longer
+// updated.
+func (c *ObjectsCache) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.exit)
+		c.pubnub.RemoveListener(c.listener)
+	})
+}
+
+// UUIDMetadata returns the metadata of the UUID, with its custom fields.
+func (c *ObjectsCache) UUIDMetadata(uuid string) (PNUUID, error) {
+	c.RLock()
+	data, ok := c.uuids[uuid]
+	c.RUnlock()
+	if ok {
+		return data, nil
+	}
+
+	resp, _, err := c.pubnub.GetUUIDMetadata().UUID(uuid).
+		Include([]PNUUIDMetadataInclude{PNUUIDMetadataIncludeCustom}).Execute()
+	if err != nil {
+		return PNUUID{}, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.setUUID(resp.Data)
+	data, ok = c.uuids[uuid]
+	if !ok {
+		return PNUUID{}, ErrObjectNotFound
+	}
+	return data, nil
+}
+
+// ChannelMetadata returns the metadata of the channel, with its custom
+// fields.
+func (c *ObjectsCache) ChannelMetadata(channel string) (PNChannel, error) {
+	c.RLock()
+	data, ok := c.channels[channel]
+	c.RUnlock()
+	if ok {
+		return data, nil
+	}
+
+	resp, _, err := c.pubnub.GetChannelMetadata().Channel(channel).
+		Include([]PNChannelMetadataInclude{PNChannelMetadataIncludeCustom}).Execute()
+	if err != nil {
+		return PNChannel{}, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.setChannel(resp.Data)
+	data, ok = c.channels[channel]
+	if !ok {
+		return PNChannel{}, ErrObjectNotFound
+	}
+	return data, nil
+}
+
+// Memberships returns all the memberships of the UUID, with their custom
+// fields and the metadata of their channels.
+func (c *ObjectsCache) Memberships(uuid string) ([]PNMemberships, error) {
+	c.Lock()
+	memberships, ok := c.memberships[uuid]
+	if ok {
+		list := membershipsList(memberships)
+		c.Unlock()
+		return list, nil
+	}
+	if _, ok := c.pendingMemberships[uuid]; !ok {
+		c.pendingMemberships[uuid] = make(map[string]PNMemberships)
+	}
+	c.Unlock()
+
+	it := c.pubnub.GetMemberships().UUID(uuid).Include([]PNMembershipsInclude{
+		PNMembershipsIncludeCustom, PNMembershipsIncludeChannel, PNMembershipsIncludeChannelCustom,
+	}).Limit(100).Iterator()
+	fetched := []PNMemberships{}
+	if err := it.ForEach(nil, func(membership PNMemberships) bool {
+		fetched = append(fetched, membership)
+		return true
+	}); err != nil {
+		c.Lock()
+		delete(c.pendingMemberships, uuid)
+		c.Unlock()
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	// the events received during the fetch win over older fetched ones
+	if _, ok := c.memberships[uuid]; !ok {
+		c.memberships[uuid] = c.pendingMemberships[uuid]
+		if c.memberships[uuid] == nil {
+			c.memberships[uuid] = make(map[string]PNMemberships, len(fetched))
+		}
+	}
+	delete(c.pendingMemberships, uuid)
+	for _, membership := range fetched {
+		c.setMembership(c.memberships[uuid], uuid, membership)
+	}
+	return membershipsList(c.memberships[uuid]), nil
+}
+
+// ChannelMembers returns all the members of the channel, with their custom
+// fields and the metadata of their UUIDs.
+func (c *ObjectsCache) ChannelMembers(channel string) ([]PNChannelMembers, error) {
+	c.Lock()
+	members, ok := c.members[channel]
+	if ok {
+		list := membersList(members)
+		c.Unlock()
+		return list, nil
+	}
+	if _, ok := c.pendingMembers[channel]; !ok {
+		c.pendingMembers[channel] = make(map[string]PNChannelMembers)
+	}
+	c.Unlock()
+
+	it := c.pubnub.GetChannelMembers().Channel(channel).Include([]PNChannelMembersInclude{
+		PNChannelMembersIncludeCustom, PNChannelMembersIncludeUUID, PNChannelMembersIncludeUUIDCustom,
+	}).Limit(100).Iterator()
+	fetched := []PNChannelMembers{}
+	if err := it.ForEach(nil, func(member PNChannelMembers) bool {
+		fetched = append(fetched, member)
+		return true
+	}); err != nil {
+		c.Lock()
+		delete(c.pendingMembers, channel)
+		c.Unlock()
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.members[channel]; !ok {
+		c.members[channel] = c.pendingMembers[channel]
+		if c.members[channel] == nil {
+			c.members[channel] = make(map[string]PNChannelMembers, len(fetched))
+		}
+	}
+	delete(c.pendingMembers, channel)
+	for _, member := range fetched {
+		c.setMember(c.members[channel], channel, member)
+	}
+	return membersList(c.members[channel]), nil
+}
+
+// Invalidate drops the cached metadata, memberships and members of the UUIDs
+// and channels and their removals, they are requested again on their next
+// read.
+func (c *ObjectsCache) Invalidate(uuids, channels []string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, uuid := range uuids {
+		delete(c.uuids, uuid)
+		delete(c.memberships, uuid)
+		delete(c.uuidRemovals, uuid)
+		delete(c.membershipRemovals, uuid)
+	}
+	for _, channel := range channels {
+		delete(c.channels, channel)
+		delete(c.members, channel)
+		delete(c.channelRemovals, channel)
+		for uuid, removals := range c.membershipRemovals {
+			delete(removals, channel)
+			if len(removals) == 0 {
+				delete(c.membershipRemovals, uuid)
+			}
+		}
+	}
+}
+
+func (c *ObjectsCache) run() {
+	for {
+		select {
+		case <-c.exit:
+			return
+		case event := <-c.listener.UUIDEvent:
+			c.applyUUIDEvent(event)
+		case event := <-c.listener.ChannelEvent:
+			c.applyChannelEvent(event)
+		case event := <-c.listener.MembershipEvent:
+			c.applyMembershipEvent(event)
+		// the other events of the channels
+		case <-c.listener.Status:
+		case <-c.listener.Message:
+		case <-c.listener.Presence:
+		case <-c.listener.Signal:
+		case <-c.listener.MessageActionsEvent:
+		case <-c.listener.File:
+		}
+	}
+}
+
+func (c *ObjectsCache) applyUUIDEvent(event *PNUUIDEvent) {
+	c.Lock()
+	changed := false
+	switch event.Event {
+	case PNObjectsEventSet:
+		data := PNUUID{
+			ID:         event.UUID,
+			Name:       event.Name,
+			ExternalID: event.ExternalID,
+			ProfileURL: event.ProfileURL,
+			Email:      event.Email,
+			Updated:    event.Updated,
+			ETag:       event.ETag,
+			Custom:     event.Custom,
+		}
+		if cached, ok := c.uuids[event.UUID]; ok && data.Custom == nil {
+			// the custom fields are only sent when they change
+			data.Custom = cached.Custom
+		}
+		changed = c.setUUID(data)
+	case PNObjectsEventRemove:
+		c.uuidRemovals[event.UUID] = c.removal(event.Updated, event.ETag)
+		_, changed = c.uuids[event.UUID]
+		delete(c.uuids, event.UUID)
+		delete(c.memberships, event.UUID)
+		for _, members := range c.members {
+			if _, ok := members[event.UUID]; ok {
+				delete(members, event.UUID)
+				changed = true
+			}
+		}
+	}
+	c.Unlock()
+
+	if changed {
+		c.notify(ObjectsCacheChange{Type: PNObjectsUUIDEvent, Event: event.Event, UUID: event.UUID})
+	}
+}
+
+func (c *ObjectsCache) applyChannelEvent(event *PNChannelEvent) {
+	c.Lock()
+	changed := false
+	switch event.Event {
+	case PNObjectsEventSet:
+		data := PNChannel{
+			ID:          event.ChannelID,
+			Name:        event.Name,
+			Description: event.Description,
+			Updated:     event.Updated,
+			ETag:        event.ETag,
+			Custom:      event.Custom,
+		}
+		if cached, ok := c.channels[event.ChannelID]; ok && data.Custom == nil {
+			data.Custom = cached.Custom
+		}
+		changed = c.setChannel(data)
+	case PNObjectsEventRemove:
+		c.channelRemovals[event.ChannelID] = c.removal(event.Updated, event.ETag)
+		_, changed = c.channels[event.ChannelID]
+		delete(c.channels, event.ChannelID)
+		delete(c.members, event.ChannelID)
+		for _, memberships := range c.memberships {
+			if _, ok := memberships[event.ChannelID]; ok {
+				delete(memberships, event.ChannelID)
+				changed = true
+			}
+		}
+	}
+	c.Unlock()
+
+	if changed {
+		c.notify(ObjectsCacheChange{Type: PNObjectsChannelEvent, Event: event.Event, ChannelID: event.ChannelID})
+	}
+}
+
+func (c *ObjectsCache) applyMembershipEvent(event *PNMembershipEvent) {
+	c.Lock()
+	changed := false
+	switch event.Event {
+	case PNObjectsEventSet:
+		if memberships := c.membershipsOf(event.UUID); memberships != nil {
+			membership := PNMemberships{
+				Channel: c.channels[event.ChannelID],
+				Updated: event.Updated,
+				ETag:    event.ETag,
+				Custom:  event.Custom,
+			}
+			membership.Channel.ID = event.ChannelID
+			if cached, ok := memberships[event.ChannelID]; ok {
+				membership.Channel = cached.Channel
+				membership.Created = cached.Created
+				if membership.Custom == nil {
+					membership.Custom = cached.Custom
+				}
+			}
+			changed = c.setMembership(memberships, event.UUID, membership) || changed
+		}
+		if members := c.membersOf(event.ChannelID); members != nil {
+			member := PNChannelMembers{
+				UUID:    c.uuids[event.UUID],
+				Updated: event.Updated,
+				ETag:    event.ETag,
+				Custom:  event.Custom,
+			}
+			member.UUID.ID = event.UUID
+			if cached, ok := members[event.UUID]; ok {
+				member.UUID = cached.UUID
+				member.Created = cached.Created
+				if member.Custom == nil {
+					member.Custom = cached.Custom
+				}
+			}
+			changed = c.setMember(members, event.ChannelID, member) || changed
+		}
+	case PNObjectsEventRemove:
+		removal := c.removal(event.Updated, event.ETag)
+		if _, ok := c.membershipRemovals[event.UUID]; !ok {
+			c.membershipRemovals[event.UUID] = make(map[string]objectVersion)
+		}
+		c.membershipRemovals[event.UUID][event.ChannelID] = removal
+		if memberships := c.membershipsOf(event.UUID); memberships != nil {
+			if _, ok := memberships[event.ChannelID]; ok {
+				delete(memberships, event.ChannelID)
+				changed = true
+			}
+		}
+		if members := c.membersOf(event.ChannelID); members != nil {
+			if _, ok := members[event.UUID]; ok {
+				delete(members, event.UUID)
+				changed = true
+			}
+		}
+	}
+	c.Unlock()
+
+	if changed {
+		c.notify(ObjectsCacheChange{Type: PNObjectsMembershipEvent, Event: event.Event, UUID: event.UUID, ChannelID: event.ChannelID})
+	}
+}
+
+// removal returns the version of a removal received now, and forgets the
+// removals older than objectsCacheRemovalTTL at most once per TTL.
+func (c *ObjectsCache) removal(updated, eTag string) objectVersion {
+	now := time.Now()
+	if now.Sub(c.lastPrune) >= objectsCacheRemovalTTL {
+		c.pruneRemovals(now.Add(-objectsCacheRemovalTTL))
+		c.lastPrune = now
+	}
+	return objectVersion{updated: updated, eTag: eTag, removedAt: now}
+}
+
+// pruneRemovals forgets the removals received before the time.
+func (c *ObjectsCache) pruneRemovals(before time.Time) {
+	for uuid, removal := range c.uuidRemovals {
+		if removal.removedAt.Before(before) {
+			delete(c.uuidRemovals, uuid)
+		}
+	}
+	for channel, removal := range c.channelRemovals {
+		if removal.removedAt.Before(before) {
+			delete(c.channelRemovals, channel)
+		}
+	}
+	for uuid, removals := range c.membershipRemovals {
+		for channel, removal := range removals {
+			if removal.removedAt.Before(before) {
+				delete(removals, channel)
+			}
+		}
+		if len(removals) == 0 {
+			delete(c.membershipRemovals, uuid)
+		}
+	}
+}
+
+// membershipsOf returns the cached memberships of the UUID, or the ones
+// collected while they are fetched, nil when neither.
+func (c *ObjectsCache) membershipsOf(uuid string) map[string]PNMemberships {
+	if memberships, ok := c.memberships[uuid]; ok {
+		return memberships
+	}
+	return c.pendingMemberships[uuid]
+}
+
+// membersOf returns the cached members of the channel, or the ones collected
+// while they are fetched, nil when neither.
+func (c *ObjectsCache) membersOf(channel string) map[string]PNChannelMembers {
+	if members, ok := c.members[channel]; ok {
+		return members
+	}
+	return c.pendingMembers[channel]
+}
+
+// setUUID caches the UUID metadata unless the cached one is newer, and
+// updates the UUID of the cached members. It reports whether it changed.
+func (c *ObjectsCache) setUUID(data PNUUID) bool {
+	if cached, ok := c.uuids[data.ID]; ok && !isNewerObject(data.Updated, data.ETag, cached.Updated, cached.ETag) {
+		return false
+	}
+	if removal, ok := c.uuidRemovals[data.ID]; ok {
+		if !isNewerObject(data.Updated, data.ETag, removal.updated, removal.eTag) {
+			return false
+		}
+		delete(c.uuidRemovals, data.ID)
+	}
+	c.uuids[data.ID] = data
+	for _, members := range c.members {
+		if member, ok := members[data.ID]; ok {
+			member.UUID = data
+			members[data.ID] = member
+		}
+	}
+	return true
+}
+
+// setChannel caches the channel metadata unless the cached one is newer, and
+// updates the channel of the cached memberships. It reports whether it
+// changed.
+func (c *ObjectsCache) setChannel(data PNChannel) bool {
+	if cached, ok := c.channels[data.ID]; ok && !isNewerObject(data.Updated, data.ETag, cached.Updated, cached.ETag) {
+		return false
+	}
+	if removal, ok := c.channelRemovals[data.ID]; ok {
+		if !isNewerObject(data.Updated, data.ETag, removal.updated, removal.eTag) {
+			return false
+		}
+		delete(c.channelRemovals, data.ID)
+	}
+	c.channels[data.ID] = data
+	for _, memberships := range c.memberships {
+		if membership, ok := memberships[data.ID]; ok {
+			membership.Channel = data
+			memberships[data.ID] = membership
+		}
+	}
+	return true
+}
+
+// setMembership sets the membership in the memberships of the UUID unless
+// the cached one or its removal is newer. It reports whether it changed.
+func (c *ObjectsCache) setMembership(memberships map[string]PNMemberships, uuid string, membership PNMemberships) bool {
+	id := membership.Channel.ID
+	if cached, ok := memberships[id]; ok && !isNewerObject(membership.Updated, membership.ETag, cached.Updated, cached.ETag) {
+		return false
+	}
+	if !c.beatsMembershipRemoval(uuid, id, membership.Updated, membership.ETag) {
+		return false
+	}
+	memberships[id] = membership
+	return true
+}
+
+// setMember sets the member in the members of the channel unless the cached
+// one or its removal is newer. It reports whether it changed.
+func (c *ObjectsCache) setMember(members map[string]PNChannelMembers, channel string, member PNChannelMembers) bool {
+	id := member.UUID.ID
+	if cached, ok := members[id]; ok && !isNewerObject(member.Updated, member.ETag, cached.Updated, cached.ETag) {
+		return false
+	}
+	if !c.beatsMembershipRemoval(id, channel, member.Updated, member.ETag) {
+		return false
+	}
+	members[id] = member
+	return true
+}
+
+// beatsMembershipRemoval reports whether a membership of the UUID and the
+// channel is newer than its removal, if any.
+func (c *ObjectsCache) beatsMembershipRemoval(uuid, channel, updated, eTag string) bool {
+	removal, ok := c.membershipRemovals[uuid][channel]
+	if !ok {
+		return true
+	}
+	return isNewerObject(updated, eTag, removal.updated, removal.eTag)
+}
+
+func (c *ObjectsCache) notify(change ObjectsCacheChange) {
+	if c.options.OnChange != nil {
+		c.options.OnChange(change)
+	}
+}
+
+// isNewerObject reports whether an object replaces the cached one: it is
+// updated later, or at the same time with another ETag. The Updated dates
+// are ISO 8601 UTC, they compare as strings; an object without one always
+// replaces the cached one.
+func isNewerObject(updated, eTag, cachedUpdated, cachedETag string) bool {
+	if updated == "" || cachedUpdated == "" {
+		return eTag == "" || eTag != cachedETag
+	}
+	if updated != cachedUpdated {
+		return updated > cachedUpdated
+	}
+	return eTag != cachedETag
+}
+
+func membershipsList(memberships map[string]PNMemberships) []PNMemberships {
+	list := make([]PNMemberships, 0, len(memberships))
+	for _, membership := range memberships {
+		list = append(list, membership)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Channel.ID < list[j].Channel.ID
+	})
+	return list
+}
+
+func membersList(members map[string]PNChannelMembers) []PNChannelMembers {
+	list := make([]PNChannelMembers, 0, len(members))
+	for _, member := range members {
+		list = append(list, member)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UUID.ID < list[j].UUID.ID
+	})
+	return list
+}
@@ -0,0 +1,219 @@
+package pubnub
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectsCacheUUIDMetadata(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":{"id":"u1","name":"Ann","updated":"2020-01-02T00:00:00.000000Z","eTag":"e2","custom":{"k":"v"}}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	changes := []ObjectsCacheChange{}
+	cache := pn.ObjectsCache(ObjectsCacheOptions{
+		OnChange: func(change ObjectsCacheChange) {
+			changes = append(changes, change)
+		},
+	})
+
+	data, err := cache.UUIDMetadata("u1")
+	assert.Nil(err)
+	assert.Equal("Ann", data.Name)
+	_, err = cache.UUIDMetadata("u1")
+	assert.Nil(err)
+	assert.Len(transport.requests, 1)
+	assert.Contains(transport.requests[0].URL.Query().Get("include"), "custom")
+
+	// older, then newer without custom fields
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventSet, UUID: "u1", Name: "Old", Updated: "2020-01-01T00:00:00.000000Z", ETag: "e1"})
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventSet, UUID: "u1", Name: "Bob", Updated: "2020-01-03T00:00:00.000000Z", ETag: "e3"})
+	data, _ = cache.UUIDMetadata("u1")
+	assert.Equal("Bob", data.Name)
+	assert.Equal(map[string]interface{}{"k": "v"}, data.Custom)
+
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventRemove, UUID: "u1"})
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventRemove, UUID: "u2"})
+	cache.RLock()
+	_, ok := cache.uuids["u1"]
+	cache.RUnlock()
+	assert.False(ok)
+
+	assert.Equal([]ObjectsCacheChange{
+		{Type: PNObjectsUUIDEvent, Event: PNObjectsEventSet, UUID: "u1"},
+		{Type: PNObjectsUUIDEvent, Event: PNObjectsEventRemove, UUID: "u1"},
+	}, changes)
+}
+
+func TestObjectsCacheMemberships(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":[{"channel":{"id":"ch2","name":"Two"},"updated":"2020-01-01T00:00:00.000000Z","eTag":"m2"},{"channel":{"id":"ch1","name":"One"},"custom":{"role":"admin"},"updated":"2020-01-01T00:00:00.000000Z","eTag":"m1"}],"next":"","totalCount":2}`, nil),
+			newSequenceResponse(200, `{"status":200,"data":[{"uuid":{"id":"u1","name":"Ann"},"updated":"2020-01-01T00:00:00.000000Z","eTag":"m1"}],"next":""}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	cache := pn.ObjectsCache(ObjectsCacheOptions{})
+
+	memberships, err := cache.Memberships("u1")
+	assert.Nil(err)
+	if assert.Len(memberships, 2) {
+		assert.Equal("ch1", memberships[0].Channel.ID)
+		assert.Equal("One", memberships[0].Channel.Name)
+	}
+	members, err := cache.ChannelMembers("ch1")
+	assert.Nil(err)
+	assert.Len(members, 1)
+
+	cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventSet, UUID: "u1", ChannelID: "ch3", Updated: "2020-01-02T00:00:00.000000Z", ETag: "m3"})
+	cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventSet, UUID: "u1", ChannelID: "ch1", Custom: map[string]interface{}{"role": "user"}, Updated: "2020-01-02T00:00:00.000000Z", ETag: "m4"})
+	cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventRemove, UUID: "u1", ChannelID: "ch2"})
+	cache.applyChannelEvent(&PNChannelEvent{Event: PNObjectsEventSet, ChannelID: "ch1", Name: "First", Updated: "2020-01-02T00:00:00.000000Z", ETag: "c2"})
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventSet, UUID: "u1", Name: "Bob", Updated: "2020-01-02T00:00:00.000000Z", ETag: "u2"})
+
+	memberships, _ = cache.Memberships("u1")
+	if assert.Len(memberships, 2) {
+		assert.Equal("ch1", memberships[0].Channel.ID)
+		assert.Equal("First", memberships[0].Channel.Name)
+		assert.Equal(map[string]interface{}{"role": "user"}, memberships[0].Custom)
+		assert.Equal("ch3", memberships[1].Channel.ID)
+	}
+	members, _ = cache.ChannelMembers("ch1")
+	if assert.Len(members, 1) {
+		assert.Equal("Bob", members[0].UUID.Name)
+		assert.Equal(map[string]interface{}{"role": "user"}, members[0].Custom)
+	}
+	assert.Len(transport.requests, 2)
+
+	cache.Invalidate(nil, []string{"ch1"})
+	cache.RLock()
+	_, ok := cache.members["ch1"]
+	cache.RUnlock()
+	assert.False(ok)
+}
+
+func TestObjectsCacheRemovalTombstone(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":{"id":"u1","name":"Ann","updated":"2020-01-01T00:00:00.000000Z","eTag":"e1"}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	cache := pn.ObjectsCache(ObjectsCacheOptions{})
+
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventRemove, UUID: "u1", Updated: "2020-01-02T00:00:00.000000Z", ETag: "e2"})
+	// the stale fetch and set don't bring the removed UUID back
+	_, err := cache.UUIDMetadata("u1")
+	assert.Equal(ErrObjectNotFound, err)
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventSet, UUID: "u1", Name: "Old", Updated: "2020-01-01T12:00:00.000000Z", ETag: "e3"})
+	cache.RLock()
+	_, ok := cache.uuids["u1"]
+	cache.RUnlock()
+	assert.False(ok)
+
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventSet, UUID: "u1", Name: "New", Updated: "2020-01-03T00:00:00.000000Z", ETag: "e4"})
+	data, err := cache.UUIDMetadata("u1")
+	assert.Nil(err)
+	assert.Equal("New", data.Name)
+	assert.Empty(cache.uuidRemovals)
+
+	cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventRemove, UUID: "u1", ChannelID: "ch1", Updated: "2020-01-02T00:00:00.000000Z", ETag: "m2"})
+	cache.Lock()
+	memberships := map[string]PNMemberships{}
+	assert.False(cache.setMembership(memberships, "u1", PNMemberships{Channel: PNChannel{ID: "ch1"}, Updated: "2020-01-01T00:00:00.000000Z", ETag: "m1"}))
+	assert.True(cache.setMembership(memberships, "u1", PNMemberships{Channel: PNChannel{ID: "ch1"}, Updated: "2020-01-03T00:00:00.000000Z", ETag: "m3"}))
+	cache.Unlock()
+}
+
+func TestObjectsCacheForgetsRemovals(t *testing.T) {
+	assert := assert.New(t)
+	cache := NewPubNub(NewDemoConfig()).ObjectsCache(ObjectsCacheOptions{})
+
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventRemove, UUID: "u1"})
+	cache.applyChannelEvent(&PNChannelEvent{Event: PNObjectsEventRemove, ChannelID: "ch1"})
+	cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventRemove, UUID: "u2", ChannelID: "ch1"})
+	cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventRemove, UUID: "u2", ChannelID: "ch2"})
+
+	cache.Invalidate([]string{"u1"}, []string{"ch1"})
+	cache.RLock()
+	assert.Empty(cache.uuidRemovals)
+	assert.Empty(cache.channelRemovals)
+	assert.Equal(1, len(cache.membershipRemovals["u2"]))
+	cache.RUnlock()
+
+	// the removals past the TTL go with the next removal
+	cache.Lock()
+	expired := cache.membershipRemovals["u2"]["ch2"]
+	expired.removedAt = expired.removedAt.Add(-objectsCacheRemovalTTL - time.Second)
+	cache.membershipRemovals["u2"]["ch2"] = expired
+	cache.lastPrune = time.Time{}
+	cache.Unlock()
+	cache.applyUUIDEvent(&PNUUIDEvent{Event: PNObjectsEventRemove, UUID: "u3"})
+	cache.RLock()
+	assert.Empty(cache.membershipRemovals)
+	assert.Equal(1, len(cache.uuidRemovals))
+	cache.RUnlock()
+}
+
+// hookTransport runs before ahead of each request.
+type hookTransport struct {
+	sequenceTransport
+	before func()
+}
+
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.before()
+	return t.sequenceTransport.RoundTrip(req)
+}
+
+func TestObjectsCacheMembershipEventDuringFetch(t *testing.T) {
+	assert := assert.New(t)
+	var cache *ObjectsCache
+	transport := &hookTransport{
+		sequenceTransport: sequenceTransport{
+			responses: []*http.Response{
+				newSequenceResponse(200, `{"status":200,"data":[{"channel":{"id":"ch1"},"updated":"2020-01-01T00:00:00.000000Z","eTag":"m1"},{"channel":{"id":"ch2"},"updated":"2020-01-01T00:00:00.000000Z","eTag":"m2"}],"next":""}`, nil),
+			},
+		},
+		before: func() {
+			cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventSet, UUID: "u1", ChannelID: "ch1", Custom: map[string]interface{}{"role": "user"}, Updated: "2020-01-02T00:00:00.000000Z", ETag: "m3"})
+			cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventSet, UUID: "u1", ChannelID: "ch3", Updated: "2020-01-02T00:00:00.000000Z", ETag: "m4"})
+			cache.applyMembershipEvent(&PNMembershipEvent{Event: PNObjectsEventRemove, UUID: "u1", ChannelID: "ch2", Updated: "2020-01-02T00:00:00.000000Z", ETag: "m5"})
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	cache = pn.ObjectsCache(ObjectsCacheOptions{})
+
+	memberships, err := cache.Memberships("u1")
+	assert.Nil(err)
+	if assert.Len(memberships, 2) {
+		assert.Equal("ch1", memberships[0].Channel.ID)
+		assert.Equal(map[string]interface{}{"role": "user"}, memberships[0].Custom)
+		assert.Equal("ch3", memberships[1].Channel.ID)
+	}
+	cache.RLock()
+	assert.Empty(cache.pendingMemberships)
+	cache.RUnlock()
+}
+
+func TestIsNewerObject(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isNewerObject("2020-01-02T00:00:00Z", "b", "2020-01-01T00:00:00Z", "a"))
+	assert.False(isNewerObject("2020-01-01T00:00:00Z", "b", "2020-01-02T00:00:00Z", "a"))
+	assert.True(isNewerObject("2020-01-01T00:00:00Z", "b", "2020-01-01T00:00:00Z", "a"))
+	assert.False(isNewerObject("2020-01-01T00:00:00Z", "a", "2020-01-01T00:00:00Z", "a"))
+	assert.True(isNewerObject("", "", "2020-01-01T00:00:00Z", "a"))
+}
@@ -0,0 +1,343 @@
+package pubnub
+
+// pageIterator follows the page tokens of a list endpoint. fetch requests the
+// page of the token and returns its number of items and the token of the next
+// page, empty on the last page. The iterators page a copy of the options of
+// their builder, which is left as it is.
+type pageIterator struct {
+	fetch    func(ctx Context, token string) (size int, next string, err error)
+	token    string
+	maxItems int
+	seen     int
+	index    int
+	size     int
+	started  bool
+	err      error
+}
+
+func newPageIterator(token string, fetch func(ctx Context, token string) (int, string, error)) pageIterator {
+	return pageIterator{
+		fetch: fetch,
+		token: token,
+		index: -1,
+	}
+}
+
+// advance moves to the next item, requesting the next page when the current
+// one is done. It returns false after the last item, on error and when ctx
+// is done.
+func (it *pageIterator) advance(ctx Context) bool {
+	if it.err != nil || (it.maxItems > 0 && it.seen >= it.maxItems) {
+		return false
+	}
+
+	it.index++
+	for it.index >= it.size {
+		if it.started && it.token == "" {
+			return false
+		}
+		if ctx != nil {
+			select {
+			case <-ctx.Done():
+				it.err = ctx.Err()
+				return false
+			default:
+			}
+		}
+
+		size, next, err := it.fetch(ctx, it.token)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.started = true
+		it.index, it.size, it.token = 0, size, next
+		if size == 0 {
+			return false
+		}
+	}
+
+	it.seen++
+	return true
+}
+
+// Err returns the error which stopped the iteration, nil when all the items
+// were iterated.
+func (it *pageIterator) Err() error {
+	return it.err
+}
+
+// ListFilesIterator iterates over the files of a channel, requesting the pages
+// of ListFiles as they are needed.
+type ListFilesIterator struct {
+	pageIterator
+	page []PNFileInfo
+}
+
+// Iterator returns the iterator over all the files of the channel, from the
+// page set by Next.
+func (b *listFilesBuilder) Iterator() *ListFilesIterator {
+	it := &ListFilesIterator{}
+	opts := *b.opts
+	pages := &listFilesBuilder{opts: &opts}
+	it.pageIterator = newPageIterator(opts.Next, func(ctx Context, token string) (int, string, error) {
+		if ctx != nil {
+			pages.opts.ctx = ctx
+		}
+		pages.opts.Next = token
+		resp, _, err := pages.Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return it
+}
+
+// MaxItems stops the iteration after max files, 0 for no limit.
+func (it *ListFilesIterator) MaxItems(max int) *ListFilesIterator {
+	it.maxItems = max
+	return it
+}
+
+// Next moves to the next file, it returns false when there is none left or
+// the iteration stopped, see Err.
+func (it *ListFilesIterator) Next(ctx Context) bool {
+	return it.advance(ctx)
+}
+
+// Item returns the current file.
+func (it *ListFilesIterator) Item() PNFileInfo {
+	return it.page[it.index]
+}
+
+// ForEach calls f for each file until it returns false.
+func (it *ListFilesIterator) ForEach(ctx Context, f func(PNFileInfo) bool) error {
+	for it.Next(ctx) {
+		if !f(it.Item()) {
+			break
+		}
+	}
+	return it.Err()
+}
+
+// GetAllUUIDMetadataIterator iterates over the UUID metadata, requesting the
+// pages of GetAllUUIDMetadata as they are needed.
+type GetAllUUIDMetadataIterator struct {
+	pageIterator
+	page []PNUUID
+}
+
+// Iterator returns the iterator over all the UUID metadata, from the page set
+// by Start.
+func (b *getAllUUIDMetadataBuilder) Iterator() *GetAllUUIDMetadataIterator {
+	it := &GetAllUUIDMetadataIterator{}
+	opts := *b.opts
+	pages := &getAllUUIDMetadataBuilder{opts: &opts}
+	it.pageIterator = newPageIterator(opts.Start, func(ctx Context, token string) (int, string, error) {
+		if ctx != nil {
+			pages.opts.ctx = ctx
+		}
+		pages.opts.Start = token
+		resp, _, err := pages.Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return it
+}
+
+// MaxItems stops the iteration after max items, 0 for no limit.
+func (it *GetAllUUIDMetadataIterator) MaxItems(max int) *GetAllUUIDMetadataIterator {
+	it.maxItems = max
+	return it
+}
+
+// Next moves to the next item, it returns false when there is none left or
+// the iteration stopped, see Err.
+func (it *GetAllUUIDMetadataIterator) Next(ctx Context) bool {
+	return it.advance(ctx)
+}
+
+// Item returns the current item.
+func (it *GetAllUUIDMetadataIterator) Item() PNUUID {
+	return it.page[it.index]
+}
+
+// ForEach calls f for each item until it returns false.
+func (it *GetAllUUIDMetadataIterator) ForEach(ctx Context, f func(PNUUID) bool) error {
+	for it.Next(ctx) {
+		if !f(it.Item()) {
+			break
+		}
+	}
+	return it.Err()
+}
+
+// GetAllChannelMetadataIterator iterates over the channel metadata,
+// requesting the pages of GetAllChannelMetadata as they are needed.
+type GetAllChannelMetadataIterator struct {
+	pageIterator
+	page []PNChannel
+}
+
+// Iterator returns the iterator over all the channel metadata, from the page
+// set by Start.
+func (b *getAllChannelMetadataBuilder) Iterator() *GetAllChannelMetadataIterator {
+	it := &GetAllChannelMetadataIterator{}
+	opts := *b.opts
+	pages := &getAllChannelMetadataBuilder{opts: &opts}
+	it.pageIterator = newPageIterator(opts.Start, func(ctx Context, token string) (int, string, error) {
+		if ctx != nil {
+			pages.opts.ctx = ctx
+		}
+		pages.opts.Start = token
+		resp, _, err := pages.Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return it
+}
+
+// MaxItems stops the iteration after max items, 0 for no limit.
+func (it *GetAllChannelMetadataIterator) MaxItems(max int) *GetAllChannelMetadataIterator {
+	it.maxItems = max
+	return it
+}
+
+// Next moves to the next item, it returns false when there is none left or
+// the iteration stopped, see Err.
+func (it *GetAllChannelMetadataIterator) Next(ctx Context) bool {
+	return it.advance(ctx)
+}
+
+// Item returns the current item.
+func (it *GetAllChannelMetadataIterator) Item() PNChannel {
+	return it.page[it.index]
+}
+
+// ForEach calls f for each item until it returns false.
+func (it *GetAllChannelMetadataIterator) ForEach(ctx Context, f func(PNChannel) bool) error {
+	for it.Next(ctx) {
+		if !f(it.Item()) {
+			break
+		}
+	}
+	return it.Err()
+}
+
+// GetChannelMembersIterator iterates over the members of a channel,
+// requesting the pages of GetChannelMembers as they are needed.
+type GetChannelMembersIterator struct {
+	pageIterator
+	page []PNChannelMembers
+}
+
+// Iterator returns the iterator over all the members of the channel, from the
+// page set by Start.
+func (b *getChannelMembersBuilderV2) Iterator() *GetChannelMembersIterator {
+	it := &GetChannelMembersIterator{}
+	opts := *b.opts
+	pages := &getChannelMembersBuilderV2{opts: &opts}
+	it.pageIterator = newPageIterator(opts.Start, func(ctx Context, token string) (int, string, error) {
+		if ctx != nil {
+			pages.opts.ctx = ctx
+		}
+		pages.opts.Start = token
+		resp, _, err := pages.Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return it
+}
+
+// MaxItems stops the iteration after max members, 0 for no limit.
+func (it *GetChannelMembersIterator) MaxItems(max int) *GetChannelMembersIterator {
+	it.maxItems = max
+	return it
+}
+
+// Next moves to the next member, it returns false when there is none left or
+// the iteration stopped, see Err.
+func (it *GetChannelMembersIterator) Next(ctx Context) bool {
+	return it.advance(ctx)
+}
+
+// Item returns the current member.
+func (it *GetChannelMembersIterator) Item() PNChannelMembers {
+	return it.page[it.index]
+}
+
+// ForEach calls f for each member until it returns false.
+func (it *GetChannelMembersIterator) ForEach(ctx Context, f func(PNChannelMembers) bool) error {
+	for it.Next(ctx) {
+		if !f(it.Item()) {
+			break
+		}
+	}
+	return it.Err()
+}
+
+// GetMembershipsIterator iterates over the memberships of a UUID, requesting
+// the pages of GetMemberships as they are needed.
+type GetMembershipsIterator struct {
+	pageIterator
+	page []PNMemberships
+}
+
+// Iterator returns the iterator over all the memberships of the UUID, from
+// the page set by Start.
+func (b *getMembershipsBuilderV2) Iterator() *GetMembershipsIterator {
+	it := &GetMembershipsIterator{}
+	opts := *b.opts
+	pages := &getMembershipsBuilderV2{opts: &opts}
+	it.pageIterator = newPageIterator(opts.Start, func(ctx Context, token string) (int, string, error) {
+		if ctx != nil {
+			pages.opts.ctx = ctx
+		}
+		pages.opts.Start = token
+		resp, _, err := pages.Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		it.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return it
+}
+
+// MaxItems stops the iteration after max memberships, 0 for no limit.
+func (it *GetMembershipsIterator) MaxItems(max int) *GetMembershipsIterator {
+	it.maxItems = max
+	return it
+}
+
+// Next moves to the next membership, it returns false when there is none
+// left or the iteration stopped, see Err.
+func (it *GetMembershipsIterator) Next(ctx Context) bool {
+	return it.advance(ctx)
+}
+
+// Item returns the current membership.
+func (it *GetMembershipsIterator) Item() PNMemberships {
+	return it.page[it.index]
+}
+
+// ForEach calls f for each membership until it returns false.
+func (it *GetMembershipsIterator) ForEach(ctx Context, f func(PNMemberships) bool) error {
+	for it.Next(ctx) {
+		if !f(it.Item()) {
+			break
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,81 @@
+package pubnub
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFilesIterator(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":[{"id":"f1"},{"id":"f2"}],"next":"n1","count":2}`, nil),
+			newSequenceResponse(200, `{"status":200,"data":[{"id":"f3"}],"count":1}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	ids := []string{}
+	b := pn.ListFiles().Channel("ch").Limit(2)
+	it := b.Iterator()
+	for it.Next(nil) {
+		ids = append(ids, it.Item().ID)
+	}
+
+	assert.Nil(it.Err())
+	assert.Equal([]string{"f1", "f2", "f3"}, ids)
+	assert.Len(transport.requests, 2)
+	assert.Equal("n1", transport.requests[1].URL.Query().Get("next"))
+	assert.Equal("2", transport.requests[1].URL.Query().Get("limit"))
+	// the builder is left as it was
+	assert.Equal("", b.opts.Next)
+}
+
+func TestGetAllUUIDMetadataIteratorMaxItems(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":[{"id":"u1"},{"id":"u2"}],"next":"n1"}`, nil),
+			newSequenceResponse(200, `{"status":200,"data":[{"id":"u3"},{"id":"u4"}],"next":"n2"}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	ids := []string{}
+	err := pn.GetAllUUIDMetadata().Filter("name == 'a'").Iterator().MaxItems(3).ForEach(nil, func(uuid PNUUID) bool {
+		ids = append(ids, uuid.ID)
+		return true
+	})
+
+	assert.Nil(err)
+	assert.Equal([]string{"u1", "u2", "u3"}, ids)
+	assert.Len(transport.requests, 2)
+	assert.Equal("n1", transport.requests[1].URL.Query().Get("start"))
+	assert.Contains(transport.requests[1].URL.String(), "filter=")
+}
+
+func TestGetMembershipsIteratorContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"data":[{"channel":{"id":"c1"}}],"next":"n1"}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	it := pn.GetMemberships().UUID("u").Iterator()
+	assert.True(it.Next(ctx))
+	assert.Equal("c1", it.Item().Channel.ID)
+	cancel()
+
+	assert.False(it.Next(ctx))
+	assert.Equal(context.Canceled, it.Err())
+	assert.Len(transport.requests, 1)
+}
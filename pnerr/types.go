@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 )
 
 // Error validating type or value of passed in params.
@@ -54,6 +55,7 @@ func NewBuildRequestError(msg string) *BuildRequestError {
 type ServerError struct {
 	StatusCode int
 	Body       []byte
+	Header     http.Header
 }
 
 func (e ServerError) Error() string {
@@ -72,9 +74,14 @@ func NewServerError(statusCode int, body io.ReadCloser) *ServerError {
 }
 
 // Something wrong with network connection.
+// OrigError holds the network cause as returned by the http client.
+// ContextError is set when the request context was already done at the time
+// of the failure, in which case the connection was torn down by the caller
+// rather than by the network.
 type ConnectionError struct {
-	message   string
-	OrigError error
+	message      string
+	OrigError    error
+	ContextError error
 }
 
 func (e ConnectionError) Error() string {
@@ -82,6 +89,11 @@ func (e ConnectionError) Error() string {
 		e.OrigError.Error())
 }
 
+// Unwrap returns the network cause so errors.Is and errors.As can inspect it.
+func (e ConnectionError) Unwrap() error {
+	return e.OrigError
+}
+
 func NewConnectionError(msg string, origError error) *ConnectionError {
 	return &ConnectionError{
 		message:   msg,
@@ -89,6 +101,14 @@ func NewConnectionError(msg string, origError error) *ConnectionError {
 	}
 }
 
+func NewConnectionErrorWithContext(msg string, origError, ctxError error) *ConnectionError {
+	return &ConnectionError{
+		message:      msg,
+		OrigError:    origError,
+		ContextError: ctxError,
+	}
+}
+
 // Malformed request or issues with decoding encrypted message
 type ResponseParsingError struct {
 	message   string
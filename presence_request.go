@@ -83,16 +83,25 @@ func (b *presenceBuilder) Execute() {
 			b.opts.pubnub.heartbeatManager.heartbeatGroups[cg] = newSubscriptionItem(cg)
 			b.opts.pubnub.heartbeatManager.Unlock()
 		}
-		b.opts.pubnub.heartbeatManager.state = b.opts.state
+		if len(b.opts.state) > 0 {
+			b.opts.pubnub.subscriptionManager.adaptState(StateOperation{
+				channels:      b.opts.channels,
+				channelGroups: b.opts.channelGroups,
+				state:         b.opts.state,
+			})
+		}
 		b.opts.pubnub.heartbeatManager.queryParam = b.opts.queryParam
 		b.opts.pubnub.heartbeatManager.startHeartbeatTimer(true)
 	} else {
 		b.opts.pubnub.heartbeatManager.Lock()
+		channels := append(b.opts.pubnub.heartbeatManager.prepareList(b.opts.pubnub.heartbeatManager.heartbeatChannels), b.opts.channels...)
+		groups := append(b.opts.pubnub.heartbeatManager.prepareList(b.opts.pubnub.heartbeatManager.heartbeatGroups), b.opts.channelGroups...)
 		b.opts.pubnub.heartbeatManager.heartbeatChannels = make(map[string]*SubscriptionItem)
 		b.opts.pubnub.heartbeatManager.heartbeatGroups = make(map[string]*SubscriptionItem)
-		b.opts.pubnub.heartbeatManager.state = nil
 		b.opts.pubnub.heartbeatManager.queryParam = nil
 
 		b.opts.pubnub.heartbeatManager.Unlock()
+		// the state set through Presence goes with it
+		b.opts.pubnub.subscriptionManager.stateManager.dropUnsubscribedState(channels, groups)
 	}
 }
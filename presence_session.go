@@ -0,0 +1,196 @@
+package pubnub
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// PresenceSession keeps the UUID of the Config online on channels and
+// channel groups with heartbeats, without subscribing to them. Several
+// sessions can run at once, each with its own heartbeat loop.
+type PresenceSession struct {
+	sync.RWMutex
+	pubnub        *PubNub
+	channels      []string
+	channelGroups []string
+	state         map[string]interface{}
+	onStatus      func(*PNStatus)
+	// heartbeatMutex is held across each heartbeat so that the last one
+	// sent carries the last state set.
+	heartbeatMutex sync.Mutex
+
+	started bool
+	// startMutex serializes Start and Close, Close waits for the loop of a
+	// Start in progress.
+	startMutex sync.Mutex
+	exit       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// PresenceSession creates the PresenceSession of the channels and channel
+// groups with the initial state, Start starts it.
+func (pn *PubNub) PresenceSession(channels, channelGroups []string, state map[string]interface{}) *PresenceSession {
+	return &PresenceSession{
+		pubnub:        pn,
+		channels:      append([]string{}, channels...),
+		channelGroups: append([]string{}, channelGroups...),
+		state:         copyState(state),
+		exit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// OnStatus sets the callback of the heartbeat failures. It is called from
+// the goroutine of the session with a PNHeartBeatOperation status.
+func (s *PresenceSession) OnStatus(f func(*PNStatus)) *PresenceSession {
+	s.Lock()
+	s.onStatus = f
+	s.Unlock()
+
+	return s
+}
+
+// Start sends the first heartbeat and then one every HeartbeatInterval of
+// the Config. The session is not started when the first heartbeat fails.
+func (s *PresenceSession) Start() error {
+	s.pubnub.Config.RLock()
+	interval := s.pubnub.Config.HeartbeatInterval
+	s.pubnub.Config.RUnlock()
+	if interval <= 0 {
+		return errors.New("presence session: HeartbeatInterval of the Config is not set")
+	}
+
+	s.startMutex.Lock()
+	defer s.startMutex.Unlock()
+	select {
+	case <-s.exit:
+		return errors.New("presence session: closed")
+	default:
+	}
+
+	// claimed before the first heartbeat, so that SetState sends the state
+	// it sets right away
+	s.Lock()
+	if s.started {
+		s.Unlock()
+		return errors.New("presence session: already started")
+	}
+	s.started = true
+	s.Unlock()
+
+	if err := s.heartbeat(); err != nil {
+		s.Lock()
+		s.started = false
+		s.Unlock()
+		return err
+	}
+	go s.run(time.Duration(interval) * time.Second)
+
+	return nil
+}
+
+// State returns a copy of the state sent with the heartbeats.
+func (s *PresenceSession) State() map[string]interface{} {
+	s.RLock()
+	defer s.RUnlock()
+
+	return copyState(s.state)
+}
+
+// SetState replaces the state of the session and sends it with a heartbeat
+// right away. The state is replaced as a whole, even when the heartbeat
+// fails; the next heartbeats carry it.
+func (s *PresenceSession) SetState(state map[string]interface{}) error {
+	s.Lock()
+	s.state = copyState(state)
+	started := s.started
+	s.Unlock()
+
+	if !started {
+		return nil
+	}
+	return s.heartbeat()
+}
+
+// Close stops the heartbeats and sends a Leave for the channels and channel
+// groups, unless SuppressLeaveEvents is set in the Config.
+func (s *PresenceSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.startMutex.Lock()
+		close(s.exit)
+		s.RLock()
+		started := s.started
+		s.RUnlock()
+		s.startMutex.Unlock()
+
+		if !started {
+			return
+		}
+		// no heartbeat is sent after the leave
+		<-s.done
+
+		if s.pubnub.Config.SuppressLeaveEvents {
+			return
+		}
+		_, err = s.pubnub.Leave().Channels(s.channels).ChannelGroups(s.channelGroups).Execute()
+	})
+	return err
+}
+
+func (s *PresenceSession) run(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.exit:
+			return
+		case <-ticker.C:
+			if err := s.heartbeat(); err != nil {
+				s.pubnub.Config.Log.Println("presence session: heartbeat failed:", err)
+			}
+		}
+	}
+}
+
+func (s *PresenceSession) heartbeat() error {
+	s.heartbeatMutex.Lock()
+	s.RLock()
+	state := s.state
+	onStatus := s.onStatus
+	s.RUnlock()
+
+	_, status, err := s.pubnub.Heartbeat().
+		Channels(s.channels).
+		ChannelGroups(s.channelGroups).
+		State(state).
+		Execute()
+	s.heartbeatMutex.Unlock()
+	if err != nil && onStatus != nil {
+		onStatus(&PNStatus{
+			Category:              categorizeError(err),
+			Operation:             PNHeartBeatOperation,
+			ErrorData:             err,
+			Error:                 true,
+			StatusCode:            status.StatusCode,
+			AffectedChannels:      s.channels,
+			AffectedChannelGroups: s.channelGroups,
+		})
+	}
+	return err
+}
+
+func copyState(state map[string]interface{}) map[string]interface{} {
+	if state == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(state))
+	for k, v := range state {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,181 @@
+package pubnub
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresenceSession(t *testing.T) {
+	assert := assert.New(t)
+	ok := `{"status":200,"message":"OK","service":"Presence"}`
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, ok, nil),
+			newSequenceResponse(200, ok, nil),
+			newSequenceResponse(403, `{"status":403,"message":"Forbidden","error":true,"service":"Access Manager"}`, nil),
+			newSequenceResponse(200, `{"status":200,"message":"OK","action":"leave","service":"Presence"}`, nil),
+		},
+	}
+	config := NewDemoConfig()
+	config.SetPresenceTimeout(300)
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: transport})
+
+	statuses := []*PNStatus{}
+	session := pn.PresenceSession([]string{"ch1", "ch2"}, []string{"cg"}, map[string]interface{}{"mood": "ok"}).
+		OnStatus(func(status *PNStatus) {
+			statuses = append(statuses, status)
+		})
+	assert.Nil(session.Start())
+	assert.NotNil(session.Start())
+	assert.Nil(session.SetState(map[string]interface{}{"mood": "busy"}))
+	assert.NotNil(session.SetState(map[string]interface{}{"mood": "away"}))
+	assert.Equal(map[string]interface{}{"mood": "away"}, session.State())
+	assert.Nil(session.Close())
+	assert.Nil(session.Close())
+
+	if assert.Len(transport.requests, 4) {
+		assert.Contains(transport.requests[0].URL.String(), "/channel/ch1,ch2/heartbeat")
+		assert.Equal("cg", transport.requests[0].URL.Query().Get("channel-group"))
+		assert.Equal(`{"mood":"ok"}`, transport.requests[0].URL.Query().Get("state"))
+		assert.Equal(`{"mood":"busy"}`, transport.requests[1].URL.Query().Get("state"))
+		assert.Contains(transport.requests[3].URL.String(), "/channel/ch1,ch2/leave")
+	}
+	if assert.Len(statuses, 1) {
+		assert.Equal(PNAccessDeniedCategory, statuses[0].Category)
+		assert.Equal(PNHeartBeatOperation, statuses[0].Operation)
+		assert.Equal(403, statuses[0].StatusCode)
+		assert.Equal([]string{"ch1", "ch2"}, statuses[0].AffectedChannels)
+	}
+}
+
+func TestPresenceSessionRequiresHeartbeatInterval(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	session := pn.PresenceSession([]string{"ch1"}, nil, nil)
+
+	assert.NotNil(session.Start())
+	assert.Nil(session.Close())
+}
+
+func TestPresenceSessionConcurrentStart(t *testing.T) {
+	assert := assert.New(t)
+	ok := `{"status":200,"message":"OK","service":"Presence"}`
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, ok, nil),
+		},
+	}
+	config := NewDemoConfig()
+	config.SetPresenceTimeout(300)
+	config.SuppressLeaveEvents = true
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: transport})
+	session := pn.PresenceSession([]string{"ch1"}, nil, nil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- session.Start()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	assert.Equal(1, failed)
+	assert.Len(transport.requests, 1)
+	assert.Nil(session.Close())
+	assert.NotNil(session.Start())
+}
+
+func TestPresenceSessionFailedStart(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(403, `{"status":403,"message":"Forbidden","error":true,"service":"Access Manager"}`, nil),
+			newSequenceResponse(200, `{"status":200,"message":"OK","service":"Presence"}`, nil),
+		},
+	}
+	config := NewDemoConfig()
+	config.SetPresenceTimeout(300)
+	config.SuppressLeaveEvents = true
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: transport})
+	session := pn.PresenceSession([]string{"ch1"}, nil, nil)
+
+	// a failed Start can be retried
+	assert.NotNil(session.Start())
+	assert.Nil(session.Start())
+	assert.Nil(session.Close())
+}
+
+// heldHeartbeatTransport holds the heartbeat sent after the first one until
+// release is closed, and records the states in the order they were sent.
+type heldHeartbeatTransport struct {
+	sync.Mutex
+	calls   int
+	states  []string
+	release chan struct{}
+}
+
+func (t *heldHeartbeatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	t.calls++
+	held := t.calls == 2
+	t.Unlock()
+	if held {
+		<-t.release
+	}
+	t.Lock()
+	t.states = append(t.states, req.URL.Query().Get("state"))
+	t.Unlock()
+	resp := newSequenceResponse(200, `{"status":200,"message":"OK","service":"Presence"}`, nil)
+	resp.Request = req
+	return resp, nil
+}
+
+func TestPresenceSessionSetStateAfterHeartbeat(t *testing.T) {
+	assert := assert.New(t)
+	transport := &heldHeartbeatTransport{release: make(chan struct{})}
+	config := NewDemoConfig()
+	config.SetPresenceTimeout(300)
+	config.SuppressLeaveEvents = true
+	pn := NewPubNub(config)
+	pn.SetClient(&http.Client{Transport: transport})
+	session := pn.PresenceSession([]string{"ch1"}, nil, map[string]interface{}{"mood": "ok"})
+	assert.Nil(session.Start())
+
+	// a heartbeat of the loop in flight with the old state
+	sent := make(chan error)
+	go func() {
+		sent <- session.heartbeat()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		sent <- session.SetState(map[string]interface{}{"mood": "busy"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(transport.release)
+	assert.Nil(<-sent)
+	assert.Nil(<-sent)
+
+	transport.Lock()
+	if assert.Len(transport.states, 3) {
+		assert.Equal(`{"mood":"busy"}`, transport.states[2])
+	}
+	transport.Unlock()
+	assert.Nil(session.Close())
+}
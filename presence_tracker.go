@@ -0,0 +1,327 @@
+package pubnub
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// PresenceMember is an occupant of a channel in the roster of a
+// PresenceTracker.
+type PresenceMember struct {
+	UUID  string
+	State interface{}
+}
+
+// PresenceChange is a change of the roster of a channel of a
+// PresenceTracker.
+type PresenceChange struct {
+	Channel string
+	Joined  []string
+	// Left are the UUIDs which left or timed out.
+	Left         []string
+	StateChanged []string
+	Occupancy    int
+	// Resync is true when the change comes from a HereNow resync.
+	Resync bool
+}
+
+// PresenceTrackerOptions is used to set the optional parameters of a
+// PresenceTracker.
+type PresenceTrackerOptions struct {
+	// OnChange is called with each change of a roster, from the goroutine
+	// of the tracker.
+	OnChange func(PresenceChange)
+}
+
+// PresenceTracker keeps the roster and the occupancy of channels. It is
+// seeded from HereNow, then follows the join, leave, timeout, state-change
+// and interval events of its presence Subscription. It resyncs from HereNow
+// when an interval event asks for it and after a reconnection.
+type PresenceTracker struct {
+	sync.RWMutex
+	pubnub    *PubNub
+	channels  []string
+	options   PresenceTrackerOptions
+	rosters   map[string]map[string]interface{}
+	occupancy map[string]int
+
+	subscription *Subscription
+	listener     *Listener
+	// seeded is closed once the HereNow seed of Start was applied, the
+	// events are held until then.
+	seeded     chan struct{}
+	seededOnce sync.Once
+	exit       chan struct{}
+	stopOnce   sync.Once
+}
+
+// presenceTrackerBufferSize is the buffer of the listener of a
+// PresenceTracker, which never drops events.
+const presenceTrackerBufferSize = 100
+
+// PresenceTracker creates the PresenceTracker of the channels, Start starts
+// it.
+func (pn *PubNub) PresenceTracker(channels []string, options PresenceTrackerOptions) *PresenceTracker {
+	t := &PresenceTracker{
+		pubnub:    pn,
+		channels:  append([]string{}, channels...),
+		options:   options,
+		rosters:   make(map[string]map[string]interface{}),
+		occupancy: make(map[string]int),
+		listener:  NewListenerWithOptions(ListenerOptions{BufferSize: presenceTrackerBufferSize}),
+		seeded:    make(chan struct{}),
+		exit:      make(chan struct{}),
+	}
+	for _, channel := range channels {
+		t.rosters[channel] = make(map[string]interface{})
+	}
+	return t
+}
+
+// Start subscribes to the presence events of the channels and seeds the
+// rosters from HereNow. The events received until the seed is applied are
+// held, then applied after it in their order. When the seed fails they are
+// applied to the empty rosters and the error is returned.
+func (t *PresenceTracker) Start() error {
+	t.subscription = newSubscription(t.pubnub, t.channels, []string{}, SubscriptionOptions{ReceivePresenceEvents: true})
+	t.subscription.AddListener(t.listener)
+	go t.run()
+	t.subscription.Subscribe()
+
+	err := t.Sync()
+	t.seedApplied()
+	return err
+}
+
+// seedApplied releases the events held until the seed.
+func (t *PresenceTracker) seedApplied() {
+	t.seededOnce.Do(func() {
+		close(t.seeded)
+	})
+}
+
+// Stop unsubscribes the tracker, the rosters are no longer updated.
+func (t *PresenceTracker) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.exit)
+		if t.subscription != nil {
+			t.subscription.Unsubscribe()
+			t.subscription.RemoveListener(t.listener)
+		}
+	})
+}
+
+// Sync replaces the rosters with the ones of HereNow.
+func (t *PresenceTracker) Sync() error {
+	return t.sync(t.channels)
+}
+
+// Roster returns the occupants of the channel, sorted by UUID.
+func (t *PresenceTracker) Roster(channel string) []PresenceMember {
+	t.RLock()
+	defer t.RUnlock()
+
+	roster := make([]PresenceMember, 0, len(t.rosters[channel]))
+	for uuid, state := range t.rosters[channel] {
+		roster = append(roster, PresenceMember{UUID: uuid, State: state})
+	}
+	sort.Slice(roster, func(i, j int) bool {
+		return roster[i].UUID < roster[j].UUID
+	})
+	return roster
+}
+
+// Occupancy returns the occupancy of the channel, as last reported by the
+// server.
+func (t *PresenceTracker) Occupancy(channel string) int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.occupancy[channel]
+}
+
+func (t *PresenceTracker) run() {
+	seeded := t.seeded
+	held := []*PNPresence{}
+	for {
+		select {
+		case <-t.exit:
+			return
+		case <-seeded:
+			for _, presence := range held {
+				t.apply(presence)
+			}
+			held = nil
+			// a nil channel is never ready
+			seeded = nil
+		case presence := <-t.listener.Presence:
+			if seeded != nil {
+				held = append(held, presence)
+				continue
+			}
+			t.apply(presence)
+		case status := <-t.listener.Status:
+			// before the seed is applied, the seed itself resyncs
+			if status.Category == PNReconnectedCategory && seeded == nil {
+				if err := t.Sync(); err != nil {
+					t.pubnub.Config.Log.Println("presence tracker resync failed:", err)
+				}
+			}
+		// the events of the channels which are not presence events
+		case <-t.listener.Message:
+		case <-t.listener.Signal:
+		case <-t.listener.UUIDEvent:
+		case <-t.listener.ChannelEvent:
+		case <-t.listener.MembershipEvent:
+		case <-t.listener.MessageActionsEvent:
+		case <-t.listener.File:
+		}
+	}
+}
+
+// apply applies a presence event to the roster of its channel.
+func (t *PresenceTracker) apply(presence *PNPresence) {
+	channel := presence.Channel
+	if presence.HereNowRefresh {
+		if err := t.sync([]string{channel}); err != nil {
+			t.pubnub.Config.Log.Println("presence tracker resync failed:", err)
+		}
+		return
+	}
+
+	t.Lock()
+	roster, ok := t.rosters[channel]
+	if !ok {
+		t.Unlock()
+		return
+	}
+	change := PresenceChange{Channel: channel}
+	switch presence.Event {
+	case "join":
+		change.Joined = t.join(roster, presence.UUID, presence.State)
+	case "leave", "timeout":
+		change.Left = t.leave(roster, presence.UUID)
+	case "state-change":
+		if !reflect.DeepEqual(roster[presence.UUID], presence.State) {
+			roster[presence.UUID] = presence.State
+			change.StateChanged = []string{presence.UUID}
+		}
+	case "interval":
+		for _, uuid := range presence.Join {
+			change.Joined = append(change.Joined, t.join(roster, uuid, nil)...)
+		}
+		for _, uuid := range append(presence.Leave, presence.Timeout...) {
+			change.Left = append(change.Left, t.leave(roster, uuid)...)
+		}
+	}
+	occupancyChanged := t.occupancy[channel] != presence.Occupancy
+	t.occupancy[channel] = presence.Occupancy
+	change.Occupancy = presence.Occupancy
+	t.Unlock()
+
+	if occupancyChanged || len(change.Joined) > 0 || len(change.Left) > 0 || len(change.StateChanged) > 0 {
+		t.notify(change)
+	}
+}
+
+func (t *PresenceTracker) join(roster map[string]interface{}, uuid string, state interface{}) []string {
+	_, present := roster[uuid]
+	if !present || state != nil {
+		roster[uuid] = state
+	}
+	if present {
+		return nil
+	}
+	return []string{uuid}
+}
+
+func (t *PresenceTracker) leave(roster map[string]interface{}, uuid string) []string {
+	if _, present := roster[uuid]; !present {
+		return nil
+	}
+	delete(roster, uuid)
+	return []string{uuid}
+}
+
+// sync replaces the rosters of the channels with the ones of HereNow, the
+// channels it doesn't return are empty.
+func (t *PresenceTracker) sync(channels []string) error {
+	resp, _, err := t.pubnub.HereNow().Channels(channels).IncludeUUIDs(true).IncludeState(true).Execute()
+	if err != nil {
+		return err
+	}
+
+	received := make(map[string]HereNowChannelData, len(resp.Channels))
+	for _, data := range resp.Channels {
+		received[data.ChannelName] = data
+	}
+	if !resp.Complete {
+		// the occupants past the first page
+		it := t.pubnub.HereNow().Channels(channels).IncludeUUIDs(true).IncludeState(true).
+			Offset(hereNowMaxLimit).Iterator()
+		for it.Next(nil) {
+			occupant := it.Item()
+			data := received[occupant.Channel]
+			data.Occupants = append(data.Occupants, occupant.HereNowOccupantsData)
+			received[occupant.Channel] = data
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+	}
+
+	changes := []PresenceChange{}
+	t.Lock()
+	for _, channel := range channels {
+		old, ok := t.rosters[channel]
+		if !ok {
+			continue
+		}
+		data := received[channel]
+		roster := make(map[string]interface{}, len(data.Occupants))
+		for _, occupant := range data.Occupants {
+			var state interface{}
+			if len(occupant.State) > 0 {
+				state = occupant.State
+			}
+			roster[occupant.UUID] = state
+		}
+
+		change := PresenceChange{Channel: channel, Occupancy: data.Occupancy, Resync: true}
+		for uuid, state := range roster {
+			oldState, present := old[uuid]
+			if !present {
+				change.Joined = append(change.Joined, uuid)
+			} else if !reflect.DeepEqual(oldState, state) {
+				change.StateChanged = append(change.StateChanged, uuid)
+			}
+		}
+		for uuid := range old {
+			if _, present := roster[uuid]; !present {
+				change.Left = append(change.Left, uuid)
+			}
+		}
+		occupancyChanged := t.occupancy[channel] != data.Occupancy
+		t.rosters[channel] = roster
+		t.occupancy[channel] = data.Occupancy
+		if occupancyChanged || len(change.Joined) > 0 || len(change.Left) > 0 || len(change.StateChanged) > 0 {
+			sort.Strings(change.Joined)
+			sort.Strings(change.Left)
+			sort.Strings(change.StateChanged)
+			changes = append(changes, change)
+		}
+	}
+	t.Unlock()
+
+	for _, change := range changes {
+		t.notify(change)
+	}
+	return nil
+}
+
+func (t *PresenceTracker) notify(change PresenceChange) {
+	if t.options.OnChange != nil {
+		t.options.OnChange(change)
+	}
+}
@@ -0,0 +1,100 @@
+package pubnub
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresenceTrackerSyncAndDeltas(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"payload":{"channels":{"ch1":{"uuids":[{"uuid":"a","state":{"mood":"ok"}},{"uuid":"b"}],"occupancy":2}},"total_channels":1,"total_occupancy":2}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	changes := []PresenceChange{}
+	tracker := pn.PresenceTracker([]string{"ch1", "ch2"}, PresenceTrackerOptions{
+		OnChange: func(change PresenceChange) {
+			changes = append(changes, change)
+		},
+	})
+
+	assert.Nil(tracker.Sync())
+	assert.Equal([]PresenceMember{
+		{UUID: "a", State: map[string]interface{}{"mood": "ok"}},
+		{UUID: "b"},
+	}, tracker.Roster("ch1"))
+	assert.Equal(2, tracker.Occupancy("ch1"))
+	assert.Len(tracker.Roster("ch2"), 0)
+	assert.Equal([]PresenceChange{{Channel: "ch1", Joined: []string{"a", "b"}, Occupancy: 2, Resync: true}}, changes)
+
+	changes = changes[:0]
+	tracker.apply(&PNPresence{Event: "join", Channel: "ch1", UUID: "c", Occupancy: 3})
+	tracker.apply(&PNPresence{Event: "timeout", Channel: "ch1", UUID: "a", Occupancy: 2})
+	tracker.apply(&PNPresence{Event: "state-change", Channel: "ch1", UUID: "b", State: "busy", Occupancy: 2})
+	tracker.apply(&PNPresence{Event: "interval", Channel: "ch1", Join: []string{"d"}, Leave: []string{"c"}, Occupancy: 2})
+	tracker.apply(&PNPresence{Event: "join", Channel: "other", UUID: "x", Occupancy: 1})
+
+	assert.Equal([]PresenceMember{{UUID: "b", State: "busy"}, {UUID: "d"}}, tracker.Roster("ch1"))
+	assert.Equal([]PresenceChange{
+		{Channel: "ch1", Joined: []string{"c"}, Occupancy: 3},
+		{Channel: "ch1", Left: []string{"a"}, Occupancy: 2},
+		{Channel: "ch1", StateChanged: []string{"b"}, Occupancy: 2},
+		{Channel: "ch1", Joined: []string{"d"}, Left: []string{"c"}, Occupancy: 2},
+	}, changes)
+}
+
+func TestPresenceTrackerHereNowRefresh(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"uuids":[{"uuid":"a"},{"uuid":"z"}],"occupancy":2}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	tracker := pn.PresenceTracker([]string{"ch1"}, PresenceTrackerOptions{})
+	tracker.apply(&PNPresence{Event: "join", Channel: "ch1", UUID: "a", Occupancy: 1})
+
+	tracker.apply(&PNPresence{Event: "interval", Channel: "ch1", Occupancy: 2, HereNowRefresh: true})
+
+	assert.Len(transport.requests, 1)
+	assert.Equal([]PresenceMember{{UUID: "a"}, {UUID: "z"}}, tracker.Roster("ch1"))
+	assert.Equal(2, tracker.Occupancy("ch1"))
+}
+
+func TestPresenceTrackerHoldsEventsUntilSeed(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, `{"status":200,"payload":{"channels":{"ch1":{"uuids":[{"uuid":"a"},{"uuid":"b"}],"occupancy":2}},"total_channels":1,"total_occupancy":2}}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	tracker := pn.PresenceTracker([]string{"ch1"}, PresenceTrackerOptions{})
+	defer tracker.Stop()
+	lm := pn.subscriptionManager.listenerManager
+	lm.addListener(tracker.listener)
+	go tracker.run()
+
+	lm.announcePresence(&PNPresence{Event: "leave", Channel: "ch1", UUID: "a", Occupancy: 1})
+	lm.announcePresence(&PNPresence{Event: "join", Channel: "ch1", UUID: "c", Occupancy: 2})
+	assert.Eventually(func() bool { return tracker.listener.Stats().QueueDepth == 0 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(tracker.Roster("ch1"), 0)
+
+	// the events are applied after the seed, the leave isn't undone by it
+	assert.Nil(tracker.Sync())
+	tracker.seedApplied()
+	assert.Eventually(func() bool {
+		roster := tracker.Roster("ch1")
+		return len(roster) == 2 && roster[0].UUID == "b" && roster[1].UUID == "c"
+	}, time.Second, time.Millisecond)
+	assert.Equal(2, tracker.Occupancy("ch1"))
+}
@@ -201,7 +201,7 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 		}
 	}
 
-	if cipherKey := o.pubnub.Config.CipherKey; cipherKey != "" {
+	if module := o.pubnub.Config.cryptoModuleFor(o.Channel); module != nil {
 		var msg string
 		var p *publishBuilder
 		if o.context() != nil {
@@ -211,7 +211,7 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 		}
 		p.opts.Message = o.Message
 
-		msg, errJSONMarshal := p.opts.encryptProcessing(cipherKey)
+		msg, errJSONMarshal := p.opts.encryptProcessing(module)
 		if errJSONMarshal != nil {
 			return "", errJSONMarshal
 		}
@@ -161,6 +161,14 @@ func (b *publishBuilder) Transport(tr http.RoundTripper) *publishBuilder {
 	return b
 }
 
+// DisableRetry when true sends the Publish request only once, even if Config.RetryPolicy
+// would retry it. Use it when a duplicate message is worse than a lost one.
+func (b *publishBuilder) DisableRetry(disable bool) *publishBuilder {
+	b.opts.disableRetry = disable
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *publishBuilder) QueryParam(queryParam map[string]string) *publishBuilder {
 	b.opts.QueryParam = queryParam
@@ -198,13 +206,13 @@ func (o *publishOpts) validate() error {
 	return nil
 }
 
-func (o *publishOpts) encryptProcessing(cipherKey string) (string, error) {
+func (o *publishOpts) encryptProcessing(module CryptoModule) (string, error) {
 	var msg string
 	var errJSONMarshal error
 
 	o.pubnub.Config.Log.Println("EncryptString: encrypting", fmt.Sprintf("%s", o.Message))
 	if o.pubnub.Config.DisablePNOtherProcessing {
-		if msg, errJSONMarshal = utils.SerializeEncryptAndSerialize(o.Message, cipherKey, o.Serialize, o.pubnub.Config.UseRandomInitializationVector); errJSONMarshal != nil {
+		if msg, errJSONMarshal = serializeEncryptAndSerialize(module, o.Message, o.Serialize); errJSONMarshal != nil {
 			o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 			return "", errJSONMarshal
 		}
@@ -218,7 +226,7 @@ func (o *publishOpts) encryptProcessing(cipherKey string) (string, error) {
 
 			if ok {
 				o.pubnub.Config.Log.Println(ok, msgPart)
-				encMsg, errJSONMarshal := utils.SerializeAndEncrypt(msgPart, cipherKey, o.Serialize, o.pubnub.Config.UseRandomInitializationVector)
+				encMsg, errJSONMarshal := serializeAndEncrypt(module, msgPart, o.Serialize)
 				if errJSONMarshal != nil {
 					o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 					return "", errJSONMarshal
@@ -231,14 +239,14 @@ func (o *publishOpts) encryptProcessing(cipherKey string) (string, error) {
 				}
 				msg = string(jsonEncBytes)
 			} else {
-				if msg, errJSONMarshal = utils.SerializeEncryptAndSerialize(o.Message, cipherKey, o.Serialize, o.pubnub.Config.UseRandomInitializationVector); errJSONMarshal != nil {
+				if msg, errJSONMarshal = serializeEncryptAndSerialize(module, o.Message, o.Serialize); errJSONMarshal != nil {
 					o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 					return "", errJSONMarshal
 				}
 			}
 			break
 		default:
-			if msg, errJSONMarshal = utils.SerializeEncryptAndSerialize(o.Message, cipherKey, o.Serialize, o.pubnub.Config.UseRandomInitializationVector); errJSONMarshal != nil {
+			if msg, errJSONMarshal = serializeEncryptAndSerialize(module, o.Message, o.Serialize); errJSONMarshal != nil {
 				o.pubnub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
 				return "", errJSONMarshal
 			}
@@ -261,8 +269,8 @@ func (o *publishOpts) buildPath() (string, error) {
 	var msg string
 	var errJSONMarshal error
 
-	if cipherKey := o.pubnub.Config.CipherKey; cipherKey != "" {
-		if msg, errJSONMarshal = o.encryptProcessing(cipherKey); errJSONMarshal != nil {
+	if module := o.pubnub.Config.cryptoModuleFor(o.Channel); module != nil {
+		if msg, errJSONMarshal = o.encryptProcessing(module); errJSONMarshal != nil {
 			return "", errJSONMarshal
 		}
 
@@ -336,8 +344,8 @@ func (o *publishOpts) buildQuery() (*url.Values, error) {
 
 func (o *publishOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
-		if cipherKey := o.pubnub.Config.CipherKey; cipherKey != "" {
-			msg, errJSONMarshal := o.encryptProcessing(cipherKey)
+		if module := o.pubnub.Config.cryptoModuleFor(o.Channel); module != nil {
+			msg, errJSONMarshal := o.encryptProcessing(module)
 			if errJSONMarshal != nil {
 				return []byte{}, errJSONMarshal
 			}
@@ -52,6 +52,8 @@ const (
 	StrMissingFileID = "Missing File ID"
 	// StrMissingFileName shows `Missing File Name` message
 	StrMissingFileName = "Missing File Name"
+	// StrMissingFile shows `Missing File` message
+	StrMissingFile = "Missing File"
 	// StrMissingToken shows `Missing PAMv3 token` message
 	StrMissingToken = "Missing PAMv3 token"
 )
@@ -493,6 +495,24 @@ func (pn *PubNub) GetSubscribedGroups() []string {
 	return pn.subscriptionManager.getSubscribedGroups()
 }
 
+// GetChannelPresenceState gets the presence state of the UUID on the channel,
+// as set by SetState, Subscribe or Presence, without a GetState request. It is
+// nil when no state is set.
+func (pn *PubNub) GetChannelPresenceState(channel string) map[string]interface{} {
+	return pn.subscriptionManager.stateManager.channelState(channel)
+}
+
+// GetChannelGroupPresenceState gets the presence state of the UUID on the
+// channel group, nil when no state is set.
+func (pn *PubNub) GetChannelGroupPresenceState(group string) map[string]interface{} {
+	return pn.subscriptionManager.stateManager.groupState(group)
+}
+
+// GetSubscribeState gets the current state of the subscribe loop.
+func (pn *PubNub) GetSubscribeState() SubscribeState {
+	return pn.subscriptionManager.GetState()
+}
+
 // UnsubscribeAll Unsubscribe from all channels and all channel groups.
 func (pn *PubNub) UnsubscribeAll() {
 	pn.subscriptionManager.unsubscribeAll()
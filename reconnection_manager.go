@@ -28,6 +28,8 @@ type ReconnectionManager struct {
 	hbRunning                   bool
 	pubnub                      *PubNub
 	exitReconnectionManager     chan bool
+	// stopPolling is closed by stopHeartbeatTimer to end the running poll.
+	stopPolling chan struct{}
 }
 
 func newReconnectionManager(pubnub *PubNub) *ReconnectionManager {
@@ -66,9 +68,11 @@ func (m *ReconnectionManager) startPolling() {
 	}
 
 	m.Lock()
-	m.ExponentialMultiplier = 1
-	m.FailedCalls = 0
 	hbRunning := m.hbRunning
+	if !hbRunning {
+		m.ExponentialMultiplier = 1
+		m.FailedCalls = 0
+	}
 	m.Unlock()
 
 	if !hbRunning {
@@ -85,14 +89,32 @@ func (m *ReconnectionManager) startHeartbeatTimer() {
 
 	timerInterval := reconnectionInterval
 
+	m.Lock()
+	if m.hbRunning {
+		m.Unlock()
+		m.pubnub.Config.Log.Println("hb already running")
+		return
+	}
+	m.hbRunning = true
+	stopPolling := make(chan struct{})
+	m.stopPolling = stopPolling
+	m.Unlock()
+
 	for {
 
-		m.Lock()
-		m.hbRunning = true
+		m.RLock()
 		failedCalls := m.FailedCalls
-		m.Unlock()
-		_, status, err := m.pubnub.Time().Execute()
-		if status.Error == nil {
+		m.RUnlock()
+		// The probe runs on its own schedule, Config.RetryPolicy would only delay it.
+		probe := m.pubnub.Time()
+		probe.opts.disableRetry = true
+		_, status, err := probe.Execute()
+		category := categorizeError(err)
+		// A cancelled probe says nothing about the network, so it neither
+		// resets nor counts towards the retry limit.
+		if status.Error != nil && category == PNCancelledCategory {
+			m.pubnub.Config.Log.Println("Reconnection probe cancelled")
+		} else if status.Error == nil {
 			if failedCalls > 0 {
 				timerInterval = reconnectionInterval
 				m.Lock()
@@ -107,7 +129,7 @@ func (m *ReconnectionManager) startHeartbeatTimer() {
 			}
 			m.Lock()
 			m.FailedCalls++
-			m.pubnub.Config.Log.Println(fmt.Sprintf("Network disconnected, reconnection try %d of %d\n %v %v", m.FailedCalls, m.pubnub.Config.MaximumReconnectionRetries, status, err))
+			m.pubnub.Config.Log.Println(fmt.Sprintf("Network disconnected, reconnection try %d of %d\n %s %v %v", m.FailedCalls, m.pubnub.Config.MaximumReconnectionRetries, category, status, err))
 			m.ExponentialMultiplier++
 
 			failedCalls := m.FailedCalls
@@ -115,9 +137,7 @@ func (m *ReconnectionManager) startHeartbeatTimer() {
 			m.Unlock()
 			if retries != -1 && failedCalls >= retries {
 				m.pubnub.Config.Log.Printf(fmt.Sprintf("Network connection retry limit (%d) exceeded", retries))
-				m.Lock()
-				m.hbRunning = false
-				m.Unlock()
+				m.stopHeartbeatTimer()
 				m.OnMaxReconnectionExhaustion()
 				return
 			}
@@ -127,12 +147,14 @@ func (m *ReconnectionManager) startHeartbeatTimer() {
 		case <-time.After(time.Duration(timerInterval) * time.Second):
 		case <-m.pubnub.ctx.Done():
 			m.pubnub.Config.Log.Printf(fmt.Sprintf("pubnub.ctx.Done\n"))
-			m.Lock()
-			m.hbRunning = false
-			m.Unlock()
+			m.stopHeartbeatTimer()
+			return
+		case <-stopPolling:
+			m.pubnub.Config.Log.Printf(fmt.Sprintf("stopPolling\n"))
 			return
 		case <-m.exitReconnectionManager:
 			m.pubnub.Config.Log.Printf(fmt.Sprintf("exitReconnectionManager\n"))
+			m.stopHeartbeatTimer()
 			return
 		}
 	}
@@ -158,12 +180,15 @@ func (m *ReconnectionManager) getExponentialInterval() int {
 	return timerInterval
 }
 
+// stopHeartbeatTimer ends the running poll. It never blocks, so it is safe to
+// call from the OnReconnection and OnMaxReconnectionExhaustion handlers.
 func (m *ReconnectionManager) stopHeartbeatTimer() {
 	m.pubnub.Config.Log.Printf("stopHeartbeatTimer")
 	m.Lock()
 	if m.hbRunning {
 		m.hbRunning = false
-		m.exitReconnectionManager <- true
+		close(m.stopPolling)
+		m.stopPolling = nil
 	}
 	m.Unlock()
 	m.pubnub.Config.Log.Printf("stopHeartbeatTimer true")
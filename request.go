@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"time"
@@ -28,6 +27,9 @@ type StatusResponse struct {
 	AffectedChannels      []string
 	AffectedChannelGroups []string
 	AdditionalData        interface{}
+	// Attempts is the number of times the request was sent, including retries
+	// made by Config.RetryPolicy.
+	Attempts int
 }
 
 // ResponseInfo is used to store the properties in the response of an request.
@@ -96,7 +98,63 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 
 	opts.config().Log.Println(fmt.Sprintf("url:%s\nmethod:%s", url, opts.httpMethod()))
 
+	// The url is built once and reused by all attempts, so a retried publish
+	// keeps its seqn and the server can tell the attempts apart from new messages.
+	policy := opts.config().RetryPolicy
+	attempt := 1
+	for {
+		val, status, err := executeRequestAttempt(opts, url)
+		status.Attempts = attempt
+		if err == nil || policy == nil || !isRetryAllowed(opts) {
+			return val, status, err
+		}
+
+		delay, retry := policy.NextRetryDelay(opts.operationType(), attempt, err)
+		if !retry {
+			return val, status, err
+		}
+		if d := retryAfter(err); d > delay {
+			delay = d
+		}
+		opts.config().Log.Println(fmt.Sprintf("retrying %s after %s, attempt %d: %s", opts.operationType(), delay, attempt, err.Error()))
+
+		if !waitForRetry(opts.context(), delay) {
+			return val, status, err
+		}
+		attempt++
+	}
+}
+
+// isRetryAllowed reports whether a failed request of the endpoint may be sent
+// again. Subscribe has its own recovery in the SubscriptionManager, multipart
+// uploads can't replay their body and callers can opt out per request.
+func isRetryAllowed(opts endpoint) bool {
+	if opts.operationType() == PNSubscribeOperation || opts.httpMethod() == "POSTFORM" {
+		return false
+	}
+	return !opts.retryDisabled()
+}
+
+func waitForRetry(ctx Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	if ctx == nil {
+		<-timer.C
+		return true
+	}
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func executeRequestAttempt(opts endpoint, url *url.URL) ([]byte, StatusResponse, error) {
 	var req *http.Request
+	var err error
 
 	if opts.httpMethod() == "POST" {
 		body, err := buildBody(opts, url)
@@ -107,18 +165,19 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 		req.Header.Set("Content-Type", "application/json")
 	} else if opts.httpMethod() == "POSTFORM" {
 
-		body, w, _, err := opts.buildBodyMultipartFileUpload()
+		body, contentType, contentLength, err := opts.buildBodyMultipartFileUpload()
 		if err != nil {
 			return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
 		}
 
-		req, err = newRequestForMultipartWriter("POST", url.RequestURI(), &body, w, opts.config().UseHTTP2)
+		req, err = newRequestForMultipartWriter("POST", url.RequestURI(), body, opts.config().UseHTTP2)
 		if err != nil {
 			opts.config().Log.Println("POST ERROR : ", err)
 			return nil, createStatus(PNUnknownCategory, "", ResponseInfo{}, err), err
 		}
 
-		req.Header.Set("Content-Type", w.FormDataContentType())
+		req.ContentLength = contentLength
+		req.Header.Set("Content-Type", contentType)
 	} else if opts.httpMethod() == "DELETE" {
 		req, err = newRequest("DELETE", url, nil, opts.config().UseHTTP2)
 	} else if opts.httpMethod() == "PATCH" {
@@ -170,14 +229,19 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 		res, err = client.Do(req)
 	}
 
-	// Host lookup failed
+	// Host lookup failed, connection dropped or the request was cancelled
 	if err != nil {
 		opts.config().Log.Println("err.Error()", err.Error())
-		e := pnerr.NewConnectionError("Failed to execute request", err)
+		var ctxErr error
+		if ctx != nil {
+			ctxErr = ctx.Err()
+		}
+		e := pnerr.NewConnectionErrorWithContext("Failed to execute request", err, ctxErr)
+		category := categorizeError(e)
 
-		opts.config().Log.Println("PNUnknownCategory", e.Error(), url)
+		opts.config().Log.Println(category, e.Error(), url)
 		return nil,
-			createStatus(PNUnknownCategory, "", ResponseInfo{}, e),
+			createStatus(category, "", ResponseInfo{Operation: opts.operationType()}, e),
 			e
 	}
 
@@ -220,7 +284,7 @@ func executeRequest(opts endpoint) ([]byte, StatusResponse, error) {
 	return val, status, nil
 }
 
-func newRequestForMultipartWriter(method string, URL string, body io.Reader, writer *multipart.Writer, useHTTP2 bool) (*http.Request, error) {
+func newRequestForMultipartWriter(method string, URL string, body io.Reader, useHTTP2 bool) (*http.Request, error) {
 	req, err := http.NewRequest(method, URL, body)
 	if useHTTP2 {
 		req.Proto = "HTTP/2.0"
@@ -280,24 +344,13 @@ func parseResponse(resp *http.Response, opts endpoint) ([]byte, StatusResponse,
 	if (resp.StatusCode != 200) && (resp.StatusCode != 204) {
 		// Errors like 400, 403, 500
 		e := pnerr.NewServerError(resp.StatusCode, resp.Body)
+		e.Header = resp.Header
 
 		opts.config().Log.Println(e.Error())
 
-		if resp.StatusCode == 408 {
-			opts.config().Log.Println("PNTimeoutCategory: resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
-			status = createStatus(PNTimeoutCategory, "", ResponseInfo{StatusCode: resp.StatusCode}, e)
-
-			return nil, status, e
-		}
-
-		if resp.StatusCode == 400 {
-			opts.config().Log.Println("PNBadRequestCategory: resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
-			status = createStatus(PNBadRequestCategory, "", ResponseInfo{StatusCode: resp.StatusCode}, e)
-
-			return nil, status, e
-		}
-		opts.config().Log.Println("PNUnknownCategory: resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
-		status = createStatus(PNUnknownCategory, "", ResponseInfo{StatusCode: resp.StatusCode, Operation: opts.operationType()}, e)
+		category := categorizeError(e)
+		opts.config().Log.Println(category, ": resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
+		status = createStatus(category, "", ResponseInfo{StatusCode: resp.StatusCode, Operation: opts.operationType()}, e)
 
 		return nil, status, e
 	}
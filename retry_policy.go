@@ -0,0 +1,179 @@
+package pubnub
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+)
+
+// RetryPolicy decides whether a failed non-subscribe request is retried and
+// how long to wait before the next attempt. It is set in Config.RetryPolicy,
+// no retries are made when it is nil.
+//
+// NextRetryDelay is called after every failed attempt with the operation, the
+// number of attempts made so far (starting at 1) and the error returned by the
+// attempt. It returns the delay before the next attempt and false when the
+// request must not be retried anymore. When the server sends a Retry-After
+// header the SDK waits for the longer of the two delays.
+type RetryPolicy interface {
+	NextRetryDelay(operation OperationType, attempt int, err error) (time.Duration, bool)
+}
+
+// LinearRetryPolicy retries failed requests after a constant Delay, at most
+// MaxRetries times. Operations listed in ExcludedOperations are never retried.
+type LinearRetryPolicy struct {
+	Delay              time.Duration
+	MaxRetries         int
+	ExcludedOperations []OperationType
+}
+
+// NewLinearRetryPolicy creates a LinearRetryPolicy.
+func NewLinearRetryPolicy(delay time.Duration, maxRetries int) *LinearRetryPolicy {
+	return &LinearRetryPolicy{
+		Delay:      delay,
+		MaxRetries: maxRetries,
+	}
+}
+
+// NextRetryDelay implements RetryPolicy.
+func (p *LinearRetryPolicy) NextRetryDelay(operation OperationType, attempt int, err error) (time.Duration, bool) {
+	if !shouldRetry(operation, attempt, err, p.MaxRetries, p.ExcludedOperations) {
+		return 0, false
+	}
+	return p.Delay, true
+}
+
+// ExponentialRetryPolicy retries failed requests after a delay which doubles
+// with each attempt, starting at MinDelay and capped at MaxDelay, at most
+// MaxRetries times. Operations listed in ExcludedOperations are never retried.
+type ExponentialRetryPolicy struct {
+	MinDelay           time.Duration
+	MaxDelay           time.Duration
+	MaxRetries         int
+	ExcludedOperations []OperationType
+}
+
+// NewExponentialRetryPolicy creates an ExponentialRetryPolicy.
+func NewExponentialRetryPolicy(minDelay, maxDelay time.Duration, maxRetries int) *ExponentialRetryPolicy {
+	return &ExponentialRetryPolicy{
+		MinDelay:   minDelay,
+		MaxDelay:   maxDelay,
+		MaxRetries: maxRetries,
+	}
+}
+
+// NextRetryDelay implements RetryPolicy.
+func (p *ExponentialRetryPolicy) NextRetryDelay(operation OperationType, attempt int, err error) (time.Duration, bool) {
+	if !shouldRetry(operation, attempt, err, p.MaxRetries, p.ExcludedOperations) {
+		return 0, false
+	}
+	return exponentialDelay(p.MinDelay, p.MaxDelay, attempt), true
+}
+
+// JitteredRetryPolicy behaves like ExponentialRetryPolicy but waits a random
+// delay between MinDelay and the exponential delay of the attempt, so that
+// many clients failing at once do not retry in lockstep.
+type JitteredRetryPolicy struct {
+	MinDelay           time.Duration
+	MaxDelay           time.Duration
+	MaxRetries         int
+	ExcludedOperations []OperationType
+
+	randMutex sync.Mutex
+	rand      *rand.Rand
+}
+
+// NewJitteredRetryPolicy creates a JitteredRetryPolicy.
+func NewJitteredRetryPolicy(minDelay, maxDelay time.Duration, maxRetries int) *JitteredRetryPolicy {
+	return &JitteredRetryPolicy{
+		MinDelay:   minDelay,
+		MaxDelay:   maxDelay,
+		MaxRetries: maxRetries,
+	}
+}
+
+// NextRetryDelay implements RetryPolicy.
+func (p *JitteredRetryPolicy) NextRetryDelay(operation OperationType, attempt int, err error) (time.Duration, bool) {
+	if !shouldRetry(operation, attempt, err, p.MaxRetries, p.ExcludedOperations) {
+		return 0, false
+	}
+	ceiling := exponentialDelay(p.MinDelay, p.MaxDelay, attempt)
+	spread := int64(ceiling - p.MinDelay)
+	if spread <= 0 {
+		return ceiling, true
+	}
+
+	p.randMutex.Lock()
+	if p.rand == nil {
+		p.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	jitter := p.rand.Int63n(spread + 1)
+	p.randMutex.Unlock()
+
+	return p.MinDelay + time.Duration(jitter), true
+}
+
+func shouldRetry(operation OperationType, attempt int, err error, maxRetries int, excluded []OperationType) bool {
+	if attempt > maxRetries {
+		return false
+	}
+	for _, op := range excluded {
+		if op == operation {
+			return false
+		}
+	}
+	return isRetryableError(err)
+}
+
+func exponentialDelay(minDelay, maxDelay time.Duration, attempt int) time.Duration {
+	delay := float64(minDelay) * math.Pow(2, float64(attempt-1))
+	if maxDelay > 0 && delay > float64(maxDelay) {
+		return maxDelay
+	}
+	return time.Duration(delay)
+}
+
+// isRetryableError reports whether err is a transient failure: a timeout,
+// a 429, a 5xx or a dropped connection. Validation errors, 4xx responses,
+// DNS and TLS failures and cancelled requests are not retried.
+func isRetryableError(err error) bool {
+	switch categorizeError(err) {
+	case PNTimeoutCategory, PNTooManyRequestsCategory, PNServerErrorCategory,
+		PNNetworkIssuesCategory:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay requested by the server in the Retry-After
+// header of a failed response, or 0 if there is none.
+func retryAfter(err error) time.Duration {
+	var serverErr *pnerr.ServerError
+	if !errors.As(err, &serverErr) || serverErr.Header == nil {
+		return 0
+	}
+
+	value := serverErr.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, e := strconv.Atoi(value); e == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, e := http.ParseTime(value); e == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
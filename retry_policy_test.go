@@ -0,0 +1,174 @@
+package pubnub
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/stretchr/testify/assert"
+)
+
+type sequenceTransport struct {
+	responses []*http.Response
+	requests  []*http.Request
+}
+
+func (t *sequenceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	resp := t.responses[0]
+	if len(t.responses) > 1 {
+		t.responses = t.responses[1:]
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+func newSequenceResponse(statusCode int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestLinearRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+	p := NewLinearRetryPolicy(2*time.Second, 2)
+	serverErr := &pnerr.ServerError{StatusCode: 503}
+
+	d, ok := p.NextRetryDelay(PNPublishOperation, 1, serverErr)
+	assert.True(ok)
+	assert.Equal(2*time.Second, d)
+	_, ok = p.NextRetryDelay(PNPublishOperation, 3, serverErr)
+	assert.False(ok)
+	_, ok = p.NextRetryDelay(PNPublishOperation, 1, &pnerr.ServerError{StatusCode: 403})
+	assert.False(ok)
+
+	p.ExcludedOperations = []OperationType{PNPublishOperation}
+	_, ok = p.NextRetryDelay(PNPublishOperation, 1, serverErr)
+	assert.False(ok)
+}
+
+func TestExponentialRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+	p := NewExponentialRetryPolicy(time.Second, 5*time.Second, 10)
+	serverErr := &pnerr.ServerError{StatusCode: 429}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, e := range expected {
+		d, ok := p.NextRetryDelay(PNSetUUIDMetadataOperation, i+1, serverErr)
+		assert.True(ok)
+		assert.Equal(e, d)
+	}
+}
+
+func TestJitteredRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+	p := NewJitteredRetryPolicy(time.Second, 8*time.Second, 10)
+	connErr := pnerr.NewConnectionError("Failed to execute request", errors.New("connection reset by peer"))
+
+	for attempt := 1; attempt <= 6; attempt++ {
+		d, ok := p.NextRetryDelay(PNAccessManagerGrantToken, attempt, connErr)
+		assert.True(ok)
+		assert.True(d >= time.Second)
+		assert.True(d <= exponentialDelay(time.Second, 8*time.Second, attempt))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Duration(0), retryAfter(errors.New("x")))
+	assert.Equal(time.Duration(0), retryAfter(&pnerr.ServerError{StatusCode: 429}))
+	assert.Equal(3*time.Second, retryAfter(&pnerr.ServerError{
+		StatusCode: 429,
+		Header:     http.Header{"Retry-After": []string{"3"}},
+	}))
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	d := retryAfter(&pnerr.ServerError{
+		StatusCode: 503,
+		Header:     http.Header{"Retry-After": []string{date}},
+	})
+	assert.True(d > 59*time.Minute)
+}
+
+func TestExecuteRequestRetries(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(503, "", nil),
+			newSequenceResponse(429, "", http.Header{"Retry-After": []string{"0"}}),
+			newSequenceResponse(200, "[15078947309567840]", nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.RetryPolicy = NewLinearRetryPolicy(time.Millisecond, 3)
+	pn.SetClient(&http.Client{Transport: transport})
+
+	res, status, err := pn.Time().Execute()
+	assert.Nil(err)
+	assert.Equal(int64(15078947309567840), res.Timetoken)
+	assert.Equal(3, status.Attempts)
+	assert.Equal(3, len(transport.requests))
+}
+
+func TestExecuteRequestRetriesExhausted(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(500, "", nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.RetryPolicy = NewLinearRetryPolicy(time.Millisecond, 2)
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, status, err := pn.Time().Execute()
+	assert.NotNil(err)
+	assert.Equal(PNServerErrorCategory, status.Category)
+	assert.Equal(3, status.Attempts)
+}
+
+func TestExecuteRequestRetryDisabled(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(503, "", nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.RetryPolicy = NewLinearRetryPolicy(time.Millisecond, 2)
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, status, err := pn.Publish().Channel("ch").Message("hey").DisableRetry(true).Execute()
+	assert.NotNil(err)
+	assert.Equal(1, status.Attempts)
+	assert.Equal(1, len(transport.requests))
+}
+
+func TestExecuteRequestRetryKeepsSequence(t *testing.T) {
+	assert := assert.New(t)
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(503, "", nil),
+			newSequenceResponse(200, `[1,"Sent","15078947309567840"]`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.Config.RetryPolicy = NewLinearRetryPolicy(time.Millisecond, 2)
+	pn.Config.MaxWorkers = 0
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, status, err := pn.Publish().Channel("ch").Message("hey").Execute()
+	assert.Nil(err)
+	assert.Equal(2, status.Attempts)
+	assert.Equal(transport.requests[0].URL.Query().Get("seqn"), transport.requests[1].URL.Query().Get("seqn"))
+}
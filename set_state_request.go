@@ -67,19 +67,27 @@ func (b *setStateBuilder) UUID(uuid string) *setStateBuilder {
 
 // Execute runs the the Set State request and returns the SetStateResponse
 func (b *setStateBuilder) Execute() (*SetStateResponse, StatusResponse, error) {
-	stateOperation := StateOperation{}
-	stateOperation.channels = b.opts.Channels
-	stateOperation.channelGroups = b.opts.ChannelGroups
-	stateOperation.state = b.opts.State
-
-	b.opts.pubnub.subscriptionManager.adaptState(stateOperation)
-
 	rawJSON, status, err := executeRequest(b.opts)
 	if err != nil {
 		return emptySetStateResponse, status, err
 	}
 
-	return newSetStateResponse(rawJSON, status)
+	resp, status, err := newSetStateResponse(rawJSON, status)
+	if err != nil {
+		return resp, status, err
+	}
+
+	// the state of other UUIDs is not sent by this client
+	if b.opts.UUID == "" || b.opts.UUID == b.opts.pubnub.Config.UUID {
+		stateOperation := StateOperation{}
+		stateOperation.channels = b.opts.Channels
+		stateOperation.channelGroups = b.opts.ChannelGroups
+		stateOperation.state = b.opts.State
+
+		b.opts.pubnub.subscriptionManager.adaptState(stateOperation)
+	}
+
+	return resp, status, nil
 }
 
 type setStateOpts struct {
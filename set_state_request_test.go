@@ -3,6 +3,7 @@ package pubnub
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"testing"
 
@@ -230,3 +231,34 @@ func TestNewSetStateResponseValueError(t *testing.T) {
 	_, _, err := newSetStateResponse([]byte(b), StatusResponse{})
 	assert.Equal("", err.Error())
 }
+
+func TestSetStateStoresState(t *testing.T) {
+	assert := assert.New(t)
+	ok := `{"status": 200, "message": "OK", "payload": {"k": "v"}, "service": "Presence"}`
+	transport := &sequenceTransport{
+		responses: []*http.Response{
+			newSequenceResponse(200, ok, nil),
+			newSequenceResponse(200, ok, nil),
+			newSequenceResponse(403, `{"status": 403, "message": "Forbidden", "error": true, "service": "Access Manager"}`, nil),
+		},
+	}
+	pn := NewPubNub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+
+	_, _, err := pn.SetState().Channels([]string{"ch1"}).ChannelGroups([]string{"cg"}).
+		State(map[string]interface{}{"k": "v"}).Execute()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"k": "v"}, pn.GetChannelPresenceState("ch1"))
+	assert.Equal(map[string]interface{}{"k": "v"}, pn.GetChannelGroupPresenceState("cg"))
+
+	// the state of another UUID
+	_, _, err = pn.SetState().Channels([]string{"ch2"}).UUID("other").
+		State(map[string]interface{}{"k": "v"}).Execute()
+	assert.Nil(err)
+	assert.Nil(pn.GetChannelPresenceState("ch2"))
+
+	_, _, err = pn.SetState().Channels([]string{"ch1"}).
+		State(map[string]interface{}{"k": "denied"}).Execute()
+	assert.NotNil(err)
+	assert.Equal(map[string]interface{}{"k": "v"}, pn.GetChannelPresenceState("ch1"))
+}
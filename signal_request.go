@@ -62,6 +62,14 @@ func (b *signalBuilder) Transport(tr http.RoundTripper) *signalBuilder {
 	return b
 }
 
+// DisableRetry when true sends the Signal request only once, even if Config.RetryPolicy
+// would retry it. Use it when a duplicate message is worse than a lost one.
+func (b *signalBuilder) DisableRetry(disable bool) *signalBuilder {
+	b.opts.disableRetry = disable
+
+	return b
+}
+
 // QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
 func (b *signalBuilder) QueryParam(queryParam map[string]string) *signalBuilder {
 	b.opts.QueryParam = queryParam
@@ -109,13 +117,12 @@ func (o *signalOpts) buildPath() (string, error) {
 			"0"), nil
 	}
 
-	var msg string
-	jsonEncBytes, errEnc := json.Marshal(o.Message)
+	jsonEncBytes, errEnc := o.serializeMessage()
 	if errEnc != nil {
 		o.pubnub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
 		return "", errEnc
 	}
-	msg = string(jsonEncBytes)
+	msg := string(jsonEncBytes)
 	return fmt.Sprintf(signalGetPath,
 		o.pubnub.Config.PublishKey,
 		o.pubnub.Config.SubscribeKey,
@@ -125,6 +132,19 @@ func (o *signalOpts) buildPath() (string, error) {
 	), nil
 }
 
+// serializeMessage serializes the message to JSON, encrypted when
+// Config.CipherKeyResolver returns a CryptoModule for the channel.
+func (o *signalOpts) serializeMessage() ([]byte, error) {
+	if module := o.pubnub.Config.signalCryptoModule(o.Channel); module != nil {
+		msg, err := serializeEncryptAndSerialize(module, o.Message, true)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(msg), nil
+	}
+	return json.Marshal(o.Message)
+}
+
 func (o *signalOpts) buildQuery() (*url.Values, error) {
 	q := defaultQuery(o.pubnub.Config.UUID, o.pubnub.telemetryManager)
 
@@ -135,7 +155,7 @@ func (o *signalOpts) buildQuery() (*url.Values, error) {
 
 func (o *signalOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
-		jsonEncBytes, errEnc := json.Marshal(o.Message)
+		jsonEncBytes, errEnc := o.serializeMessage()
 		if errEnc != nil {
 			o.pubnub.Config.Log.Printf("ERROR: Signal error: %s\n", errEnc.Error())
 			return []byte{}, errEnc
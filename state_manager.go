@@ -14,12 +14,26 @@ type StateManager struct {
 	groups           map[string]*SubscriptionItem
 	presenceChannels map[string]*SubscriptionItem
 	presenceGroups   map[string]*SubscriptionItem
+
+	// Number of Subscription objects holding each channel and channel group,
+	// presence ones are counted under their -pnpres name.
+	channelRefs map[string]int
+	groupRefs   map[string]int
+	// Names subscribed with pn.Subscribe(), they hold one reference until
+	// pn.Unsubscribe() or pn.UnsubscribeAll().
+	legacyChannels map[string]bool
+	legacyGroups   map[string]bool
+
+	// Presence state of the UUID per channel and channel group, written by
+	// SetState, the subscribe State option and Presence, and sent with the
+	// subscribe and heartbeat requests of the subscribed ones.
+	channelStates map[string]map[string]interface{}
+	groupStates   map[string]map[string]interface{}
 }
 
 // SubscriptionItem is used to store the subscription item's properties.
 type SubscriptionItem struct {
-	name  string
-	state map[string]interface{}
+	name string
 }
 
 func newStateManager() *StateManager {
@@ -28,20 +42,18 @@ func newStateManager() *StateManager {
 		presenceChannels: make(map[string]*SubscriptionItem),
 		groups:           make(map[string]*SubscriptionItem),
 		presenceGroups:   make(map[string]*SubscriptionItem),
+		channelRefs:      make(map[string]int),
+		groupRefs:        make(map[string]int),
+		legacyChannels:   make(map[string]bool),
+		legacyGroups:     make(map[string]bool),
+		channelStates:    make(map[string]map[string]interface{}),
+		groupStates:      make(map[string]map[string]interface{}),
 	}
 }
 
 func newSubscriptionItem(name string) *SubscriptionItem {
 	return &SubscriptionItem{
-		name:  name,
-		state: make(map[string]interface{}),
-	}
-}
-
-func newSubscriptionItemWithState(name string, state map[string]interface{}) *SubscriptionItem {
-	return &SubscriptionItem{
-		name:  name,
-		state: state,
+		name: name,
 	}
 }
 
@@ -69,80 +81,112 @@ func (m *StateManager) adaptSubscribeOperation(
 
 	for _, ch := range subscribeOperation.Channels {
 		if strings.Contains(ch, "-pnpres") {
-			if len(subscribeOperation.State) > 0 {
-				m.presenceChannels[ch] = newSubscriptionItemWithState(ch, subscribeOperation.State)
-			} else {
-				m.presenceChannels[ch] = newSubscriptionItem(ch)
-			}
+			// keyed by the plain name, like the WithPresence entries, so
+			// that unsubscribing from the -pnpres name removes it
+			key := strings.Replace(ch, "-pnpres", "", -1)
+			m.presenceChannels[key] = newSubscriptionItem(ch)
 		} else {
-			if len(subscribeOperation.State) > 0 {
-				m.channels[ch] = newSubscriptionItemWithState(ch, subscribeOperation.State)
-			} else {
-				m.channels[ch] = newSubscriptionItem(ch)
-			}
+			m.channels[ch] = newSubscriptionItem(ch)
 
 			if subscribeOperation.PresenceEnabled {
 				m.presenceChannels[ch] = newSubscriptionItem(ch)
 			}
 		}
-
 	}
 
 	for _, cg := range subscribeOperation.ChannelGroups {
 		if strings.Contains(cg, "-pnpres") {
-			if len(subscribeOperation.State) > 0 {
-				m.presenceGroups[cg] = newSubscriptionItemWithState(cg, subscribeOperation.State)
-			} else {
-				m.presenceGroups[cg] = newSubscriptionItem(cg)
-			}
+			key := strings.Replace(cg, "-pnpres", "", -1)
+			m.presenceGroups[key] = newSubscriptionItem(cg)
 		} else {
-			if len(subscribeOperation.State) > 0 {
-				m.groups[cg] = newSubscriptionItemWithState(cg, subscribeOperation.State)
-			} else {
-				m.groups[cg] = newSubscriptionItem(cg)
-			}
+			m.groups[cg] = newSubscriptionItem(cg)
 
 			if subscribeOperation.PresenceEnabled {
 				m.presenceGroups[cg] = newSubscriptionItem(cg)
 			}
 		}
 	}
+
+	// subscribing without State keeps the stored one
+	if len(subscribeOperation.State) > 0 {
+		m.setState(subscribeOperation.Channels, subscribeOperation.ChannelGroups, subscribeOperation.State)
+	}
 	m.Unlock()
 }
 
 func (m *StateManager) adaptStateOperation(stateOperation StateOperation) {
 	m.Lock()
+	m.setState(stateOperation.channels, stateOperation.channelGroups, stateOperation.state)
+	m.Unlock()
+}
 
-	for _, ch := range stateOperation.channels {
-		if _, ok := m.channels[ch]; ok {
-			subscribedChannel := m.channels[ch]
+// setState stores the state of the channels and groups, an empty state
+// removes it. The -pnpres names store the state of the plain ones.
+func (m *StateManager) setState(channels, groups []string, state map[string]interface{}) {
+	for _, ch := range channels {
+		storeState(m.channelStates, strings.Replace(ch, "-pnpres", "", -1), state)
+	}
+	for _, cg := range groups {
+		storeState(m.groupStates, strings.Replace(cg, "-pnpres", "", -1), state)
+	}
+}
 
-			if subscribedChannel.name != "" {
-				subscribedChannel.state = stateOperation.state
-			}
-		}
+func storeState(states map[string]map[string]interface{}, name string, state map[string]interface{}) {
+	if len(state) == 0 {
+		delete(states, name)
+		return
 	}
+	states[name] = copyState(state)
+}
 
-	for _, cg := range stateOperation.channelGroups {
-		if _, ok := m.groups[cg]; ok {
-			subscribedChannelGroup := m.groups[cg]
+// dropUnsubscribedState removes the state of the channels and groups which
+// are not subscribed, the subscribed ones keep sending theirs.
+func (m *StateManager) dropUnsubscribedState(channels, groups []string) {
+	m.Lock()
+	defer m.Unlock()
 
-			if subscribedChannelGroup.name != "" {
-				subscribedChannelGroup.state = stateOperation.state
-			}
+	for _, ch := range channels {
+		ch = strings.Replace(ch, "-pnpres", "", -1)
+		if _, ok := m.channels[ch]; !ok {
+			delete(m.channelStates, ch)
+		}
+	}
+	for _, cg := range groups {
+		cg = strings.Replace(cg, "-pnpres", "", -1)
+		if _, ok := m.groups[cg]; !ok {
+			delete(m.groupStates, cg)
 		}
 	}
-	m.Unlock()
+}
+
+// channelState returns a copy of the stored state of the channel, nil if
+// there is none.
+func (m *StateManager) channelState(channel string) map[string]interface{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	return copyState(m.channelStates[channel])
+}
+
+// groupState returns a copy of the stored state of the channel group, nil
+// if there is none.
+func (m *StateManager) groupState(group string) map[string]interface{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	return copyState(m.groupStates[group])
 }
 
 func (m *StateManager) adaptUnsubscribeOperation(unsubscribeOperation *UnsubscribeOperation) {
 	m.Lock()
 
+	// the server drops the state of the channels left
 	for _, ch := range unsubscribeOperation.Channels {
 		if strings.Contains(ch, "-pnpres") {
 			delete(m.presenceChannels, strings.Replace(ch, "-pnpres", "", -1))
 		} else {
 			delete(m.channels, ch)
+			delete(m.channelStates, ch)
 		}
 	}
 
@@ -151,6 +195,7 @@ func (m *StateManager) adaptUnsubscribeOperation(unsubscribeOperation *Unsubscri
 			delete(m.presenceGroups, strings.Replace(cg, "-pnpres", "", -1))
 		} else {
 			delete(m.groups, cg)
+			delete(m.groupStates, cg)
 		}
 
 	}
@@ -164,14 +209,37 @@ func (m *StateManager) createStatePayload() map[string]interface{} {
 	stateResponse := make(map[string]interface{})
 
 	for _, ch := range m.channels {
-		if len(ch.state) != 0 {
-			stateResponse[ch.name] = ch.state
+		if state, ok := m.channelStates[ch.name]; ok {
+			stateResponse[ch.name] = state
 		}
 	}
 
 	for _, gr := range m.groups {
-		if len(gr.state) != 0 {
-			stateResponse[gr.name] = gr.state
+		if state, ok := m.groupStates[gr.name]; ok {
+			stateResponse[gr.name] = state
+		}
+	}
+
+	return stateResponse
+}
+
+// statePayload is the state of the channels and groups, keyed by name, as
+// sent by the heartbeats.
+func (m *StateManager) statePayload(channels, groups []string) map[string]interface{} {
+	m.RLock()
+	defer m.RUnlock()
+
+	stateResponse := make(map[string]interface{})
+
+	for _, ch := range channels {
+		if state, ok := m.channelStates[ch]; ok {
+			stateResponse[ch] = state
+		}
+	}
+
+	for _, cg := range groups {
+		if state, ok := m.groupStates[cg]; ok {
+			stateResponse[cg] = state
 		}
 	}
 
@@ -182,8 +250,121 @@ func (m *StateManager) isEmpty() bool {
 	m.RLock()
 	defer m.RUnlock()
 
-	return len(m.channels) != 0 && len(m.presenceChannels) != 0 &&
-		len(m.groups) != 0 && len(m.presenceGroups) != 0
+	return len(m.channels) == 0 && len(m.presenceChannels) == 0 &&
+		len(m.groups) == 0 && len(m.presenceGroups) == 0
+}
+
+// retain counts one more Subscription holding each of the channels and
+// groups and returns the ones which are not subscribed yet: the ones no
+// Subscription held before and the ones dropped by UnsubscribeAll().
+func (m *StateManager) retain(channels, groups []string) ([]string, []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	retainedChannels := []string{}
+	for _, ch := range channels {
+		m.channelRefs[ch]++
+		if !isSubscribedName(ch, m.channels, m.presenceChannels) {
+			retainedChannels = append(retainedChannels, ch)
+		}
+	}
+
+	retainedGroups := []string{}
+	for _, cg := range groups {
+		m.groupRefs[cg]++
+		if !isSubscribedName(cg, m.groups, m.presenceGroups) {
+			retainedGroups = append(retainedGroups, cg)
+		}
+	}
+
+	return retainedChannels, retainedGroups
+}
+
+// release counts one Subscription less holding each of the channels and
+// groups and returns the ones no Subscription holds anymore.
+func (m *StateManager) release(channels, groups []string) ([]string, []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	return releaseRefs(m.channelRefs, channels), releaseRefs(m.groupRefs, groups)
+}
+
+// retainLegacy counts pn.Subscribe() as one reference to each of the
+// channels and groups, however many times it subscribed to them.
+func (m *StateManager) retainLegacy(channels, groups []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	retainLegacyRefs(m.channelRefs, m.legacyChannels, channels)
+	retainLegacyRefs(m.groupRefs, m.legacyGroups, groups)
+}
+
+// releaseLegacy drops the reference of pn.Subscribe() to the channels and
+// groups and returns the ones to unsubscribe from: those no Subscription
+// holds.
+func (m *StateManager) releaseLegacy(channels, groups []string) ([]string, []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	return releaseLegacyRefs(m.channelRefs, m.legacyChannels, channels),
+		releaseLegacyRefs(m.groupRefs, m.legacyGroups, groups)
+}
+
+// releaseAllLegacy drops all the references of pn.Subscribe().
+func (m *StateManager) releaseAllLegacy() {
+	m.Lock()
+	defer m.Unlock()
+
+	for name := range m.legacyChannels {
+		releaseLegacyRefs(m.channelRefs, m.legacyChannels, []string{name})
+	}
+	for name := range m.legacyGroups {
+		releaseLegacyRefs(m.groupRefs, m.legacyGroups, []string{name})
+	}
+}
+
+func retainLegacyRefs(refs map[string]int, legacy map[string]bool, names []string) {
+	for _, name := range names {
+		if !legacy[name] {
+			legacy[name] = true
+			refs[name]++
+		}
+	}
+}
+
+func releaseLegacyRefs(refs map[string]int, legacy map[string]bool, names []string) []string {
+	released := []string{}
+	for _, name := range names {
+		if legacy[name] {
+			delete(legacy, name)
+			released = append(released, releaseRefs(refs, []string{name})...)
+		} else if refs[name] == 0 {
+			released = append(released, name)
+		}
+	}
+	return released
+}
+
+func releaseRefs(refs map[string]int, names []string) []string {
+	released := []string{}
+	for _, name := range names {
+		if refs[name] <= 1 {
+			delete(refs, name)
+			released = append(released, name)
+		} else {
+			refs[name]--
+		}
+	}
+	return released
+}
+
+func isSubscribedName(name string, items, presenceItems map[string]*SubscriptionItem) bool {
+	if strings.Contains(name, "-pnpres") {
+		_, ok := presenceItems[strings.Replace(name, "-pnpres", "", -1)]
+		return ok
+	}
+	_, ok := items[name]
+	return ok
 }
 
 func (m *StateManager) hasNonPresenceChannels() bool {
@@ -0,0 +1,79 @@
+package pubnub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateManagerStateStore(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	// set before subscribing, sent once subscribed
+	m.adaptStateOperation(StateOperation{channels: []string{"ch1"}, state: map[string]interface{}{"k": "v1"}})
+	assert.Len(m.createStatePayload(), 0)
+	assert.Equal(map[string]interface{}{"k": "v1"}, m.channelState("ch1"))
+
+	m.adaptSubscribeOperation(&SubscribeOperation{
+		Channels:      []string{"ch1", "ch2"},
+		ChannelGroups: []string{"cg"},
+		State:         map[string]interface{}{"k": "v2"},
+	})
+	assert.Equal(map[string]interface{}{
+		"ch1": map[string]interface{}{"k": "v2"},
+		"ch2": map[string]interface{}{"k": "v2"},
+		"cg":  map[string]interface{}{"k": "v2"},
+	}, m.createStatePayload())
+
+	// subscribing again without State keeps it
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: []string{"ch1"}, PresenceEnabled: true})
+	assert.Equal(map[string]interface{}{"k": "v2"}, m.channelState("ch1"))
+
+	m.adaptStateOperation(StateOperation{channels: []string{"ch2"}, state: map[string]interface{}{}})
+	assert.Nil(m.channelState("ch2"))
+	assert.Equal(map[string]interface{}{
+		"ch1": map[string]interface{}{"k": "v2"},
+		"cg":  map[string]interface{}{"k": "v2"},
+	}, m.statePayload([]string{"ch1", "ch2"}, []string{"cg"}))
+
+	m.adaptUnsubscribeOperation(&UnsubscribeOperation{Channels: []string{"ch1"}, ChannelGroups: []string{"cg"}})
+	assert.Nil(m.channelState("ch1"))
+	assert.Nil(m.groupState("cg"))
+	assert.Len(m.createStatePayload(), 0)
+}
+
+func TestStateManagerStateIsCopied(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+	state := map[string]interface{}{"k": "v"}
+
+	m.adaptStateOperation(StateOperation{channelGroups: []string{"cg"}, state: state})
+	state["k"] = "changed"
+	stored := m.groupState("cg")
+	stored["k"] = "changed"
+
+	assert.Equal(map[string]interface{}{"k": "v"}, m.groupState("cg"))
+}
+
+func TestPresenceDisconnectDropsState(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	m := pn.subscriptionManager.stateManager
+	pn.heartbeatManager.Lock()
+	pn.heartbeatManager.heartbeatChannels["ch1"] = newSubscriptionItem("ch1")
+	pn.heartbeatManager.heartbeatGroups["cg1"] = newSubscriptionItem("cg1")
+	pn.heartbeatManager.Unlock()
+	m.Lock()
+	m.channels["ch2"] = newSubscriptionItem("ch2")
+	m.Unlock()
+	state := map[string]interface{}{"k": "v"}
+	m.adaptStateOperation(StateOperation{channels: []string{"ch1", "ch2"}, channelGroups: []string{"cg1"}, state: state})
+
+	pn.Presence().Connected(false).Execute()
+
+	assert.Nil(pn.GetChannelPresenceState("ch1"))
+	assert.Nil(pn.GetChannelGroupPresenceState("cg1"))
+	// still sent by the subscribe loop
+	assert.Equal(state, pn.GetChannelPresenceState("ch2"))
+}
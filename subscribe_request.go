@@ -99,6 +99,19 @@ func (b *subscribeBuilder) QueryParam(queryParam map[string]string) *subscribeBu
 
 // Execute runs the Subscribe operation.
 func (b *subscribeBuilder) Execute() {
+	channels := append([]string{}, b.operation.Channels...)
+	groups := append([]string{}, b.operation.ChannelGroups...)
+	if b.operation.PresenceEnabled {
+		for _, ch := range b.operation.Channels {
+			channels = append(channels, ch+"-pnpres")
+		}
+		for _, cg := range b.operation.ChannelGroups {
+			groups = append(groups, cg+"-pnpres")
+		}
+	}
+	// the channels are not unsubscribed when a Subscription on them is
+	b.opts.pubnub.subscriptionManager.stateManager.retainLegacy(channels, groups)
+
 	b.opts.pubnub.subscriptionManager.adaptSubscribe(b.operation)
 }
 
@@ -0,0 +1,193 @@
+package pubnub
+
+import (
+	"sync"
+)
+
+// subscribeEvent is a typed input of the subscribeStateMachine. Every change
+// of the subscribe loop state is caused by exactly one event.
+type subscribeEvent int
+
+const (
+	// subscribeEventSubscriptionChanged is sent when the channel mix changed and
+	// at least one channel or channel group is left to subscribe to.
+	subscribeEventSubscriptionChanged subscribeEvent = 1 + iota
+	// subscribeEventSubscriptionEmpty is sent when no channels or channel
+	// groups are left to subscribe to.
+	subscribeEventSubscriptionEmpty
+	// subscribeEventReceiveSuccess is sent after each successful subscribe
+	// response.
+	subscribeEventReceiveSuccess
+	// subscribeEventReceiveFailure is sent when a subscribe request failed with
+	// an error the reconnection manager can recover from.
+	subscribeEventReceiveFailure
+	// subscribeEventUnrecoverableFailure is sent when a subscribe request
+	// failed with an error retrying can't fix, for ex. 403.
+	subscribeEventUnrecoverableFailure
+	// subscribeEventReconnected is sent by the reconnection manager once the
+	// network is reachable again.
+	subscribeEventReconnected
+	// subscribeEventReconnectionGiveUp is sent by the reconnection manager
+	// when MaximumReconnectionRetries is exhausted.
+	subscribeEventReconnectionGiveUp
+	// subscribeEventStop is sent when the PubNub instance is destroyed.
+	subscribeEventStop
+)
+
+func (e subscribeEvent) String() string {
+	switch e {
+	case subscribeEventSubscriptionChanged:
+		return "Subscription Changed"
+	case subscribeEventSubscriptionEmpty:
+		return "Subscription Empty"
+	case subscribeEventReceiveSuccess:
+		return "Receive Success"
+	case subscribeEventReceiveFailure:
+		return "Receive Failure"
+	case subscribeEventUnrecoverableFailure:
+		return "Unrecoverable Failure"
+	case subscribeEventReconnected:
+		return "Reconnected"
+	case subscribeEventReconnectionGiveUp:
+		return "Reconnection Give Up"
+	case subscribeEventStop:
+		return "Stop"
+	default:
+		return "Unknown Event"
+	}
+}
+
+// subscribeTransitions lists the state each event leads to from each state.
+// Events missing from a state are ignored in that state.
+var subscribeTransitions = map[SubscribeState]map[subscribeEvent]SubscribeState{
+	PNSubscribeStateUnsubscribed: {
+		subscribeEventSubscriptionChanged: PNSubscribeStateHandshaking,
+		subscribeEventStop:                PNSubscribeStateStopped,
+	},
+	PNSubscribeStateHandshaking: {
+		subscribeEventSubscriptionChanged:  PNSubscribeStateHandshaking,
+		subscribeEventSubscriptionEmpty:    PNSubscribeStateUnsubscribed,
+		subscribeEventReceiveSuccess:       PNSubscribeStateReceiving,
+		subscribeEventReceiveFailure:       PNSubscribeStateReconnecting,
+		subscribeEventUnrecoverableFailure: PNSubscribeStateFailed,
+		subscribeEventStop:                 PNSubscribeStateStopped,
+	},
+	PNSubscribeStateReceiving: {
+		subscribeEventSubscriptionChanged:  PNSubscribeStateHandshaking,
+		subscribeEventSubscriptionEmpty:    PNSubscribeStateUnsubscribed,
+		subscribeEventReceiveSuccess:       PNSubscribeStateReceiving,
+		subscribeEventReceiveFailure:       PNSubscribeStateReconnecting,
+		subscribeEventUnrecoverableFailure: PNSubscribeStateFailed,
+		subscribeEventStop:                 PNSubscribeStateStopped,
+	},
+	PNSubscribeStateReconnecting: {
+		subscribeEventSubscriptionChanged:  PNSubscribeStateHandshaking,
+		subscribeEventSubscriptionEmpty:    PNSubscribeStateUnsubscribed,
+		subscribeEventReceiveSuccess:       PNSubscribeStateReceiving,
+		subscribeEventReceiveFailure:       PNSubscribeStateReconnecting,
+		subscribeEventUnrecoverableFailure: PNSubscribeStateFailed,
+		subscribeEventReconnected:          PNSubscribeStateReceiving,
+		subscribeEventReconnectionGiveUp:   PNSubscribeStateFailed,
+		subscribeEventStop:                 PNSubscribeStateStopped,
+	},
+	PNSubscribeStateFailed: {
+		subscribeEventSubscriptionChanged: PNSubscribeStateHandshaking,
+		subscribeEventSubscriptionEmpty:   PNSubscribeStateUnsubscribed,
+		subscribeEventStop:                PNSubscribeStateStopped,
+	},
+	PNSubscribeStateStopped: {},
+}
+
+// subscribeTransition describes a single state change of the
+// subscribeStateMachine.
+type subscribeTransition struct {
+	From  SubscribeState
+	To    SubscribeState
+	Event subscribeEvent
+	Err   error
+}
+
+// subscribeStateMachine is the single source of truth for the state of the
+// subscribe loop. It has no dependencies on the network or on the
+// SubscriptionManager, which feeds it events and reacts to its transitions.
+type subscribeStateMachine struct {
+	sync.Mutex
+	state        SubscribeState
+	onTransition func(transition subscribeTransition)
+
+	// transitionMutex keeps the calls of onTransition in the order of the
+	// transitions.
+	transitionMutex sync.Mutex
+}
+
+func newSubscribeStateMachine(onTransition func(transition subscribeTransition)) *subscribeStateMachine {
+	return &subscribeStateMachine{
+		state:        PNSubscribeStateUnsubscribed,
+		onTransition: onTransition,
+	}
+}
+
+func (m *subscribeStateMachine) currentState() SubscribeState {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.state
+}
+
+// handle applies the event to the current state. It returns the transition
+// and true when the state changed. Events which keep the machine in the same
+// state or which are not valid in the current state return false and are not
+// reported to onTransition.
+func (m *subscribeStateMachine) handle(event subscribeEvent, err error) (subscribeTransition, bool) {
+	m.Lock()
+	from := m.state
+	to, ok := subscribeTransitions[from][event]
+	if !ok || to == from {
+		m.Unlock()
+		return subscribeTransition{}, false
+	}
+	m.state = to
+	// taken before the state is released so that the next transition waits
+	// for this one to be reported
+	m.transitionMutex.Lock()
+	defer m.transitionMutex.Unlock()
+	m.Unlock()
+
+	transition := subscribeTransition{
+		From:  from,
+		To:    to,
+		Event: event,
+		Err:   err,
+	}
+	if m.onTransition != nil {
+		m.onTransition(transition)
+	}
+	return transition, true
+}
+
+// statusCategory returns the category of the status announced for the
+// transition.
+func (t subscribeTransition) statusCategory() StatusCategory {
+	switch t.To {
+	case PNSubscribeStateHandshaking:
+		return PNConnectingCategory
+	case PNSubscribeStateReceiving:
+		if t.From == PNSubscribeStateReconnecting {
+			return PNReconnectedCategory
+		}
+		return PNConnectedCategory
+	case PNSubscribeStateReconnecting:
+		return categorizeError(t.Err)
+	case PNSubscribeStateFailed:
+		if t.Event == subscribeEventReconnectionGiveUp {
+			return PNReconnectionAttemptsExhausted
+		}
+		return categorizeError(t.Err)
+	case PNSubscribeStateUnsubscribed:
+		return PNDisconnectedCategory
+	case PNSubscribeStateStopped:
+		return PNLoopStopCategory
+	default:
+		return PNUnknownCategory
+	}
+}
@@ -0,0 +1,157 @@
+package pubnub
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pubnub/go/v7/pnerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRecordingStateMachine() (*subscribeStateMachine, *[]subscribeTransition) {
+	transitions := []subscribeTransition{}
+	m := newSubscribeStateMachine(func(t subscribeTransition) {
+		transitions = append(transitions, t)
+	})
+	return m, &transitions
+}
+
+func TestSubscribeStateMachineHandshake(t *testing.T) {
+	assert := assert.New(t)
+	m, transitions := newRecordingStateMachine()
+
+	assert.Equal(PNSubscribeStateUnsubscribed, m.currentState())
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	m.handle(subscribeEventReceiveSuccess, nil)
+	m.handle(subscribeEventReceiveSuccess, nil)
+
+	assert.Equal(PNSubscribeStateReceiving, m.currentState())
+	assert.Equal(2, len(*transitions))
+	assert.Equal(PNConnectingCategory, (*transitions)[0].statusCategory())
+	assert.Equal(PNConnectedCategory, (*transitions)[1].statusCategory())
+}
+
+func TestSubscribeStateMachineReconnect(t *testing.T) {
+	assert := assert.New(t)
+	m, transitions := newRecordingStateMachine()
+	netErr := pnerr.NewConnectionError("Failed to execute request", errors.New("connection reset by peer"))
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	m.handle(subscribeEventReceiveSuccess, nil)
+	m.handle(subscribeEventReceiveFailure, netErr)
+	assert.Equal(PNSubscribeStateReconnecting, m.currentState())
+
+	_, changed := m.handle(subscribeEventReceiveFailure, netErr)
+	assert.False(changed)
+
+	m.handle(subscribeEventReconnected, nil)
+	assert.Equal(PNSubscribeStateReceiving, m.currentState())
+
+	assert.Equal(4, len(*transitions))
+	assert.Equal(PNNetworkIssuesCategory, (*transitions)[2].statusCategory())
+	assert.Equal(netErr, (*transitions)[2].Err)
+	assert.Equal(PNReconnectedCategory, (*transitions)[3].statusCategory())
+}
+
+func TestSubscribeStateMachineReconnectedIgnoredWhenReceiving(t *testing.T) {
+	assert := assert.New(t)
+	m, _ := newRecordingStateMachine()
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	m.handle(subscribeEventReceiveSuccess, nil)
+
+	_, changed := m.handle(subscribeEventReconnected, nil)
+	assert.False(changed)
+	assert.Equal(PNSubscribeStateReceiving, m.currentState())
+}
+
+func TestSubscribeStateMachineGiveUp(t *testing.T) {
+	assert := assert.New(t)
+	m, transitions := newRecordingStateMachine()
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	m.handle(subscribeEventReceiveFailure, &pnerr.ServerError{StatusCode: 503})
+	m.handle(subscribeEventReconnectionGiveUp, nil)
+	m.handle(subscribeEventSubscriptionEmpty, nil)
+
+	assert.Equal(PNSubscribeStateUnsubscribed, m.currentState())
+	assert.Equal(4, len(*transitions))
+	assert.Equal(PNServerErrorCategory, (*transitions)[1].statusCategory())
+	assert.Equal(PNSubscribeStateFailed, (*transitions)[2].To)
+	assert.Equal(PNReconnectionAttemptsExhausted, (*transitions)[2].statusCategory())
+	assert.Equal(PNDisconnectedCategory, (*transitions)[3].statusCategory())
+}
+
+func TestSubscribeStateMachineAccessDenied(t *testing.T) {
+	assert := assert.New(t)
+	m, transitions := newRecordingStateMachine()
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	m.handle(subscribeEventUnrecoverableFailure, &pnerr.ServerError{StatusCode: 403})
+
+	assert.Equal(PNSubscribeStateFailed, m.currentState())
+	assert.Equal(PNAccessDeniedCategory, (*transitions)[1].statusCategory())
+
+	_, changed := m.handle(subscribeEventReceiveSuccess, nil)
+	assert.False(changed)
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	assert.Equal(PNSubscribeStateHandshaking, m.currentState())
+}
+
+func TestSubscribeStateMachineStop(t *testing.T) {
+	assert := assert.New(t)
+	m, transitions := newRecordingStateMachine()
+
+	m.handle(subscribeEventSubscriptionChanged, nil)
+	m.handle(subscribeEventStop, nil)
+	assert.Equal(PNSubscribeStateStopped, m.currentState())
+	assert.Equal(PNLoopStopCategory, (*transitions)[1].statusCategory())
+
+	_, changed := m.handle(subscribeEventSubscriptionChanged, nil)
+	assert.False(changed)
+	assert.Equal(PNSubscribeStateStopped, m.currentState())
+}
+
+func TestSubscribeStateMachineEmptyWhileUnsubscribed(t *testing.T) {
+	assert := assert.New(t)
+	m, transitions := newRecordingStateMachine()
+
+	_, changed := m.handle(subscribeEventSubscriptionEmpty, nil)
+	assert.False(changed)
+	assert.Equal(0, len(*transitions))
+}
+
+func TestSubscribeStateMachineTransitionsReportedInOrder(t *testing.T) {
+	assert := assert.New(t)
+	transitions := []subscribeTransition{}
+	m := newSubscribeStateMachine(func(t subscribeTransition) {
+		// a slow listener, the next transitions wait for it
+		time.Sleep(time.Millisecond)
+		transitions = append(transitions, t)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.handle(subscribeEventSubscriptionChanged, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			m.handle(subscribeEventReceiveSuccess, nil)
+		}()
+	}
+	wg.Wait()
+
+	for i := 1; i < len(transitions); i++ {
+		assert.Equal(transitions[i-1].To, transitions[i].From)
+	}
+	if assert.NotEmpty(transitions) {
+		assert.Equal(m.currentState(), transitions[len(transitions)-1].To)
+	}
+}
@@ -0,0 +1,330 @@
+package pubnub
+
+import (
+	"strings"
+	"sync"
+)
+
+// ChannelEntity is a handle to a single channel, used to create
+// Subscriptions to it.
+type ChannelEntity struct {
+	pubnub *PubNub
+	name   string
+}
+
+// ChannelGroupEntity is a handle to a single channel group, used to create
+// Subscriptions to it.
+type ChannelGroupEntity struct {
+	pubnub *PubNub
+	name   string
+}
+
+// SubscriptionOptions is used to set the optional parameters of a
+// Subscription.
+type SubscriptionOptions struct {
+	// ReceivePresenceEvents subscribes to the presence channel as well and
+	// delivers its events to the listeners of the Subscription.
+	ReceivePresenceEvents bool
+}
+
+// Subscription is a subscription to a set of channels and channel groups
+// which owns its listeners. Listeners added to a Subscription only receive
+// the events of its own channels and channel groups.
+//
+// Subscriptions are reference counted against the shared subscribe loop:
+// a channel stays subscribed until every Subscription which includes it is
+// unsubscribed, so independent modules can subscribe to overlapping channels
+// without unsubscribing each other. Unsubscribe() and UnsubscribeAll() of the
+// PubNub instance bypass the reference counting.
+type Subscription struct {
+	sync.RWMutex
+	pubnub        *PubNub
+	channels      []string
+	channelGroups []string
+	options       SubscriptionOptions
+	listeners     map[*Listener]bool
+	subscribed    bool
+}
+
+// SubscriptionSet groups Subscriptions so they can be subscribed,
+// unsubscribed and listened to together.
+type SubscriptionSet struct {
+	sync.RWMutex
+	pubnub        *PubNub
+	subscriptions []*Subscription
+	listeners     map[*Listener]bool
+}
+
+// Channel creates a handle to the channel.
+func (pn *PubNub) Channel(name string) *ChannelEntity {
+	return &ChannelEntity{
+		pubnub: pn,
+		name:   name,
+	}
+}
+
+// ChannelGroup creates a handle to the channel group.
+func (pn *PubNub) ChannelGroup(name string) *ChannelGroupEntity {
+	return &ChannelGroupEntity{
+		pubnub: pn,
+		name:   name,
+	}
+}
+
+// Subscription creates a new, not yet subscribed, Subscription to the channel.
+func (e *ChannelEntity) Subscription(options SubscriptionOptions) *Subscription {
+	return newSubscription(e.pubnub, []string{e.name}, []string{}, options)
+}
+
+// Subscription creates a new, not yet subscribed, Subscription to the channel group.
+func (e *ChannelGroupEntity) Subscription(options SubscriptionOptions) *Subscription {
+	return newSubscription(e.pubnub, []string{}, []string{e.name}, options)
+}
+
+// SubscriptionSet creates a SubscriptionSet of the subscriptions.
+func (pn *PubNub) SubscriptionSet(subscriptions ...*Subscription) *SubscriptionSet {
+	return &SubscriptionSet{
+		pubnub:        pn,
+		subscriptions: subscriptions,
+		listeners:     make(map[*Listener]bool),
+	}
+}
+
+func newSubscription(pubnub *PubNub, channels, channelGroups []string, options SubscriptionOptions) *Subscription {
+	return &Subscription{
+		pubnub:        pubnub,
+		channels:      channels,
+		channelGroups: channelGroups,
+		options:       options,
+		listeners:     make(map[*Listener]bool),
+	}
+}
+
+// Channels gets the channels of the Subscription.
+func (s *Subscription) Channels() []string {
+	return append([]string{}, s.channels...)
+}
+
+// ChannelGroups gets the channel groups of the Subscription.
+func (s *Subscription) ChannelGroups() []string {
+	return append([]string{}, s.channelGroups...)
+}
+
+// IsSubscribed returns true between Subscribe() and Unsubscribe().
+func (s *Subscription) IsSubscribed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.subscribed
+}
+
+// AddListener adds a listener which receives the events of the Subscription.
+func (s *Subscription) AddListener(listener *Listener) {
+	s.Lock()
+	s.listeners[listener] = true
+	s.Unlock()
+}
+
+// RemoveListener removes the listener.
+func (s *Subscription) RemoveListener(listener *Listener) {
+	s.Lock()
+	delete(s.listeners, listener)
+	s.Unlock()
+}
+
+// Subscribe starts the delivery of the events of the Subscription and
+// subscribes to the channels and channel groups no other Subscription holds
+// yet. Calling it on a subscribed Subscription does nothing.
+func (s *Subscription) Subscribe() {
+	s.Lock()
+	if s.subscribed {
+		s.Unlock()
+		return
+	}
+	s.subscribed = true
+	s.Unlock()
+
+	m := s.pubnub.subscriptionManager
+	m.listenerManager.addSubscription(s)
+
+	channels, groups := m.stateManager.retain(s.subscribeNames())
+	if len(channels) == 0 && len(groups) == 0 {
+		return
+	}
+	m.adaptSubscribe(&SubscribeOperation{
+		Channels:      channels,
+		ChannelGroups: groups,
+	})
+}
+
+// Unsubscribe stops the delivery of the events of the Subscription and
+// unsubscribes from the channels and channel groups no other Subscription
+// holds anymore. Calling it on an unsubscribed Subscription does nothing.
+func (s *Subscription) Unsubscribe() {
+	s.Lock()
+	if !s.subscribed {
+		s.Unlock()
+		return
+	}
+	s.subscribed = false
+	s.Unlock()
+
+	m := s.pubnub.subscriptionManager
+	m.listenerManager.removeSubscription(s)
+
+	channels, groups := m.stateManager.release(s.subscribeNames())
+	if len(channels) == 0 && len(groups) == 0 {
+		return
+	}
+	m.adaptUnsubscribe(&UnsubscribeOperation{
+		Channels:      channels,
+		ChannelGroups: groups,
+	})
+}
+
+// subscribeNames returns the names the Subscription needs in the subscribe
+// request, including the presence channels and groups.
+func (s *Subscription) subscribeNames() ([]string, []string) {
+	channels := append([]string{}, s.channels...)
+	groups := append([]string{}, s.channelGroups...)
+	if s.options.ReceivePresenceEvents {
+		for _, ch := range s.channels {
+			channels = append(channels, ch+"-pnpres")
+		}
+		for _, cg := range s.channelGroups {
+			groups = append(groups, cg+"-pnpres")
+		}
+	}
+	return channels, groups
+}
+
+// matches reports whether an event received on channel through the
+// subscription match subscriptionMatch (a channel group or a wildcard
+// channel) belongs to the Subscription.
+func (s *Subscription) matches(channel, subscriptionMatch string, presence bool) bool {
+	if presence && !s.options.ReceivePresenceEvents {
+		return false
+	}
+	for _, ch := range s.channels {
+		if ch == channel || ch == subscriptionMatch {
+			return true
+		}
+	}
+	for _, cg := range s.channelGroups {
+		if cg == subscriptionMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// affectedBy reports whether a status about the channels and groups concerns
+// the Subscription. Statuses which name no channels concern everybody.
+func (s *Subscription) affectedBy(channels, groups []string) bool {
+	if len(channels) == 0 && len(groups) == 0 {
+		return true
+	}
+	for _, ch := range channels {
+		if s.matches(ch, "", false) || s.matches(strings.Replace(ch, "-pnpres", "", -1), "", true) {
+			return true
+		}
+	}
+	for _, cg := range groups {
+		if s.matches("", cg, false) || s.matches("", strings.Replace(cg, "-pnpres", "", -1), true) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) copyListeners(lis map[*Listener]bool) {
+	s.RLock()
+	for l := range s.listeners {
+		lis[l] = true
+	}
+	s.RUnlock()
+}
+
+// Add adds a subscription to the set. It is subscribed when the set is.
+func (s *SubscriptionSet) Add(subscription *Subscription) {
+	s.Lock()
+	s.subscriptions = append(s.subscriptions, subscription)
+	listeners := s.copyListeners()
+	s.Unlock()
+
+	for l := range listeners {
+		subscription.AddListener(l)
+	}
+}
+
+// Remove removes a subscription from the set. It is not unsubscribed.
+func (s *SubscriptionSet) Remove(subscription *Subscription) {
+	s.Lock()
+	for i, sub := range s.subscriptions {
+		if sub == subscription {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+			break
+		}
+	}
+	listeners := s.copyListeners()
+	s.Unlock()
+
+	for l := range listeners {
+		subscription.RemoveListener(l)
+	}
+}
+
+// Subscriptions gets the subscriptions of the set.
+func (s *SubscriptionSet) Subscriptions() []*Subscription {
+	s.RLock()
+	defer s.RUnlock()
+
+	return append([]*Subscription{}, s.subscriptions...)
+}
+
+// AddListener adds a listener which receives the events of all subscriptions
+// of the set. An event matching several subscriptions is delivered once.
+func (s *SubscriptionSet) AddListener(listener *Listener) {
+	s.Lock()
+	s.listeners[listener] = true
+	subscriptions := append([]*Subscription{}, s.subscriptions...)
+	s.Unlock()
+
+	for _, sub := range subscriptions {
+		sub.AddListener(listener)
+	}
+}
+
+// RemoveListener removes the listener from the set and all its subscriptions.
+func (s *SubscriptionSet) RemoveListener(listener *Listener) {
+	s.Lock()
+	delete(s.listeners, listener)
+	subscriptions := append([]*Subscription{}, s.subscriptions...)
+	s.Unlock()
+
+	for _, sub := range subscriptions {
+		sub.RemoveListener(listener)
+	}
+}
+
+// Subscribe subscribes all subscriptions of the set.
+func (s *SubscriptionSet) Subscribe() {
+	for _, sub := range s.Subscriptions() {
+		sub.Subscribe()
+	}
+}
+
+// Unsubscribe unsubscribes all subscriptions of the set.
+func (s *SubscriptionSet) Unsubscribe() {
+	for _, sub := range s.Subscriptions() {
+		sub.Unsubscribe()
+	}
+}
+
+func (s *SubscriptionSet) copyListeners() map[*Listener]bool {
+	lis := make(map[*Listener]bool, len(s.listeners))
+	for l := range s.listeners {
+		lis[l] = true
+	}
+	return lis
+}
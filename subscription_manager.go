@@ -9,14 +9,17 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/pubnub/go/v7/utils"
 )
 
 // SubscriptionManager Events:
+// The state of the subscribe loop is kept by a subscribeStateMachine and
+// every change of it is announced as a status carrying the new SubscribeState:
+// - ConnectingCategory - a handshake with a new channel mix started
 // - ConnectedCategory - after connection established
-// - DisconnectedCategory - after subscription loop stops for any reason (no
-// channels left or error happened)
+// - ReconnectedCategory - after the network came back
+// - DisconnectedCategory - no channels or channel groups left
+// - LoopStopCategory - the PubNub instance was destroyed
+// - category of the error - after the subscribe request failed
 // Unsubscribe
 // When you unsubscribe from channel or channel group the following events
 // happens:
@@ -53,17 +56,25 @@ type SubscriptionManager struct {
 	// latest timetoken.
 	timetoken int64
 
-	// When changing the channel mix, store the timetoken for a later date
+	// When changing the channel mix, store the timetoken for a later date,
+	// 0 when there is nothing to resume from.
 	storedTimetoken int64
 
 	region int8
 
-	subscriptionStateAnnounced   bool
-	heartbeatStopCalled          bool
+	// loopGeneration is bumped by each new subscribe loop, an older loop
+	// stops at its next response.
+	loopGeneration uint64
+
+	stateMachine                 *subscribeStateMachine
+	gapFiller                    *gapFiller
+	cursorTracker                *cursorTracker
+	dedupCacheOnce               sync.Once
+	dedupCache                   *dedupCache
+	destroyOnce                  sync.Once
 	exitSubscriptionManagerMutex sync.RWMutex
 	exitSubscriptionManager      chan bool
 	queryParam                   map[string]string
-	channelsOpen                 bool
 	requestSentAt                int64
 }
 
@@ -102,51 +113,32 @@ func newSubscriptionManager(pubnub *PubNub, ctx Context) *SubscriptionManager {
 
 	manager.Lock()
 	manager.timetoken = 0
-	manager.storedTimetoken = -1
-	manager.subscriptionStateAnnounced = false
+	manager.storedTimetoken = 0
 	manager.ctx, manager.subscribeCancel = contextWithCancel(backgroundContext)
 	manager.messages = make(chan subscribeMessage, 1000)
 	manager.reconnectionManager = newReconnectionManager(pubnub)
-	manager.channelsOpen = true
+	manager.stateMachine = newSubscribeStateMachine(manager.onStateTransition)
+	manager.gapFiller = newGapFiller(pubnub)
+	manager.cursorTracker = newCursorTracker(pubnub)
 	manager.Unlock()
 
 	if manager.pubnub.Config.PNReconnectionPolicy != PNNonePolicy {
 
 		manager.reconnectionManager.HandleReconnection(func() {
-			go manager.reconnect()
-
-			manager.Lock()
-			manager.subscriptionStateAnnounced = true
-			manager.Unlock()
-			combinedChannels := manager.stateManager.prepareChannelList(true)
-			combinedGroups := manager.stateManager.prepareGroupList(true)
-
-			pnStatus := &PNStatus{
-				Error:                 false,
-				AffectedChannels:      combinedChannels,
-				AffectedChannelGroups: combinedGroups,
-				Category:              PNReconnectedCategory,
+			if _, changed := manager.stateMachine.handle(subscribeEventReconnected, nil); changed {
+				go func() {
+					// the recovered messages are queued before the live ones
+					if manager.pubnub.Config.EnableGapFill {
+						manager.fillGap()
+					}
+					manager.reconnect()
+				}()
 			}
-
-			pubnub.Config.Log.Println("Status: ", pnStatus)
-
-			manager.listenerManager.announceStatus(pnStatus)
 		})
 	}
 
 	manager.reconnectionManager.HandleOnMaxReconnectionExhaustion(func() {
-		combinedChannels := manager.stateManager.prepareChannelList(true)
-		combinedGroups := manager.stateManager.prepareGroupList(true)
-
-		pnStatus := &PNStatus{
-			Error:                 false,
-			AffectedChannels:      combinedChannels,
-			AffectedChannelGroups: combinedGroups,
-			Category:              PNReconnectionAttemptsExhausted,
-		}
-		pubnub.Config.Log.Println("Status: ", pnStatus)
-
-		manager.listenerManager.announceStatus(pnStatus)
+		manager.stateMachine.handle(subscribeEventReconnectionGiveUp, nil)
 
 		manager.Disconnect()
 	})
@@ -167,10 +159,8 @@ func (m *SubscriptionManager) Destroy() {
 	if m.subscribeCancel != nil {
 		m.subscribeCancel()
 	}
-	if m.channelsOpen {
-		m.RLock()
-		m.channelsOpen = false
-		m.RUnlock()
+	m.destroyOnce.Do(func() {
+		m.stateMachine.handle(subscribeEventStop, nil)
 		m.exitSubscriptionManagerMutex.RLock()
 		if m.exitSubscriptionManager != nil {
 			close(m.exitSubscriptionManager)
@@ -186,9 +176,49 @@ func (m *SubscriptionManager) Destroy() {
 			m.reconnectionManager.stopHeartbeatTimer()
 			close(m.reconnectionManager.exitReconnectionManager)
 		}
+	})
+}
 
+// onStateTransition announces every change of the subscribe loop state and
+// runs the reconnection manager only while the loop is Reconnecting.
+func (m *SubscriptionManager) onStateTransition(transition subscribeTransition) {
+	m.pubnub.Config.Log.Println("subscribe state:", transition.From, "->", transition.To, "on", transition.Event)
+
+	if transition.To == PNSubscribeStateReconnecting {
+		go m.reconnectionManager.startPolling()
+	} else if transition.From == PNSubscribeStateReconnecting {
+		m.reconnectionManager.stopHeartbeatTimer()
+	}
+
+	pnStatus := &PNStatus{
+		Category:              transition.statusCategory(),
+		Operation:             PNSubscribeOperation,
+		AffectedChannels:      m.stateManager.prepareChannelList(true),
+		AffectedChannelGroups: m.stateManager.prepareGroupList(true),
+		SubscribeState:        transition.To,
+	}
+	if transition.Err != nil {
+		pnStatus.Error = true
+		pnStatus.ErrorData = transition.Err
+		pnStatus.StatusCode = statusCodeOf(transition.Err)
 	}
+	m.pubnub.Config.Log.Println("Status:", pnStatus)
+	m.listenerManager.announceStatus(pnStatus)
+}
 
+// subscriptionChanged tells the state machine whether there is anything left
+// to subscribe to after the channel mix changed.
+func (m *SubscriptionManager) subscriptionChanged() {
+	if m.stateManager.isEmpty() {
+		m.stateMachine.handle(subscribeEventSubscriptionEmpty, nil)
+	} else {
+		m.stateMachine.handle(subscribeEventSubscriptionChanged, nil)
+	}
+}
+
+// GetState returns the current state of the subscribe loop.
+func (m *SubscriptionManager) GetState() SubscribeState {
+	return m.stateMachine.currentState()
 }
 
 func (m *SubscriptionManager) adaptState(stateOperation StateOperation) {
@@ -203,9 +233,12 @@ func (m *SubscriptionManager) adaptSubscribe(
 
 	m.Lock()
 
-	m.subscriptionStateAnnounced = false
 	m.queryParam = subscribeOperation.QueryParam
 
+	if subscribeOperation.Timetoken == 0 && m.timetoken == 0 && m.storedTimetoken == 0 {
+		m.resumeFromCursorStore()
+	}
+
 	if subscribeOperation.Timetoken != 0 {
 		m.timetoken = subscribeOperation.Timetoken
 	}
@@ -218,6 +251,7 @@ func (m *SubscriptionManager) adaptSubscribe(
 
 	m.Unlock()
 
+	m.subscriptionChanged()
 	m.reconnect()
 }
 
@@ -226,10 +260,7 @@ func (m *SubscriptionManager) adaptUnsubscribe(
 	m.pubnub.Config.Log.Println("before adaptUnsubscribeOperation")
 	m.stateManager.adaptUnsubscribeOperation(unsubscribeOperation)
 	m.pubnub.Config.Log.Println("after adaptUnsubscribeOperation")
-
-	m.Lock()
-	m.subscriptionStateAnnounced = false
-	m.Unlock()
+	m.cursorTracker.release(append(append([]string{}, unsubscribeOperation.Channels...), unsubscribeOperation.ChannelGroups...))
 
 	go func() {
 		announceAck := false
@@ -273,7 +304,7 @@ func (m *SubscriptionManager) adaptUnsubscribe(
 	m.Lock()
 	if m.stateManager.isEmpty() {
 		m.region = 0
-		m.storedTimetoken = -1
+		m.storedTimetoken = 0
 		m.timetoken = 0
 	} else {
 		m.storedTimetoken = m.timetoken
@@ -282,16 +313,23 @@ func (m *SubscriptionManager) adaptUnsubscribe(
 	m.Unlock()
 	m.pubnub.Config.Log.Println("after storedTimetoken reset")
 
+	m.subscriptionChanged()
+
 	m.reconnect()
 	m.pubnub.Config.Log.Println("after reconnect")
 }
 
 func (m *SubscriptionManager) startSubscribeLoop() {
 	m.pubnub.Config.Log.Println("startSubscribeLoop")
+	m.Lock()
+	if m.ctx == nil && m.subscribeCancel == nil {
+		m.ctx, m.subscribeCancel = contextWithCancel(backgroundContext)
+	}
+	m.loopGeneration++
+	generation := m.loopGeneration
+	m.Unlock()
 	go subscribeMessageWorker(m)
 
-	go m.reconnectionManager.startPolling()
-
 	for {
 		m.pubnub.Config.Log.Println("startSubscribeLoop looping...")
 		combinedChannels := m.stateManager.prepareChannelList(true)
@@ -299,11 +337,7 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 
 		if len(combinedChannels) == 0 && len(combinedGroups) == 0 {
 			m.pubnub.Config.Log.Println("no channels left to subscribe")
-			m.listenerManager.announceStatus(&PNStatus{
-				Category: PNDisconnectedCategory,
-			})
-
-			m.reconnectionManager.stopHeartbeatTimer()
+			m.stateMachine.handle(subscribeEventSubscriptionEmpty, nil)
 
 			break
 		}
@@ -326,80 +360,60 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 		if s := m.stateManager.createStatePayload(); len(s) > 0 {
 			opts.State = s
 		}
+		if m.pubnub.Config.EnableGapFill {
+			// the messages of this request are newer than tt
+			m.gapFiller.seed(combinedChannels, tt)
+		}
 		m.hbDataMutex.Lock()
 		m.requestSentAt = time.Now().Unix()
 		m.hbDataMutex.Unlock()
 
-		res, _, err := executeRequest(opts)
+		res, status, err := executeRequest(opts)
 		if err != nil {
 			m.pubnub.Config.Log.Println(err.Error())
 
-			if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "request canceled") {
-				m.listenerManager.announceStatus(&PNStatus{
-					Category: PNTimeoutCategory,
-				})
-				m.pubnub.Config.Log.Println("continue")
-				continue
-			} else {
-
-				if strings.Contains(err.Error(), "context canceled") {
-					pnStatus := &PNStatus{
-						Category: PNCancelledCategory,
-					}
-					m.pubnub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
-					m.pubnub.Config.Log.Println("context canceled")
-					break
-				} else if strings.Contains(err.Error(), "Forbidden") ||
-					strings.Contains(err.Error(), "403") {
-					pnStatus := &PNStatus{
-						Category: PNAccessDeniedCategory,
-					}
-					m.pubnub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
-					m.unsubscribeAll()
-					break
-				} else if strings.Contains(err.Error(), "400") ||
-					strings.Contains(err.Error(), "Bad Request") {
-					pnStatus := &PNStatus{
-						Category: PNBadRequestCategory,
-					}
-					m.pubnub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
-					m.unsubscribeAll()
-					break
-				} else if strings.Contains(err.Error(), "530") || strings.Contains(err.Error(), "No Stub Matched") {
-					pnStatus := &PNStatus{
-						Category: PNNoStubMatchedCategory,
-					}
-					m.pubnub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
-					m.unsubscribeAll()
-					break
-				} else {
-					pnStatus := &PNStatus{
-						Category: PNUnknownCategory,
-					}
-					m.pubnub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
-
-					break
+			category := categorizeError(err)
+			switch {
+			case category == PNTimeoutCategory || category == PNCancelledCategory:
+				// A long-poll timeout or a loop stopped by reconnect() is not a
+				// change of the subscribe state.
+				pnStatus := &PNStatus{
+					Category:              category,
+					ErrorData:             err,
+					Error:                 true,
+					StatusCode:            status.StatusCode,
+					Operation:             PNSubscribeOperation,
+					AffectedChannels:      combinedChannels,
+					AffectedChannelGroups: combinedGroups,
+					SubscribeState:        m.stateMachine.currentState(),
 				}
+				m.pubnub.Config.Log.Println("Status:", pnStatus)
+				m.listenerManager.announceStatus(pnStatus)
+			case isUnrecoverableCategory(category):
+				m.stateMachine.handle(subscribeEventUnrecoverableFailure, err)
+				m.unsubscribeAll()
+			default:
+				m.stateMachine.handle(subscribeEventReceiveFailure, err)
 			}
 
+			if category == PNTimeoutCategory {
+				m.pubnub.Config.Log.Println("continue")
+				continue
+			}
+			break
 		}
 
-		m.Lock()
-		announced := m.subscriptionStateAnnounced
-
-		if announced == false {
-
-			m.listenerManager.announceStatus(&PNStatus{
-				Category: PNConnectedCategory,
-			})
-			m.subscriptionStateAnnounced = true
+		m.RLock()
+		superseded := m.loopGeneration != generation
+		m.RUnlock()
+		if superseded {
+			// reconnect() already started a new loop for a new channel mix,
+			// this response belongs to the old one.
+			m.pubnub.Config.Log.Println("subscribe loop superseded")
+			break
 		}
-		m.Unlock()
+
+		m.stateMachine.handle(subscribeEventReceiveSuccess, nil)
 
 		var envelope subscribeEnvelope
 		err = json.Unmarshal(res, &envelope)
@@ -416,6 +430,17 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 
 			m.listenerManager.announceStatus(pnStatus)
 		}
+		var batch *cursorBatch
+		if m.pubnub.Config.CursorStore != nil {
+			var acks []*cursorAck
+			batch, acks = m.cursorTracker.begin(ackableSubscriptions(envelope.Messages))
+			for i := range envelope.Messages {
+				if isAckable(envelope.Messages[i]) {
+					envelope.Messages[i].ack, acks = acks[0], acks[1:]
+				}
+			}
+		}
+
 		messageCount := len(envelope.Messages)
 		if messageCount > 0 {
 			if messageCount > m.pubnub.Config.MessageQueueOverflowCount {
@@ -435,10 +460,10 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 		}
 
 		m.Lock()
-		if m.storedTimetoken != -1 {
+		if m.storedTimetoken != 0 {
 
 			m.timetoken = m.storedTimetoken
-			m.storedTimetoken = -1
+			m.storedTimetoken = 0
 		} else {
 			tt, err := strconv.ParseInt(envelope.Metadata.Timetoken, 10, 64)
 			if err != nil {
@@ -459,8 +484,67 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 		}
 
 		m.region = envelope.Metadata.Region
+		cursor := SubscribeCursor{
+			Timetoken: m.timetoken,
+			Region:    m.region,
+		}
 		m.Unlock()
+
+		if batch != nil {
+			m.cursorTracker.commit(batch, cursor)
+		}
+	}
+}
+
+// resumeFromCursorStore starts the loop from the cursor saved in
+// Config.CursorStore, if any. Called with the lock held.
+func (m *SubscriptionManager) resumeFromCursorStore() {
+	store := m.pubnub.Config.CursorStore
+	if store == nil {
+		return
+	}
+	cursor, err := store.Load()
+	if err != nil {
+		m.pubnub.Config.Log.Println("CursorStore.Load: err", err)
+		return
+	}
+	if cursor == nil {
+		return
+	}
+	m.pubnub.Config.Log.Println("resuming from cursor", cursor.Timetoken, cursor.Region)
+	m.timetoken = cursor.Timetoken
+	m.region = cursor.Region
+}
+
+// isAckable reports whether the message is delivered as an event with Ack(),
+// the others don't hold the subscribe cursor.
+func isAckable(message subscribeMessage) bool {
+	if strings.Contains(message.Channel, "-pnpres") {
+		return false
+	}
+	switch message.MessageType {
+	case 0, PNMessageTypeSignal, PNMessageTypeFile:
+		return true
+	default:
+		return false
+	}
+}
+
+// ackableSubscriptions returns the channel, channel group or wildcard each
+// ackable message was received through.
+func ackableSubscriptions(messages []subscribeMessage) []string {
+	subscriptions := []string{}
+	for _, message := range messages {
+		if !isAckable(message) {
+			continue
+		}
+		if message.SubscriptionMatch != "" {
+			subscriptions = append(subscriptions, message.SubscriptionMatch)
+		} else {
+			subscriptions = append(subscriptions, message.Channel)
+		}
 	}
+	return subscriptions
 }
 
 type subscribeEnvelope struct {
@@ -484,6 +568,9 @@ type subscribeMessage struct {
 	SequenceNumber    int           `json:"s"`
 
 	PublishMetaData publishMetadata `json:"p"`
+
+	// ack is handed to the delivered event when a CursorStore is set.
+	ack *cursorAck
 }
 
 type presenceEnvelope struct {
@@ -637,9 +724,36 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 	}
 	var messagePayload interface{}
 
+	if m.pubnub.Config.EnableGapFill && (payload.MessageType == 0 || payload.MessageType == PNMessageTypeFile) &&
+		!m.gapFiller.track(channel, subscriptionMatch, timetoken) {
+		m.pubnub.Config.Log.Println("gap fill: duplicate message skipped", channel, timetoken)
+		payload.ack.ack()
+		return
+	}
+
+	switch payload.MessageType {
+	case 0, PNMessageTypeSignal, PNMessageTypeFile:
+		if m.isDuplicate(channel, payload.IssuingClientID, timetoken) {
+			m.pubnub.Config.Log.Println("duplicate message skipped", channel, payload.IssuingClientID, timetoken)
+			payload.ack.ack()
+			return
+		}
+	}
+
 	switch payload.MessageType {
 	case PNMessageTypeSignal:
-		pnMessageResult := createPNMessageResult(payload.Payload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
+		messagePayload = payload.Payload
+		var decryptionErr *DecryptionError
+		if m.pubnub.Config.signalCryptoModule(channel) != nil {
+			if messagePayload, decryptionErr = decryptPayload(payload.Payload, channel, timetoken, m.pubnub.Config); decryptionErr != nil {
+				m.announceDecryptionError(decryptionErr)
+			}
+		}
+		pnMessageResult := createPNMessageResult(messagePayload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
+		if decryptionErr != nil {
+			pnMessageResult.Error = decryptionErr
+		}
+		pnMessageResult.ack = payload.ack
 		m.pubnub.Config.Log.Println("announceSignal,", pnMessageResult)
 		m.listenerManager.announceSignal(pnMessageResult)
 	case PNMessageTypeObjects:
@@ -659,42 +773,44 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 	case PNMessageTypeMessageActions:
 		pnMessageActionsEvent := createPNMessageActionsEventResult(payload.Payload, m, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID)
 		m.pubnub.Config.Log.Println("PNMessageTypeMessageActions:", pnMessageActionsEvent)
-		m.listenerManager.announceMessageActionsEvent(pnMessageActionsEvent)
+		if pnMessageActionsEvent != nil {
+			m.listenerManager.announceMessageActionsEvent(pnMessageActionsEvent)
+		}
 	case PNMessageTypeFile:
-		var err error
-		messagePayload, err = parseCipherInterface(payload.Payload, m.pubnub.Config)
-		if err != nil {
-			pnStatus := &PNStatus{
-				Category:         PNBadRequestCategory,
-				ErrorData:        err,
-				Error:            true,
-				Operation:        PNSubscribeOperation,
-				AffectedChannels: []string{channel},
+		var pnFilesEvent *PNFilesEvent
+		if decrypted, err := decryptPayload(payload.Payload, channel, timetoken, m.pubnub.Config); err != nil {
+			m.announceDecryptionError(err)
+			// the file can't be named, the event only carries the error
+			pnFilesEvent = &PNFilesEvent{
+				ActualChannel:     actualCh,
+				SubscribedChannel: subscribedCh,
+				Channel:           channel,
+				Subscription:      subscriptionMatch,
+				Timetoken:         timetoken,
+				Publisher:         payload.IssuingClientID,
+				UserMetadata:      payload.UserMetadata,
+				Error:             err,
 			}
-			m.pubnub.Config.Log.Println("DecryptString: err", err, pnStatus)
-			m.listenerManager.announceStatus(pnStatus)
-
+		} else {
+			pnFilesEvent = createPNFilesEvent(decrypted, m, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
 		}
-
-		pnFilesEvent := createPNFilesEvent(messagePayload, m, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
 		m.pubnub.Config.Log.Println("PNMessageTypeFile:", PNMessageTypeFile)
-		m.listenerManager.announceFile(pnFilesEvent)
+		if pnFilesEvent != nil {
+			pnFilesEvent.ack = payload.ack
+			m.listenerManager.announceFile(pnFilesEvent)
+		} else {
+			payload.ack.ack()
+		}
 	default:
-		var err error
-		messagePayload, err = parseCipherInterface(payload.Payload, m.pubnub.Config)
-		if err != nil {
-			pnStatus := &PNStatus{
-				Category:         PNBadRequestCategory,
-				ErrorData:        err,
-				Error:            true,
-				Operation:        PNSubscribeOperation,
-				AffectedChannels: []string{channel},
-			}
-			m.pubnub.Config.Log.Println("DecryptString: err", err, pnStatus)
-			m.listenerManager.announceStatus(pnStatus)
-
+		var decryptionErr *DecryptionError
+		if messagePayload, decryptionErr = decryptPayload(payload.Payload, channel, timetoken, m.pubnub.Config); decryptionErr != nil {
+			m.announceDecryptionError(decryptionErr)
 		}
 		pnMessageResult := createPNMessageResult(messagePayload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
+		if decryptionErr != nil {
+			pnMessageResult.Error = decryptionErr
+		}
+		pnMessageResult.ack = payload.ack
 		m.pubnub.Config.Log.Println("announceMessage,", pnMessageResult)
 		m.listenerManager.announceMessage(pnMessageResult)
 	}
@@ -924,6 +1040,8 @@ func createPNObjectsResult(objPayload interface{}, m *SubscriptionManager, actua
 		ChannelID:         channelID,
 		Description:       pnObjectsResult.Description,
 		Timestamp:         pnObjectsResult.Timestamp,
+		Updated:           pnObjectsResult.Updated,
+		ETag:              pnObjectsResult.ETag,
 		Custom:            pnObjectsResult.Custom,
 		ActualChannel:     actualCh,
 		SubscribedChannel: subscribedCh,
@@ -956,11 +1074,12 @@ func createPNMessageResult(messagePayload interface{}, actualCh, subscribedCh, c
 //
 // parameters
 // data: the data to decrypt as interface.
-// cipherKey: cipher key to use to decrypt.
+// channel: the channel of the data, it selects the CryptoModule.
+// pnConf: the config holding the CryptoModule.
 //
 // returns the decrypted data as interface and error.
-func parseCipherInterface(data interface{}, pnConf *Config) (interface{}, error) {
-	if pnConf.CipherKey != "" {
+func parseCipherInterface(data interface{}, channel string, pnConf *Config) (interface{}, error) {
+	if module := pnConf.cryptoModuleFor(channel); module != nil {
 		pnConf.Log.Println("reflect.TypeOf(data).Kind()", reflect.TypeOf(data).Kind(), data)
 		switch v := data.(type) {
 		case map[string]interface{}:
@@ -970,13 +1089,13 @@ func parseCipherInterface(data interface{}, pnConf *Config) (interface{}, error)
 				msg, ok := v["pn_other"].(string)
 				if ok {
 					pnConf.Log.Println("v[pn_other]", v["pn_other"], v, msg)
-					decrypted, errDecryption := utils.DecryptString(pnConf.CipherKey, msg, pnConf.UseRandomInitializationVector)
+					decrypted, errDecryption := decryptString(module, msg)
 					if errDecryption != nil {
 						pnConf.Log.Println(errDecryption, msg)
 						return v, errDecryption
 					} else {
 						var intf interface{}
-						err := json.Unmarshal([]byte(decrypted.(string)), &intf)
+						err := json.Unmarshal([]byte(decrypted), &intf)
 						if err != nil {
 							pnConf.Log.Println("Unmarshal: err", err)
 							return intf, err
@@ -993,15 +1112,15 @@ func parseCipherInterface(data interface{}, pnConf *Config) (interface{}, error)
 			return v, nil
 		case string:
 			var intf interface{}
-			decrypted, errDecryption := utils.DecryptString(pnConf.CipherKey, data.(string), pnConf.UseRandomInitializationVector)
+			decrypted, errDecryption := decryptString(module, v)
 			if errDecryption != nil {
 				pnConf.Log.Println(errDecryption, intf)
 				intf = data
 				return intf, errDecryption
 			}
-			pnConf.Log.Println("reflect.TypeOf(intf).Kind()", reflect.TypeOf(decrypted).Kind(), decrypted)
+			pnConf.Log.Println("decrypted", decrypted)
 
-			err := json.Unmarshal([]byte(decrypted.(string)), &intf)
+			err := json.Unmarshal([]byte(decrypted), &intf)
 			if err != nil {
 				pnConf.Log.Println("Unmarshal: err", err)
 				return intf, err
@@ -1093,6 +1212,7 @@ func (m *SubscriptionManager) getSubscribedGroups() []string {
 }
 
 func (m *SubscriptionManager) unsubscribeAll() {
+	m.stateManager.releaseAllLegacy()
 	m.adaptUnsubscribe(&UnsubscribeOperation{
 		Channels:      m.stateManager.prepareChannelList(true),
 		ChannelGroups: m.stateManager.prepareGroupList(true),
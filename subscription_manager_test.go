@@ -3,8 +3,13 @@ package pubnub
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,7 +27,7 @@ func TestParseCipherInterfaceCipherWithCipher(t *testing.T) {
 	pn.Config.CipherKey = "enigma"
 	pn.Config.UseRandomInitializationVector = false
 
-	intf, err := parseCipherInterface(s, pn.Config)
+	intf, err := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Nil(err)
 	assert.Equal("yay!", intf.(string))
@@ -35,7 +40,7 @@ func TestParseCipherInterfacePlainWithCipher(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = "enigma"
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Equal("yay!", intf.(string))
 }
@@ -47,7 +52,7 @@ func TestParseCipherInterfaceCipherWithDiffCipher(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = "test"
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Equal("Wi24KS4pcTzvyuGOHubiXg==", intf.(string))
 
@@ -60,7 +65,7 @@ func TestParseCipherInterfacePlainWithDiffCipher(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = "test"
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Equal("yay!", intf.(string))
 }
@@ -72,7 +77,7 @@ func TestParseCipherInterfaceCipherWithoutCipher(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = ""
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Equal("Wi24KS4pcTzvyuGOHubiXg==", intf.(string))
 }
@@ -84,7 +89,7 @@ func TestParseCipherInterfacePlainWithoutCipher(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = ""
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Equal("yay!", intf.(string))
 }
@@ -99,7 +104,7 @@ func TestParseCipherInterfacePlainWithCipherStruct(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = "enigma"
 
-	intf, err := parseCipherInterface(s, pn.Config)
+	intf, err := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Nil(err)
 	if msg, ok := intf.(customStruct); !ok {
@@ -121,7 +126,7 @@ func TestParseCipherInterfacePlainWithoutCipherStruct(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = ""
 
-	intf, err := parseCipherInterface(s, pn.Config)
+	intf, err := parseCipherInterface(s, "ch", pn.Config)
 
 	assert.Nil(err)
 	if msg, ok := intf.(customStruct); !ok {
@@ -148,7 +153,7 @@ func TestParseCipherInterfacePlainWithCipherMapPNOther(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = "enigma"
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("12345", msg["not_other"])
@@ -174,7 +179,7 @@ func TestParseCipherInterfacePlainWithoutCipherMapPNOther(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = ""
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("12345", msg["not_other"])
@@ -196,7 +201,7 @@ func TestParseCipherInterfaceCipherWithoutCipherStringPNOther(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = ""
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("1234", msg["not_other"])
@@ -219,7 +224,7 @@ func TestParseCipherInterfaceCipherWithCipherStringPNOther(t *testing.T) {
 	pn.Config.CipherKey = "enigma"
 	pn.Config.UseRandomInitializationVector = false
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("1234", msg["not_other"])
@@ -238,7 +243,7 @@ func TestParseCipherInterfaceCipherWithoutCipherStruct(t *testing.T) {
 	pn.Config.CipherKey = "enigma"
 	pn.Config.UseRandomInitializationVector = false
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 	msg := intf.(map[string]interface{})
 	assert.Equal("hi!", msg["Foo"])
 
@@ -256,7 +261,7 @@ func TestParseCipherInterfaceCipherWithCipherStructPNOther(t *testing.T) {
 	pn.Config.CipherKey = "enigma"
 	pn.Config.UseRandomInitializationVector = false
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("1234", msg["not_other"])
@@ -278,7 +283,7 @@ func TestParseCipherInterfaceCipherWithOtherCipherStructPNOther(t *testing.T) {
 	pn := NewPubNub(NewDemoConfig())
 	pn.Config.CipherKey = "test"
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("1234", msg["not_other"])
@@ -298,7 +303,7 @@ func TestParseCipherInterfaceCipherWithCipherStructPNOtherDisable(t *testing.T)
 	pn.Config.UseRandomInitializationVector = false
 	pn.Config.CipherKey = "enigma"
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.(map[string]interface{})
 	assert.Equal("1234", msg["not_other"])
@@ -314,7 +319,7 @@ func TestParseCipherInterfaceCipherWithIntSlice(t *testing.T) {
 	pn.Config.DisablePNOtherProcessing = true
 	pn.Config.CipherKey = ""
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 
 	msg := intf.([]int)
 	assert.Equal(1, msg[0])
@@ -329,7 +334,7 @@ func TestParseCipherInterfaceCipherWithoutCipherStruct2(t *testing.T) {
 	pn.Config.CipherKey = "enigma"
 	pn.Config.UseRandomInitializationVector = false
 
-	intf, _ := parseCipherInterface(s, pn.Config)
+	intf, _ := parseCipherInterface(s, "ch", pn.Config)
 	msg := intf.(map[string]interface{})
 	assert.Equal("12345", msg["not_other"])
 	if msgOther, ok := msg["pn_other"].(map[string]interface{}); !ok {
@@ -569,3 +574,52 @@ func TestProcessSubscribePayloadCipherErr(t *testing.T) {
 	<-done
 	//pn.Destroy()
 }
+
+func TestSubscribeLoopDeliversAcrossRequests(t *testing.T) {
+	assert := assert.New(t)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v2/subscribe/") {
+			fmt.Fprint(w, `{"status":200,"message":"OK","service":"Presence"}`)
+			return
+		}
+		n := atomic.AddInt32(&requests, 1)
+		if n > 5 {
+			<-r.Context().Done()
+			return
+		}
+		tt := r.URL.Query().Get("tt")
+		if tt == "" || tt == "0" {
+			fmt.Fprintf(w, `{"t":{"t":"%d","r":1},"m":[]}`, n)
+			return
+		}
+		fmt.Fprintf(w, `{"t":{"t":"%d","r":1},"m":[{"a":"1","f":0,"i":"u","p":{"t":"%d","r":1},"k":"demo","c":"ch","d":"msg %s","b":"ch"}]}`, n, n, tt)
+	}))
+	defer server.Close()
+
+	config := NewDemoConfig()
+	config.Origin = strings.TrimPrefix(server.URL, "http://")
+	config.Secure = false
+	pn := NewPubNub(config)
+	defer pn.Destroy()
+	listener := NewListener()
+	pn.AddListener(listener)
+
+	pn.Subscribe().Channels([]string{"ch"}).Execute()
+
+	select {
+	case status := <-listener.Status:
+		assert.Equal(PNConnectingCategory, status.Category)
+	case <-time.After(5 * time.Second):
+		assert.Fail("status not delivered")
+	}
+	for i := 1; i <= 4; i++ {
+		select {
+		case msg := <-listener.Message:
+			assert.Equal(fmt.Sprintf("msg %d", i), msg.Message)
+		case <-time.After(5 * time.Second):
+			assert.Fail("message not delivered", "message %d", i)
+			return
+		}
+	}
+}
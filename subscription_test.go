@@ -0,0 +1,191 @@
+package pubnub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateManagerRetainRelease(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	channels, groups := m.retain([]string{"ch1", "ch1-pnpres"}, []string{"cg1"})
+	assert.Equal([]string{"ch1", "ch1-pnpres"}, channels)
+	assert.Equal([]string{"cg1"}, groups)
+	m.adaptSubscribeOperation(&SubscribeOperation{
+		Channels:      channels,
+		ChannelGroups: groups,
+	})
+
+	channels, groups = m.retain([]string{"ch1", "ch2"}, []string{})
+	assert.Equal([]string{"ch2"}, channels)
+	assert.Equal([]string{}, groups)
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: channels})
+
+	channels, groups = m.release([]string{"ch1", "ch1-pnpres"}, []string{"cg1"})
+	assert.Equal([]string{"ch1-pnpres"}, channels)
+	assert.Equal([]string{"cg1"}, groups)
+	m.adaptUnsubscribeOperation(&UnsubscribeOperation{
+		Channels:      channels,
+		ChannelGroups: groups,
+	})
+
+	assert.ElementsMatch([]string{"ch1", "ch2"}, m.prepareChannelList(true))
+	assert.Equal([]string{}, m.prepareGroupList(true))
+
+	channels, _ = m.release([]string{"ch1", "ch2"}, []string{})
+	assert.Equal([]string{"ch1", "ch2"}, channels)
+}
+
+func TestStateManagerRetainAfterDirectUnsubscribe(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	channels, _ := m.retain([]string{"ch1"}, []string{})
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: channels})
+	m.adaptUnsubscribeOperation(&UnsubscribeOperation{Channels: []string{"ch1"}})
+	assert.True(m.isEmpty())
+
+	channels, _ = m.retain([]string{"ch1"}, []string{})
+	assert.Equal([]string{"ch1"}, channels)
+}
+
+func TestStateManagerLegacyReferences(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	// pn.Subscribe() twice, then a Subscription on the same channel
+	m.retainLegacy([]string{"ch1", "ch2"}, []string{"cg1"})
+	m.retainLegacy([]string{"ch1"}, []string{})
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: []string{"ch1", "ch2"}, ChannelGroups: []string{"cg1"}})
+	channels, _ := m.retain([]string{"ch1"}, []string{})
+	assert.Equal([]string{}, channels)
+
+	channels, _ = m.release([]string{"ch1"}, []string{})
+	assert.Equal([]string{}, channels)
+
+	channels, groups := m.releaseLegacy([]string{"ch1", "ch3"}, []string{"cg1"})
+	assert.Equal([]string{"ch1", "ch3"}, channels)
+	assert.Equal([]string{"cg1"}, groups)
+
+	// a channel a Subscription holds stays subscribed
+	m.retain([]string{"ch2"}, []string{})
+	channels, _ = m.releaseLegacy([]string{"ch2"}, []string{})
+	assert.Equal([]string{}, channels)
+
+	m.retainLegacy([]string{"ch4"}, []string{})
+	m.releaseAllLegacy()
+	channels, _ = m.release([]string{"ch2"}, []string{})
+	assert.Equal([]string{"ch2"}, channels)
+	assert.Len(m.channelRefs, 0)
+	assert.Len(m.legacyChannels, 0)
+}
+
+func TestStateManagerPresenceUnsubscribe(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: []string{"ch1-pnpres"}})
+	assert.False(m.isEmpty())
+
+	m.adaptUnsubscribeOperation(&UnsubscribeOperation{Channels: []string{"ch1-pnpres"}})
+	assert.True(m.isEmpty())
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	sub := pn.Channel("ch1").Subscription(SubscriptionOptions{})
+	assert.True(sub.matches("ch1", "", false))
+	assert.False(sub.matches("ch2", "", false))
+	assert.False(sub.matches("ch1", "", true))
+
+	wildcard := pn.Channel("a.*").Subscription(SubscriptionOptions{ReceivePresenceEvents: true})
+	assert.True(wildcard.matches("a.b", "a.*", false))
+	assert.True(wildcard.matches("a.b", "a.*", true))
+
+	group := pn.ChannelGroup("cg1").Subscription(SubscriptionOptions{})
+	assert.True(group.matches("ch3", "cg1", false))
+	assert.False(group.matches("ch3", "", false))
+
+	assert.True(sub.affectedBy([]string{}, []string{}))
+	assert.True(sub.affectedBy([]string{"ch1", "ch2"}, []string{}))
+	assert.False(sub.affectedBy([]string{"ch2"}, []string{"cg1"}))
+	assert.True(group.affectedBy([]string{}, []string{"cg1"}))
+}
+
+func TestSubscriptionSubscribeNames(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+
+	channels, groups := pn.Channel("ch1").Subscription(SubscriptionOptions{ReceivePresenceEvents: true}).subscribeNames()
+	assert.Equal([]string{"ch1", "ch1-pnpres"}, channels)
+	assert.Equal([]string{}, groups)
+
+	channels, groups = pn.ChannelGroup("cg1").Subscription(SubscriptionOptions{}).subscribeNames()
+	assert.Equal([]string{}, channels)
+	assert.Equal([]string{"cg1"}, groups)
+}
+
+func TestSubscriptionListenersReceiveOwnEvents(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	sub1 := pn.Channel("ch1").Subscription(SubscriptionOptions{})
+	sub2 := pn.Channel("ch2").Subscription(SubscriptionOptions{})
+	listener1 := NewListener()
+	listener2 := NewListener()
+	sub1.AddListener(listener1)
+	sub2.AddListener(listener2)
+	lm.addSubscription(sub1)
+	lm.addSubscription(sub2)
+
+	lm.announceMessage(&PNMessage{Channel: "ch1", Message: "hi"})
+
+	select {
+	case msg := <-listener1.Message:
+		assert.Equal("hi", msg.Message)
+	case <-time.After(time.Second):
+		assert.Fail("message not delivered to the subscription listener")
+	}
+	select {
+	case <-listener2.Message:
+		assert.Fail("message delivered to the listener of another channel")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	lm.removeSubscription(sub1)
+	lis := lm.copyListenersForEvent("ch1", "", false)
+	assert.Equal(0, len(lis))
+}
+
+func TestSubscriptionSetListenerDeliveredOnce(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	lm := pn.subscriptionManager.listenerManager
+
+	sub1 := pn.Channel("a.*").Subscription(SubscriptionOptions{})
+	sub2 := pn.Channel("a.b").Subscription(SubscriptionOptions{})
+	set := pn.SubscriptionSet(sub1, sub2)
+	listener := NewListener()
+	set.AddListener(listener)
+	lm.addSubscription(sub1)
+	lm.addSubscription(sub2)
+
+	lis := lm.copyListenersForEvent("a.b", "a.*", false)
+	assert.Equal(1, len(lis))
+	assert.True(lis[listener])
+
+	sub3 := pn.Channel("c").Subscription(SubscriptionOptions{})
+	set.Add(sub3)
+	lm.addSubscription(sub3)
+	assert.True(lm.copyListenersForEvent("c", "", false)[listener])
+
+	set.Remove(sub3)
+	assert.Equal(0, len(lm.copyListenersForEvent("c", "", false)))
+	assert.Equal(2, len(set.Subscriptions()))
+}
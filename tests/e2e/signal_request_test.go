@@ -82,6 +82,7 @@ func SubscribeSignalUnsubscribeMultiCommon(t *testing.T, s interface{}, cipher s
 					doneUnsubscribe <- true
 				case pubnub.PNAcknowledgmentCategory:
 					doneUnsubscribe <- true
+				case pubnub.PNConnectingCategory:
 				default:
 					doneUnsubscribe <- true
 				}
@@ -104,6 +104,7 @@ func TestRequestMesssageOverflow(t *testing.T) {
 				case pubnub.PNRequestMessageCountExceededCategory:
 					doneSubscribe <- true
 					break ExitLabel
+				case pubnub.PNConnectingCategory:
 				default:
 					errChan <- fmt.Sprintf("error ===> %v", status)
 				}
@@ -175,6 +176,7 @@ func TestSubscribeUnsubscribe(t *testing.T) {
 					break
 				case pubnub.PNCancelledCategory:
 					continue
+				case pubnub.PNConnectingCategory:
 				default:
 					//fmt.Println("default...", status)
 					errChan <- fmt.Sprintf("error ===> %v", status)
@@ -948,6 +950,7 @@ func SubscribePublishUnsubscribeMultiCommon(t *testing.T, s interface{}, cipher
 					},
 					)
 
+				case pubnub.PNConnectingCategory:
 				default:
 					once.Do(func() {
 						doneUnsubscribe <- true
@@ -2324,6 +2327,7 @@ func TestSubscribeSuperCall(t *testing.T) {
 				switch status.Category {
 				case pubnub.PNConnectedCategory:
 					doneSubscribe <- true
+				case pubnub.PNConnectingCategory:
 				default:
 					errChan <- fmt.Sprintf("Not connected: %v", status)
 				}
@@ -45,6 +45,7 @@ func TestSubscribeUnsubscribeGroup(t *testing.T) {
 					break
 				case pubnub.PNCancelledCategory:
 					continue
+				case pubnub.PNConnectingCategory:
 				default:
 					errChan <- fmt.Sprintf("%v", status)
 					//break
@@ -0,0 +1,99 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// timetokenUnit is the duration of a timetoken unit.
+const timetokenUnit = 100 * time.Nanosecond
+
+// Timetoken is a PubNub timetoken: the number of 100 nanoseconds since the
+// Unix epoch. It is marshalled to JSON as a string, as the server does, since
+// it doesn't fit in the float64 of the JSON numbers.
+type Timetoken int64
+
+// TimetokenFromTime returns the timetoken of t.
+func TimetokenFromTime(t time.Time) Timetoken {
+	return Timetoken(t.UnixNano() / int64(timetokenUnit))
+}
+
+// ParseTimetoken parses the timetoken of a string, as sent by the server.
+func ParseTimetoken(s string) (Timetoken, error) {
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timetoken %q", s)
+	}
+	return Timetoken(value), nil
+}
+
+// Time returns the time of the timetoken.
+func (t Timetoken) Time() time.Time {
+	return time.Unix(0, int64(t)*int64(timetokenUnit))
+}
+
+// Add returns the timetoken t+d, d is truncated to the timetoken unit.
+func (t Timetoken) Add(d time.Duration) Timetoken {
+	return t + Timetoken(d/timetokenUnit)
+}
+
+// Sub returns the duration t-u.
+func (t Timetoken) Sub(u Timetoken) time.Duration {
+	return time.Duration(t-u) * timetokenUnit
+}
+
+// Before reports whether t is before u.
+func (t Timetoken) Before(u Timetoken) bool {
+	return t < u
+}
+
+// After reports whether t is after u.
+func (t Timetoken) After(u Timetoken) bool {
+	return t > u
+}
+
+// Compare returns -1 when t is before u, +1 when it is after and 0 when they
+// are equal.
+func (t Timetoken) Compare(u Timetoken) int {
+	switch {
+	case t < u:
+		return -1
+	case t > u:
+		return 1
+	}
+	return 0
+}
+
+// Int64 returns the timetoken as the int64 of the builders and responses.
+func (t Timetoken) Int64() int64 {
+	return int64(t)
+}
+
+func (t Timetoken) String() string {
+	return strconv.FormatInt(int64(t), 10)
+}
+
+// MarshalJSON marshals the timetoken to a JSON string.
+func (t Timetoken) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON reads a JSON string or number.
+func (t *Timetoken) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid timetoken %s", data)
+		}
+		s = n.String()
+	}
+	value, err := ParseTimetoken(s)
+	if err != nil {
+		return err
+	}
+	*t = value
+	return nil
+}
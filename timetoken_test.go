@@ -0,0 +1,70 @@
+package pubnub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimetokenTime(t *testing.T) {
+	assert := assert.New(t)
+	at := time.Date(2020, 7, 28, 17, 31, 38, 411534200, time.UTC)
+
+	tt := TimetokenFromTime(at)
+	assert.Equal(Timetoken(15959574984115342), tt)
+	assert.True(at.Equal(tt.Time()))
+	assert.Equal("15959574984115342", tt.String())
+
+	later := tt.Add(time.Second)
+	assert.Equal(Timetoken(15959574994115342), later)
+	assert.Equal(time.Second, later.Sub(tt))
+	assert.True(tt.Before(later))
+	assert.True(later.After(tt))
+	assert.Equal(-1, tt.Compare(later))
+	assert.Equal(0, tt.Compare(tt))
+}
+
+func TestTimetokenJSON(t *testing.T) {
+	assert := assert.New(t)
+	value := struct {
+		T Timetoken `json:"t"`
+	}{T: 15959610984115342}
+
+	data, err := json.Marshal(value)
+	assert.Nil(err)
+	assert.Equal(`{"t":"15959610984115342"}`, string(data))
+
+	value.T = 0
+	assert.Nil(json.Unmarshal(data, &value))
+	assert.Equal(Timetoken(15959610984115342), value.T)
+	assert.Nil(json.Unmarshal([]byte(`{"t":15959610984115342}`), &value))
+	assert.Equal(Timetoken(15959610984115342), value.T)
+	assert.NotNil(json.Unmarshal([]byte(`{"t":"soon"}`), &value))
+
+	_, err = ParseTimetoken("x")
+	assert.NotNil(err)
+}
+
+func TestBuildersAcceptTime(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewPubNub(NewDemoConfig())
+	since := time.Unix(1600000000, 0)
+	until := since.Add(time.Hour)
+
+	fetch := pn.Fetch().Channels([]string{"ch"}).Since(since).Until(until)
+	q, _ := fetch.opts.buildQuery()
+	assert.Equal("16000000000000000", q.Get("end"))
+	assert.Equal("16000036000000000", q.Get("start"))
+
+	history := pn.History().Channel("ch").Since(since)
+	q, _ = history.opts.buildQuery()
+	assert.Equal("16000000000000000", q.Get("end"))
+
+	counts := pn.MessageCounts().Channels([]string{"ch"}).ChannelsSince([]time.Time{since})
+	assert.Equal([]int64{16000000000000000}, counts.opts.ChannelsTimetoken)
+
+	actions := pn.GetMessageActions().Channel("ch").Until(until)
+	assert.Equal("16000036000000000", actions.opts.Start)
+}
@@ -0,0 +1,269 @@
+//go:build go1.21
+// +build go1.21
+
+package pubnub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// MessageDecodeError is returned when a message payload can't be decoded
+// into the requested type.
+type MessageDecodeError struct {
+	Channel   string
+	Timetoken int64
+	Err       error
+}
+
+func (e *MessageDecodeError) Error() string {
+	return fmt.Sprintf("pubnub: can't decode message %d on channel %s: %s", e.Timetoken, e.Channel, e.Err)
+}
+
+func (e *MessageDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeMessage decodes a message payload, as found in PNMessage.Message,
+// FetchResponseItem.Message or HistoryResponseItem.Message, into T.
+//
+// The payload is converted through JSON, a string payload holding a JSON
+// document is decoded as that document.
+func DecodeMessage[T any](message interface{}) (T, error) {
+	var value T
+	if v, ok := message.(T); ok {
+		return v, nil
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return value, err
+	}
+	err = json.Unmarshal(data, &value)
+	if err == nil {
+		return value, nil
+	}
+
+	if s, ok := message.(string); ok {
+		var fromString T
+		if json.Unmarshal([]byte(s), &fromString) == nil {
+			return fromString, nil
+		}
+	}
+	return value, err
+}
+
+// MessageMeta holds the metadata of a message delivered to a typed handler.
+type MessageMeta struct {
+	Channel      string
+	Subscription string
+	Publisher    string
+	Timetoken    int64
+	UserMetadata interface{}
+	// Err is a *MessageDecodeError when the payload could not be decoded,
+	// the message is then the zero value of its type.
+	Err error
+	// Raw is the event as received by the listener, use it to Ack() the
+	// message when a CursorStore is configured.
+	Raw *PNMessage
+}
+
+const typedListenerBufferSize = 100
+
+// TypedSubscription is the Subscription created by OnMessage. Unsubscribe
+// also stops the delivery to the handler.
+type TypedSubscription[T any] struct {
+	*Subscription
+	listener *Listener
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// OnMessage subscribes to the channel and calls handler with every message
+// received on it, decoded into T. The handler is called from a single
+// goroutine, in the order the messages were received.
+func OnMessage[T any](pn *PubNub, channel string, handler func(message T, meta MessageMeta)) *TypedSubscription[T] {
+	typed := &TypedSubscription[T]{
+		Subscription: pn.Channel(channel).Subscription(SubscriptionOptions{}),
+		// buffered, so that the messages reach the handler in order
+		listener: NewListenerWithOptions(ListenerOptions{BufferSize: typedListenerBufferSize}),
+		done:     make(chan struct{}),
+	}
+	typed.Subscription.AddListener(typed.listener)
+
+	go func() {
+		for {
+			select {
+			case <-typed.done:
+				return
+			case msg := <-typed.listener.Message:
+				value, meta := decodePNMessage[T](msg)
+				handler(value, meta)
+			// the other events of the channel are not for the handler
+			case <-typed.listener.Status:
+			case <-typed.listener.Signal:
+			case <-typed.listener.Presence:
+			case <-typed.listener.UUIDEvent:
+			case <-typed.listener.ChannelEvent:
+			case <-typed.listener.MembershipEvent:
+			case <-typed.listener.MessageActionsEvent:
+			case <-typed.listener.File:
+			}
+		}
+	}()
+
+	typed.Subscription.Subscribe()
+	return typed
+}
+
+// Unsubscribe unsubscribes from the channel and stops the handler.
+func (s *TypedSubscription[T]) Unsubscribe() {
+	s.Subscription.Unsubscribe()
+	s.Subscription.RemoveListener(s.listener)
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func decodePNMessage[T any](msg *PNMessage) (T, MessageMeta) {
+	meta := MessageMeta{
+		Channel:      msg.Channel,
+		Subscription: msg.Subscription,
+		Publisher:    msg.Publisher,
+		Timetoken:    msg.Timetoken,
+		UserMetadata: msg.UserMetadata,
+		Raw:          msg,
+	}
+	value, err := DecodeMessage[T](msg.Message)
+	if err != nil {
+		meta.Err = &MessageDecodeError{
+			Channel:   msg.Channel,
+			Timetoken: msg.Timetoken,
+			Err:       err,
+		}
+	}
+	return value, meta
+}
+
+// TypedFetchResponseItem is a FetchResponseItem with its message decoded
+// into T.
+type TypedFetchResponseItem[T any] struct {
+	FetchResponseItem
+	// Value is the decoded message, the zero value when Err is set.
+	Value T
+	// Err is a *MessageDecodeError when the message could not be decoded,
+	// or the *DecryptionError of the item.
+	Err error
+}
+
+// TypedFetchResponse is a FetchResponse with its messages decoded into T.
+type TypedFetchResponse[T any] struct {
+	Messages map[string][]TypedFetchResponseItem[T]
+}
+
+// FetchAs executes the Fetch request and decodes the messages into T. A
+// message which can't be decoded doesn't fail the request, its item carries
+// the error.
+func FetchAs[T any](builder *fetchBuilder) (*TypedFetchResponse[T], StatusResponse, error) {
+	resp, status, err := builder.Execute()
+	if err != nil {
+		return nil, status, err
+	}
+	return DecodeFetchResponse[T](resp), status, nil
+}
+
+// DecodeFetchResponse decodes the messages of a FetchResponse into T.
+func DecodeFetchResponse[T any](resp *FetchResponse) *TypedFetchResponse[T] {
+	typed := &TypedFetchResponse[T]{
+		Messages: make(map[string][]TypedFetchResponseItem[T]),
+	}
+	if resp == nil {
+		return typed
+	}
+
+	for channel, items := range resp.Messages {
+		typedItems := make([]TypedFetchResponseItem[T], len(items))
+		for i, item := range items {
+			typedItems[i].FetchResponseItem = item
+			if item.Error != nil {
+				typedItems[i].Err = item.Error
+				continue
+			}
+			value, err := DecodeMessage[T](item.Message)
+			if err != nil {
+				timetoken, _ := strconv.ParseInt(item.Timetoken, 10, 64)
+				typedItems[i].Err = &MessageDecodeError{
+					Channel:   channel,
+					Timetoken: timetoken,
+					Err:       err,
+				}
+				continue
+			}
+			typedItems[i].Value = value
+		}
+		typed.Messages[channel] = typedItems
+	}
+	return typed
+}
+
+// TypedHistoryResponseItem is a HistoryResponseItem with its message decoded
+// into T.
+type TypedHistoryResponseItem[T any] struct {
+	HistoryResponseItem
+	// Value is the decoded message, the zero value when Err is set.
+	Value T
+	// Err is a *MessageDecodeError when the message could not be decoded,
+	// or the *DecryptionError of the item.
+	Err error
+}
+
+// TypedHistoryResponse is a HistoryResponse with its messages decoded into T.
+type TypedHistoryResponse[T any] struct {
+	Messages       []TypedHistoryResponseItem[T]
+	StartTimetoken int64
+	EndTimetoken   int64
+}
+
+// HistoryAs executes the History request and decodes the messages into T. A
+// message which can't be decoded doesn't fail the request, its item carries
+// the error.
+func HistoryAs[T any](builder *historyBuilder) (*TypedHistoryResponse[T], StatusResponse, error) {
+	resp, status, err := builder.Execute()
+	if err != nil {
+		return nil, status, err
+	}
+	return DecodeHistoryResponse[T](builder.opts.Channel, resp), status, nil
+}
+
+// DecodeHistoryResponse decodes the messages of the HistoryResponse of the
+// channel into T.
+func DecodeHistoryResponse[T any](channel string, resp *HistoryResponse) *TypedHistoryResponse[T] {
+	typed := &TypedHistoryResponse[T]{}
+	if resp == nil {
+		return typed
+	}
+	typed.StartTimetoken = resp.StartTimetoken
+	typed.EndTimetoken = resp.EndTimetoken
+
+	typed.Messages = make([]TypedHistoryResponseItem[T], len(resp.Messages))
+	for i, item := range resp.Messages {
+		typed.Messages[i].HistoryResponseItem = item
+		if item.Error != nil {
+			typed.Messages[i].Err = item.Error
+			continue
+		}
+		value, err := DecodeMessage[T](item.Message)
+		if err != nil {
+			typed.Messages[i].Err = &MessageDecodeError{
+				Channel:   channel,
+				Timetoken: item.Timetoken,
+				Err:       err,
+			}
+			continue
+		}
+		typed.Messages[i].Value = value
+	}
+	return typed
+}
@@ -0,0 +1,124 @@
+//go:build go1.21
+// +build go1.21
+
+package pubnub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedTestMessage struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+func TestDecodeMessageFromMap(t *testing.T) {
+	assert := assert.New(t)
+
+	msg, err := DecodeMessage[typedTestMessage](map[string]interface{}{"text": "hi", "count": float64(2)})
+	assert.Nil(err)
+	assert.Equal(typedTestMessage{Text: "hi", Count: 2}, msg)
+}
+
+func TestDecodeMessageFromJSONString(t *testing.T) {
+	assert := assert.New(t)
+
+	msg, err := DecodeMessage[typedTestMessage](`{"text":"hi","count":2}`)
+	assert.Nil(err)
+	assert.Equal(typedTestMessage{Text: "hi", Count: 2}, msg)
+
+	s, err := DecodeMessage[string]("plain")
+	assert.Nil(err)
+	assert.Equal("plain", s)
+}
+
+func TestDecodeMessageError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := DecodeMessage[typedTestMessage]([]interface{}{"a"})
+	assert.NotNil(err)
+
+	_, err = DecodeMessage[int]("not a number")
+	assert.NotNil(err)
+}
+
+func TestDecodePNMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	value, meta := decodePNMessage[typedTestMessage](&PNMessage{
+		Message:   map[string]interface{}{"text": "hi"},
+		Channel:   "ch1",
+		Publisher: "u1",
+		Timetoken: 15,
+	})
+	assert.Nil(meta.Err)
+	assert.Equal("hi", value.Text)
+	assert.Equal("ch1", meta.Channel)
+	assert.Equal("u1", meta.Publisher)
+	assert.Equal(int64(15), meta.Timetoken)
+
+	_, meta = decodePNMessage[typedTestMessage](&PNMessage{Message: "oops", Channel: "ch1", Timetoken: 16})
+	var decodeErr *MessageDecodeError
+	assert.True(errors.As(meta.Err, &decodeErr))
+	assert.Equal("ch1", decodeErr.Channel)
+	assert.Equal(int64(16), decodeErr.Timetoken)
+}
+
+func TestDecodeFetchResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	typed := DecodeFetchResponse[typedTestMessage](&FetchResponse{
+		Messages: map[string][]FetchResponseItem{
+			"ch1": {
+				{Message: map[string]interface{}{"text": "a"}, Timetoken: "15"},
+				{Message: "oops", Timetoken: "16"},
+			},
+		},
+	})
+
+	items := typed.Messages["ch1"]
+	assert.Equal(2, len(items))
+	assert.Nil(items[0].Err)
+	assert.Equal("a", items[0].Value.Text)
+	assert.Equal("15", items[0].Timetoken)
+	assert.NotNil(items[1].Err)
+	assert.Equal(int64(16), items[1].Err.(*MessageDecodeError).Timetoken)
+}
+
+func TestDecodeHistoryResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	typed := DecodeHistoryResponse[typedTestMessage]("ch1", &HistoryResponse{
+		Messages: []HistoryResponseItem{
+			{Message: map[string]interface{}{"count": float64(3)}, Timetoken: 15},
+			{Message: true, Timetoken: 16},
+		},
+		StartTimetoken: 15,
+		EndTimetoken:   16,
+	})
+
+	assert.Equal(int64(15), typed.StartTimetoken)
+	assert.Equal(3, typed.Messages[0].Value.Count)
+	assert.NotNil(typed.Messages[1].Err)
+	assert.Equal("ch1", typed.Messages[1].Err.(*MessageDecodeError).Channel)
+}
+
+func TestDecodeResponseKeepsDecryptionError(t *testing.T) {
+	assert := assert.New(t)
+	decryptionErr := &DecryptionError{Channel: "ch1", Timetoken: 15}
+
+	history := DecodeHistoryResponse[typedTestMessage]("ch1", &HistoryResponse{
+		Messages: []HistoryResponseItem{{Message: "garbage", Timetoken: 15, Error: decryptionErr}},
+	})
+	assert.Equal(decryptionErr, history.Messages[0].Err)
+
+	fetch := DecodeFetchResponse[typedTestMessage](&FetchResponse{
+		Messages: map[string][]FetchResponseItem{
+			"ch1": {{Message: "garbage", Timetoken: "15", Error: decryptionErr}},
+		},
+	})
+	assert.Equal(decryptionErr, fetch.Messages["ch1"][0].Err)
+}
@@ -35,7 +35,16 @@ func (b *unsubscribeBuilder) QueryParam(queryParam map[string]string) *unsubscri
 	return b
 }
 
-// Execute runs the Unsubscribe request and unsubscribes from the specified channels.
+// Execute runs the Unsubscribe request and unsubscribes from the specified
+// channels, except from those a Subscription still holds.
 func (b *unsubscribeBuilder) Execute() {
-	b.pubnub.subscriptionManager.adaptUnsubscribe(b.operation)
+	m := b.pubnub.subscriptionManager
+	channels, groups := m.stateManager.releaseLegacy(b.operation.Channels, b.operation.ChannelGroups)
+	if len(channels) == 0 && len(groups) == 0 {
+		return
+	}
+	operation := *b.operation
+	operation.Channels = channels
+	operation.ChannelGroups = groups
+	m.adaptUnsubscribe(&operation)
 }
@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+const (
+	// LegacyCryptorID identifies the AES-CBC cryptor used by EncryptString and
+	// EncryptFile. Its payloads carry no header.
+	LegacyCryptorID = "0000"
+	// AesGcmCryptorID identifies the AES-256-GCM cryptor.
+	AesGcmCryptorID = "ACGM"
+
+	// CryptoHeaderVersion is the version of the header written by
+	// EncodeCryptoHeader.
+	CryptoHeaderVersion = 1
+
+	cryptoHeaderSentinel    = "PNED"
+	cryptorIDLength         = 4
+	cryptoHeaderShortLength = 255
+)
+
+// ErrUnknownCryptoHeaderVersion is returned when a payload starts with a
+// header written by a newer version of the SDK.
+var ErrUnknownCryptoHeaderVersion = errors.New("unknown crypto header version")
+
+// EncryptedData is the output of a Cryptor: the data needed to decrypt, the
+// IV or nonce, and the encrypted data.
+type EncryptedData struct {
+	Metadata []byte
+	Data     []byte
+}
+
+// Cryptor encrypts and decrypts data with one algorithm. The ID is written in
+// the header of the payloads, so that they are decrypted with the same
+// cryptor.
+type Cryptor interface {
+	// ID is 4 bytes long.
+	ID() string
+	Encrypt(data []byte) (*EncryptedData, error)
+	Decrypt(encrypted *EncryptedData) ([]byte, error)
+}
+
+// LegacyCryptor is the AES-CBC cryptor with the key derived by
+// EncryptCipherKey. The IV is prepended to the data when it is random.
+type LegacyCryptor struct {
+	block                         cipher.Block
+	useRandomInitializationVector bool
+}
+
+// NewLegacyCryptor creates the AES-CBC cryptor for the cipherKey.
+// useRandomInitializationVector: if false the hardcoded IV is used, as
+// EncryptString does.
+func NewLegacyCryptor(cipherKey string, useRandomInitializationVector bool) *LegacyCryptor {
+	// the key is always 32 bytes long, NewCipher can't fail
+	block, _ := aesCipher(cipherKey)
+	return &LegacyCryptor{
+		block:                         block,
+		useRandomInitializationVector: useRandomInitializationVector,
+	}
+}
+
+// ID returns LegacyCryptorID.
+func (c *LegacyCryptor) ID() string {
+	return LegacyCryptorID
+}
+
+// WithRandomInitializationVector returns the cryptor for the same key using a
+// random IV. Files are always encrypted with a random IV.
+func (c *LegacyCryptor) WithRandomInitializationVector() *LegacyCryptor {
+	return &LegacyCryptor{
+		block:                         c.block,
+		useRandomInitializationVector: true,
+	}
+}
+
+// Encrypt pads the data with PKCS7 and encrypts it.
+func (c *LegacyCryptor) Encrypt(data []byte) (*EncryptedData, error) {
+	var iv []byte
+	if c.useRandomInitializationVector {
+		iv = generateIV(aes.BlockSize)
+	} else {
+		iv = []byte(valIV)
+	}
+
+	value := padWithPKCS7(append([]byte{}, data...))
+	encrypted := make([]byte, len(value))
+	cipher.NewCBCEncrypter(c.block, iv).CryptBlocks(encrypted, value)
+	if c.useRandomInitializationVector {
+		encrypted = append(iv, encrypted...)
+	}
+	return &EncryptedData{Data: encrypted}, nil
+}
+
+// Decrypt decrypts the data and removes the PKCS7 padding.
+func (c *LegacyCryptor) Decrypt(encrypted *EncryptedData) ([]byte, error) {
+	value := encrypted.Data
+	iv := []byte(valIV)
+	if c.useRandomInitializationVector {
+		if len(value) < aes.BlockSize {
+			return nil, fmt.Errorf("decrypt error: invalid data len %d", len(value))
+		}
+		iv = value[:aes.BlockSize]
+		value = value[aes.BlockSize:]
+	}
+	if len(value) == 0 || len(value)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("decrypt error: invalid data len %d", len(value))
+	}
+
+	decrypted := make([]byte, len(value))
+	cipher.NewCBCDecrypter(c.block, iv).CryptBlocks(decrypted, value)
+	decrypted, err := unpadPKCS7(decrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt error: %s", err)
+	}
+	return decrypted, nil
+}
+
+// AesGcmCryptor is the authenticated AES-256-GCM cryptor. The key is the
+// SHA-256 of the cipher key and the nonce is random, it is stored in the
+// metadata.
+type AesGcmCryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAesGcmCryptor creates the AES-256-GCM cryptor for the cipherKey.
+func NewAesGcmCryptor(cipherKey string) *AesGcmCryptor {
+	key := sha256.Sum256([]byte(cipherKey))
+	// the key is 32 bytes long and the nonce has the standard size, neither
+	// can fail
+	block, _ := aes.NewCipher(key[:])
+	aead, _ := cipher.NewGCM(block)
+	return &AesGcmCryptor{
+		aead: aead,
+	}
+}
+
+// ID returns AesGcmCryptorID.
+func (c *AesGcmCryptor) ID() string {
+	return AesGcmCryptorID
+}
+
+// Encrypt seals the data with a random nonce.
+func (c *AesGcmCryptor) Encrypt(data []byte) (*EncryptedData, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &EncryptedData{
+		Metadata: nonce,
+		Data:     c.aead.Seal(nil, nonce, data, nil),
+	}, nil
+}
+
+// Decrypt opens the data, it fails when the data was modified or encrypted
+// with another key.
+func (c *AesGcmCryptor) Decrypt(encrypted *EncryptedData) ([]byte, error) {
+	if len(encrypted.Metadata) != c.aead.NonceSize() {
+		return nil, fmt.Errorf("decrypt error: invalid nonce len %d", len(encrypted.Metadata))
+	}
+	decrypted, err := c.aead.Open(nil, encrypted.Metadata, encrypted.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt error: %s", err)
+	}
+	return decrypted, nil
+}
+
+// EncodeCryptoHeader creates the header identifying the cryptor of a
+// payload:
+// "PNED", the version, the cryptor ID, the metadata length and the metadata.
+// The length takes 1 byte, or 3 bytes when it is 255 or more.
+func EncodeCryptoHeader(cryptorID string, metadata []byte) ([]byte, error) {
+	if len(cryptorID) != cryptorIDLength {
+		return nil, fmt.Errorf("invalid cryptor id %q", cryptorID)
+	}
+	if len(metadata) > 0xffff {
+		return nil, fmt.Errorf("metadata too long %d", len(metadata))
+	}
+
+	header := bytes.NewBufferString(cryptoHeaderSentinel)
+	header.WriteByte(CryptoHeaderVersion)
+	header.WriteString(cryptorID)
+	if len(metadata) < cryptoHeaderShortLength {
+		header.WriteByte(byte(len(metadata)))
+	} else {
+		header.Write([]byte{cryptoHeaderShortLength, byte(len(metadata) >> 8), byte(len(metadata))})
+	}
+	header.Write(metadata)
+	return header.Bytes(), nil
+}
+
+// DecodeCryptoHeader splits a payload into its cryptor ID, metadata and
+// encrypted data. ok is false when the payload has no header, it was then
+// encrypted by the legacy cryptor.
+func DecodeCryptoHeader(payload []byte) (cryptorID string, encrypted *EncryptedData, ok bool, err error) {
+	if !bytes.HasPrefix(payload, []byte(cryptoHeaderSentinel)) {
+		return "", nil, false, nil
+	}
+
+	rest := payload[len(cryptoHeaderSentinel):]
+	if len(rest) < 1+cryptorIDLength+1 {
+		return "", nil, true, errors.New("decrypt error: truncated crypto header")
+	}
+	if rest[0] != CryptoHeaderVersion {
+		return "", nil, true, ErrUnknownCryptoHeaderVersion
+	}
+	cryptorID = string(rest[1 : 1+cryptorIDLength])
+	rest = rest[1+cryptorIDLength:]
+
+	metadataLength := int(rest[0])
+	rest = rest[1:]
+	if metadataLength == cryptoHeaderShortLength {
+		if len(rest) < 2 {
+			return "", nil, true, errors.New("decrypt error: truncated crypto header")
+		}
+		metadataLength = int(rest[0])<<8 | int(rest[1])
+		rest = rest[2:]
+	}
+	if len(rest) < metadataLength {
+		return "", nil, true, errors.New("decrypt error: truncated crypto header")
+	}
+
+	return cryptorID, &EncryptedData{
+		Metadata: rest[:metadataLength],
+		Data:     rest[metadataLength:],
+	}, true, nil
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// cbcStreamChunkSize is the size of the chunks read from the stream, a
+// multiple of the block size.
+const cbcStreamChunkSize = 32 * aes.BlockSize
+
+// StreamCryptor is implemented by the cryptors which encrypt a stream
+// without holding it in memory.
+type StreamCryptor interface {
+	Cryptor
+	// EncryptStream returns the metadata and the encrypted stream of r.
+	EncryptStream(r io.Reader) (metadata []byte, encrypted io.Reader, err error)
+	// EncryptedSize returns the size of the encrypted stream of size bytes.
+	EncryptedSize(size int64) int64
+	// DecryptStream returns the decrypted stream of r, decrypted as it is
+	// read.
+	DecryptStream(metadata []byte, r io.Reader) (io.Reader, error)
+}
+
+// EncryptStream encrypts r block by block, the IV is the first block of the
+// stream when it is random.
+func (c *LegacyCryptor) EncryptStream(r io.Reader) ([]byte, io.Reader, error) {
+	var iv []byte
+	if c.useRandomInitializationVector {
+		iv = generateIV(aes.BlockSize)
+	} else {
+		iv = []byte(valIV)
+	}
+
+	stream := &cbcEncryptReader{
+		src:   r,
+		mode:  cipher.NewCBCEncrypter(c.block, iv),
+		chunk: make([]byte, cbcStreamChunkSize),
+	}
+	if c.useRandomInitializationVector {
+		stream.out = iv
+	}
+	return nil, stream, nil
+}
+
+// EncryptedSize returns the size of the padded data, and of the IV when it
+// is random.
+func (c *LegacyCryptor) EncryptedSize(size int64) int64 {
+	encrypted := (size/aes.BlockSize + 1) * aes.BlockSize
+	if c.useRandomInitializationVector {
+		encrypted += aes.BlockSize
+	}
+	return encrypted
+}
+
+// cbcEncryptReader encrypts the chunks of src as they are read, the last one
+// is padded with PKCS7.
+type cbcEncryptReader struct {
+	src   io.Reader
+	mode  cipher.BlockMode
+	chunk []byte
+	out   []byte
+	done  bool
+	err   error
+}
+
+func (r *cbcEncryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.fill()
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *cbcEncryptReader) fill() {
+	n, err := readChunk(r.src, r.chunk)
+	switch err {
+	case nil:
+		r.mode.CryptBlocks(r.chunk[:n], r.chunk[:n])
+		r.out = r.chunk[:n]
+	case io.EOF:
+		last := padWithPKCS7(append([]byte{}, r.chunk[:n]...))
+		r.mode.CryptBlocks(last, last)
+		r.out = last
+		r.done = true
+	default:
+		r.err = err
+	}
+}
+
+// DecryptStream decrypts r block by block, the IV is read from the stream
+// when it is random.
+func (c *LegacyCryptor) DecryptStream(metadata []byte, r io.Reader) (io.Reader, error) {
+	iv := []byte(valIV)
+	if c.useRandomInitializationVector {
+		iv = make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(r, iv); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, errors.New("decrypt error: missing IV")
+			}
+			return nil, err
+		}
+	}
+
+	return &cbcDecryptReader{
+		src:   r,
+		mode:  cipher.NewCBCDecrypter(c.block, iv),
+		chunk: make([]byte, cbcStreamChunkSize),
+	}, nil
+}
+
+// cbcDecryptReader decrypts the chunks of src as they are read. The last
+// decrypted block is held back until the end of src, where its PKCS7 padding
+// is removed.
+type cbcDecryptReader struct {
+	src   io.Reader
+	mode  cipher.BlockMode
+	chunk []byte
+	last  []byte
+	out   []byte
+	done  bool
+	err   error
+}
+
+func (r *cbcDecryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.fill()
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *cbcDecryptReader) fill() {
+	n, err := readChunk(r.src, r.chunk)
+	if err != nil && err != io.EOF {
+		r.err = err
+		return
+	}
+	if n%aes.BlockSize != 0 {
+		r.err = fmt.Errorf("decrypt error: invalid data len %d", n)
+		return
+	}
+
+	decrypted := append(r.last, r.chunk[:n]...)
+	r.mode.CryptBlocks(decrypted[len(r.last):], decrypted[len(r.last):])
+	if err == nil {
+		split := len(decrypted) - aes.BlockSize
+		r.out = decrypted[:split]
+		r.last = append([]byte{}, decrypted[split:]...)
+		return
+	}
+
+	r.done = true
+	unpadded, errUnpad := unpadPKCS7(decrypted)
+	if errUnpad != nil {
+		r.err = fmt.Errorf("decrypt error: %s", errUnpad)
+		return
+	}
+	r.out = unpadded
+}
+
+// readChunk fills chunk from src. Unlike io.ReadFull the error is io.EOF when
+// src ends before chunk is full, and any other error of src is returned as
+// is, io.ErrUnexpectedEOF included.
+func readChunk(src io.Reader, chunk []byte) (int, error) {
+	n := 0
+	for n < len(chunk) {
+		read, err := src.Read(chunk[n:])
+		n += read
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ReadCryptoHeader reads the header of the stream, as written by
+// EncodeCryptoHeader. ok is false when the stream has no header, nothing is
+// read then.
+func ReadCryptoHeader(r *bufio.Reader) (cryptorID string, metadata []byte, ok bool, err error) {
+	sentinel, _ := r.Peek(len(cryptoHeaderSentinel))
+	if !bytes.Equal(sentinel, []byte(cryptoHeaderSentinel)) {
+		return "", nil, false, nil
+	}
+
+	fixed := make([]byte, len(cryptoHeaderSentinel)+1+cryptorIDLength+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return "", nil, true, errors.New("decrypt error: truncated crypto header")
+	}
+	if fixed[len(cryptoHeaderSentinel)] != CryptoHeaderVersion {
+		return "", nil, true, ErrUnknownCryptoHeaderVersion
+	}
+	cryptorID = string(fixed[len(cryptoHeaderSentinel)+1 : len(fixed)-1])
+
+	metadataLength := int(fixed[len(fixed)-1])
+	if metadataLength == cryptoHeaderShortLength {
+		long := make([]byte, 2)
+		if _, err := io.ReadFull(r, long); err != nil {
+			return "", nil, true, errors.New("decrypt error: truncated crypto header")
+		}
+		metadataLength = int(long[0])<<8 | int(long[1])
+	}
+	metadata = make([]byte, metadataLength)
+	if _, err := io.ReadFull(r, metadata); err != nil {
+		return "", nil, true, errors.New("decrypt error: truncated crypto header")
+	}
+	return cryptorID, metadata, true, nil
+}
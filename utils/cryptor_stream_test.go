@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyCryptorEncryptStream(t *testing.T) {
+	assert := assert.New(t)
+	cryptor := NewLegacyCryptor("enigma", true)
+
+	for _, size := range []int{0, 15, 16, 17, cbcStreamChunkSize, cbcStreamChunkSize + 1, 3*cbcStreamChunkSize - 1} {
+		content := bytes.Repeat([]byte{'a'}, size)
+		_, stream, err := cryptor.EncryptStream(bytes.NewReader(content))
+		assert.Nil(err)
+		encrypted, err := ioutil.ReadAll(stream)
+		assert.Nil(err)
+		assert.Equal(cryptor.EncryptedSize(int64(size)), int64(len(encrypted)), "size %d", size)
+
+		decrypted, err := cryptor.Decrypt(&EncryptedData{Data: encrypted})
+		assert.Nil(err, "size %d", size)
+		assert.Equal(content, decrypted, "size %d", size)
+
+		r, w := io.Pipe()
+		DecryptFile("enigma", int64(len(encrypted)), bytes.NewReader(encrypted), w)
+		fromFile, _ := ioutil.ReadAll(r)
+		assert.Equal(content, fromFile, "size %d", size)
+	}
+}
+
+func TestLegacyCryptorEncryptStreamMatchesEncrypt(t *testing.T) {
+	assert := assert.New(t)
+	cryptor := NewLegacyCryptor("enigma", false)
+
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	_, stream, _ := cryptor.EncryptStream(bytes.NewReader(content))
+	streamed, _ := ioutil.ReadAll(stream)
+	encrypted, _ := cryptor.Encrypt(content)
+	assert.Equal(encrypted.Data, streamed)
+}
+
+func TestLegacyCryptorDecryptStream(t *testing.T) {
+	assert := assert.New(t)
+	for _, size := range []int{0, 15, 16, 17, cbcStreamChunkSize, cbcStreamChunkSize + 1, 3 * cbcStreamChunkSize} {
+		for _, randomIV := range []bool{false, true} {
+			cryptor := NewLegacyCryptor("enigma", randomIV)
+			data := bytes.Repeat([]byte{'a'}, size)
+			encrypted, err := cryptor.Encrypt(data)
+			assert.Nil(err)
+
+			r, err := cryptor.DecryptStream(nil, bytes.NewReader(encrypted.Data))
+			assert.Nil(err)
+			decrypted, err := ioutil.ReadAll(r)
+			assert.Nil(err)
+			assert.Equal(data, decrypted, "size %d", size)
+		}
+	}
+}
+
+func TestLegacyCryptorDecryptStreamInvalidLength(t *testing.T) {
+	assert := assert.New(t)
+	cryptor := NewLegacyCryptor("enigma", false)
+
+	r, err := cryptor.DecryptStream(nil, bytes.NewReader(make([]byte, 20)))
+	assert.Nil(err)
+	_, err = ioutil.ReadAll(r)
+	assert.NotNil(err)
+}
+
+func TestReadCryptoHeader(t *testing.T) {
+	assert := assert.New(t)
+	header, _ := EncodeCryptoHeader(AesGcmCryptorID, []byte("nonce"))
+	r := bufio.NewReader(bytes.NewReader(append(header, "data"...)))
+
+	id, metadata, ok, err := ReadCryptoHeader(r)
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal(AesGcmCryptorID, id)
+	assert.Equal([]byte("nonce"), metadata)
+	rest, _ := ioutil.ReadAll(r)
+	assert.Equal([]byte("data"), rest)
+
+	r = bufio.NewReader(bytes.NewReader([]byte("data")))
+	_, _, ok, err = ReadCryptoHeader(r)
+	assert.Nil(err)
+	assert.False(ok)
+	rest, _ = ioutil.ReadAll(r)
+	assert.Equal([]byte("data"), rest)
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyCryptorMatchesEncryptString(t *testing.T) {
+	assert := assert.New(t)
+
+	encrypted, err := NewLegacyCryptor("enigma", false).Encrypt([]byte("yay!"))
+	assert.Nil(err)
+	assert.Equal(EncryptString("enigma", "yay!", false), base64.StdEncoding.EncodeToString(encrypted.Data))
+}
+
+func TestLegacyCryptorRandomIV(t *testing.T) {
+	assert := assert.New(t)
+	cryptor := NewLegacyCryptor("enigma", true)
+
+	encrypted, err := cryptor.Encrypt([]byte("yay!"))
+	assert.Nil(err)
+	decrypted, err := DecryptString("enigma", base64.StdEncoding.EncodeToString(encrypted.Data), true)
+	assert.Nil(err)
+	assert.Equal("yay!", decrypted)
+
+	plain, err := cryptor.Decrypt(encrypted)
+	assert.Nil(err)
+	assert.Equal([]byte("yay!"), plain)
+
+	_, err = cryptor.Decrypt(&EncryptedData{Data: []byte("short")})
+	assert.NotNil(err)
+}
+
+func TestAesGcmCryptor(t *testing.T) {
+	assert := assert.New(t)
+	cryptor := NewAesGcmCryptor("enigma")
+
+	encrypted, err := cryptor.Encrypt([]byte("yay!"))
+	assert.Nil(err)
+	assert.Equal(12, len(encrypted.Metadata))
+
+	plain, err := cryptor.Decrypt(encrypted)
+	assert.Nil(err)
+	assert.Equal([]byte("yay!"), plain)
+
+	_, err = NewAesGcmCryptor("other").Decrypt(encrypted)
+	assert.NotNil(err)
+
+	encrypted.Data[0] ^= 1
+	_, err = cryptor.Decrypt(encrypted)
+	assert.NotNil(err)
+}
+
+func TestCryptoHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	header, err := EncodeCryptoHeader(AesGcmCryptorID, []byte{1, 2, 3})
+	assert.Nil(err)
+	assert.Equal([]byte{'P', 'N', 'E', 'D', 1, 'A', 'C', 'G', 'M', 3, 1, 2, 3}, header)
+
+	id, encrypted, ok, err := DecodeCryptoHeader(append(header, 9, 9))
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal(AesGcmCryptorID, id)
+	assert.Equal([]byte{1, 2, 3}, encrypted.Metadata)
+	assert.Equal([]byte{9, 9}, encrypted.Data)
+
+	metadata := bytes.Repeat([]byte{7}, 300)
+	header, err = EncodeCryptoHeader("ABCD", metadata)
+	assert.Nil(err)
+	_, encrypted, ok, err = DecodeCryptoHeader(header)
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal(metadata, encrypted.Metadata)
+	assert.Empty(encrypted.Data)
+
+	_, err = EncodeCryptoHeader("AB", nil)
+	assert.NotNil(err)
+}
+
+func TestDecodeCryptoHeaderInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, ok, err := DecodeCryptoHeader([]byte("legacy payload"))
+	assert.Nil(err)
+	assert.False(ok)
+
+	_, _, ok, err = DecodeCryptoHeader([]byte{'P', 'N', 'E', 'D', 2, 'A', 'C', 'G', 'M', 0})
+	assert.True(ok)
+	assert.Equal(ErrUnknownCryptoHeaderVersion, err)
+
+	_, _, _, err = DecodeCryptoHeader([]byte{'P', 'N', 'E', 'D', 1, 'A', 'C', 'G', 'M', 5, 1})
+	assert.NotNil(err)
+}